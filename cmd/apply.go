@@ -3,13 +3,22 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
 	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/diff"
 	"github.com/tuannvm/mcpenetes/internal/log"
 	"github.com/tuannvm/mcpenetes/internal/translator"
-	"github.com/tuannvm/mcpenetes/internal/util"
+)
+
+// Answers of the apply confirmation prompt
+const (
+	confirmApply    = "Yes, apply"
+	confirmShowDiff = "Show diff"
+	confirmCancel   = "No, cancel"
 )
 
 // applyCmd represents the apply command (renamed from reload)
@@ -28,184 +37,339 @@ var applyCmd = &cobra.Command{
 4. Backing up existing configuration files before overwriting
 5. Writing the new converted configuration for each client
 
-This command requires confirmation before proceeding.`,
+This command requires confirmation before proceeding. Use --dry-run (or the
+'plan' command) to print the changes as unified diffs without writing anything.
+
+For scripts and CI, select clients with --client (repeatable) or --all-clients
+and skip the confirmation with --yes. With --atomic, either every client is
+updated or, if one fails, the ones already written are rolled back from the
+backups taken at the start of the run. When stdin is not a terminal, or with
+--non-interactive, apply fails instead of prompting.
+
+Some clients rewrite their own config files while running. If a client's file
+changes between being read and being written, apply merges again against the
+new content (--on-conflict=retry, the default) or leaves that client unwritten
+with a conflict error (--on-conflict=abort).`,
 	Run: func(cmd *cobra.Command, args []string) {
-		log.Info("Preparing to apply MCP configuration...")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		runApply(cmd, dryRun)
+	},
+}
 
-		// 1. Load configurations
-		cfg, err := config.LoadConfig()
-		if err != nil {
-			log.Fatal("Error loading config.yaml: %v", err)
-		}
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Shows the changes apply would make, without writing",
+	Long: `Renders each client's target configuration in memory and prints a unified diff
+against the current file. Nothing is written. This is the same as 'apply --dry-run'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runApply(cmd, true)
+	},
+}
 
-		mcpCfg, err := config.LoadMCPConfig()
+// runApply renders the configuration for the selected clients and, unless dryRun is set,
+// writes it after confirmation.
+func runApply(cmd *cobra.Command, dryRun bool) {
+	clientFilter, _ := cmd.Flags().GetStringArray("client")
+	allClients, _ := cmd.Flags().GetBool("all-clients")
+	assumeYes, _ := cmd.Flags().GetBool("yes")
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	atomic, _ := cmd.Flags().GetBool("atomic")
+	onConflict, _ := cmd.Flags().GetString("on-conflict")
+	if parallel < 1 {
+		parallel = 1
+	}
+	if !dryRun && onConflict != conflictRetry && onConflict != conflictAbort {
+		log.Fatal("Invalid --on-conflict value '%s': must be %s or %s", onConflict, conflictRetry, conflictAbort)
+	}
+
+	log.Info("Preparing to apply MCP configuration...")
+
+	// 1. Load configurations
+	validateMCPFile()
+	eff := loadEffective()
+	reportExcluded(eff)
+	cfg, mcpCfg := eff.Config, eff.MCP
+
+	// Get the list of available servers from mcp.json
+	if len(mcpCfg.MCPServers) == 0 {
+		log.Fatal("No MCP servers found in mcp.json. Please add a server configuration first.")
+	}
+
+	// Use the clients from config.yaml, or auto-detect installed clients
+	clients := resolveClients(cfg)
+	if len(clients) == 0 {
+		log.Warn("No clients found to apply configuration to.")
+		return
+	}
+
+	trans := newTranslator(eff, dryRun)
+
+	// Select clients from flags, or prompt. A dry run only reads, so it plans every client by default.
+	var selectedClientMap map[string]config.Client
+	var err error
+	switch {
+	case len(clientFilter) > 0:
+		selectedClientMap, err = filterClients(clients, clientFilter)
 		if err != nil {
-			log.Fatal("Error loading mcp.json: %v", err)
+			log.Fatal("%v", err)
 		}
-
-		// Get the list of available servers from mcp.json
-		if len(mcpCfg.MCPServers) == 0 {
-			log.Fatal("No MCP servers found in mcp.json. Please add a server configuration first.")
+	case allClients || dryRun:
+		selectedClientMap = clients
+	default:
+		if err := canPrompt(cmd, "pass --client <name> or --all-clients"); err != nil {
+			log.Fatal("Cannot select clients: %v", err)
 		}
-
-		// Check if clients are defined in config
-		if len(cfg.Clients) == 0 {
-			log.Info("No clients defined in config.yaml. Detecting installed clients...")
-
-			// Auto-detect installed clients
-			detectedClients, err := util.DetectMCPClients()
-			if err != nil {
-				log.Warn("Error detecting clients: %v", err)
-			}
-
-			if len(detectedClients) == 0 {
-				log.Warn("No MCP-compatible clients detected on this system.")
-				return
-			}
-
-			// Use the detected clients
-			cfg.Clients = detectedClients
-			log.Success("Detected %d client(s) on your system!", len(detectedClients))
+		// The summaries only compare, so they must not run ${cmd:...} references
+		selectedClientMap = promptClientSelection(cfg, newTranslator(eff, true), clients)
+	}
+
+	if len(selectedClientMap) == 0 {
+		log.Warn("No clients selected. Nothing to apply.")
+		return
+	}
+
+	// 2. Render every selected client's target configuration in memory
+	results := renderClients(trans, selectedClientMap, parallel)
+	var renders []*translator.ClientRender
+	renderFailures := 0
+	for _, result := range results {
+		if result.Err != nil {
+			log.Error("Error rendering config for %s: %v", result.ClientName, result.Err)
+			renderFailures++
+			continue
 		}
-
-		if len(cfg.Clients) == 0 {
-			log.Warn("No clients found to apply configuration to.")
-			return
+		renders = append(renders, result.Render)
+	}
+	skippedServers := reportSkippedServers(results)
+
+	if dryRun {
+		changed := printPlan(renders)
+		log.Info("\nPlan: %d of %d client(s) would change. Nothing was written.", changed, len(renders))
+		if renderFailures > 0 {
+			log.Error("Failed to render %d clients.", renderFailures)
+			os.Exit(1)
 		}
-
-		// Create a list of client names for selection
-		var clientNames []string
-		for name := range cfg.Clients {
-			clientNames = append(clientNames, name)
+		if skippedServers > 0 {
+			os.Exit(1)
 		}
-		clientNames = append(clientNames, "ALL") // Add option to select all clients
-
-		// Let user choose which client to apply to (only one selection allowed)
-		var selectedClient string
-		clientPrompt := &survey.Select{
-			Message: "Select client to apply MCP configuration to:",
-			Options: clientNames,
-			Default: "ALL", // Default to ALL
+		return
+	}
+
+	// Generate client list for display
+	clientList := ""
+	for _, render := range renders {
+		clientList += fmt.Sprintf("  - %s\n", render.ClientName)
+	}
+
+	// Generate server list for display
+	serverList := ""
+	for _, serverName := range sortedServerNames(mcpCfg.MCPServers) {
+		serverList += fmt.Sprintf("  - %s\n", serverName)
+	}
+
+	// Ask for confirmation, optionally showing the diff first
+	confirmMessage := fmt.Sprintf("This will apply ALL MCP server configurations to the following clients:\n%s\nThe following MCP servers will be applied:\n%s\nBackups will be created. Do you want to continue?", clientList, serverList)
+	if assumeYes {
+		log.Info("Applying to %d client(s) without confirmation (--yes).", len(renders))
+	} else if err := canPrompt(cmd, "pass --yes to apply without confirmation"); err != nil {
+		log.Fatal("Cannot ask for confirmation: %v", err)
+	}
+	for !assumeYes {
+		var answer string
+		prompt := &survey.Select{
+			Message: confirmMessage,
+			Options: []string{confirmApply, confirmShowDiff, confirmCancel},
+			Default: confirmCancel, // Safer default - user must explicitly choose yes
 		}
 
-		// Use AskOne with single selection
-		err = survey.AskOne(clientPrompt, &selectedClient, survey.WithValidator(survey.Required))
+		err = survey.AskOne(prompt, &answer)
 		if err != nil {
-			log.Fatal("Error during client selection: %v", err)
+			log.Fatal("Error during confirmation: %v", err)
 		}
 
-		// Process selection
-		applyToAllClients := selectedClient == "ALL"
-
-		// Create a filtered client map
-		selectedClientMap := make(map[string]config.Client)
-		if applyToAllClients {
-			selectedClientMap = cfg.Clients // Use all clients
-		} else {
-			// Only include the single selected client
-			if client, ok := cfg.Clients[selectedClient]; ok {
-				selectedClientMap[selectedClient] = client
-			}
+		if answer == confirmShowDiff {
+			printPlan(renders)
+			continue
 		}
-
-		if len(selectedClientMap) == 0 {
-			log.Warn("No clients selected. Nothing to apply.")
+		if answer != confirmApply {
+			log.Info("Operation cancelled by user.")
 			return
 		}
-
-		// Generate client list for display
-		clientList := ""
-		for clientName := range selectedClientMap {
-			clientList += fmt.Sprintf("  - %s\n", clientName)
+		break
+	}
+
+	// 3. Back up and write each client
+	log.Info("Processing clients...")
+	if atomic {
+		if !writeClientsAtomically(trans, selectedClientMap, results, parallel, onConflict) {
+			printApplySummary(results)
+			for _, result := range results {
+				if result.Written && !result.RolledBack {
+					log.Fatal("Apply failed and %s could not be rolled back. Run 'mcpenetes restore' to recover it.", result.ClientName)
+				}
+			}
+			log.Fatal("Apply failed; every client written in this run was rolled back.")
 		}
-
-		// Generate server list for display
-		serverList := ""
-		for serverName := range mcpCfg.MCPServers {
-			serverList += fmt.Sprintf("  - %s\n", serverName)
+	} else {
+		writeClients(trans, selectedClientMap, results, parallel, onConflict)
+	}
+
+	clientSuccessCount := 0
+	clientFailureCount := 0
+	printApplySummary(results)
+	for _, result := range results {
+		if result.Err != nil {
+			clientFailureCount++
+		} else {
+			clientSuccessCount++
 		}
+	}
+
+	log.Info("\nApply operation finished.")
+	log.Success("Successfully applied %d server configurations across %d clients.", len(mcpCfg.MCPServers), clientSuccessCount)
+	if clientFailureCount > 0 {
+		log.Error("Failed to apply to %d clients.", clientFailureCount)
+		os.Exit(1) // Exit with error if any client failed
+	}
+	if skippedServers > 0 {
+		log.Error("%d server(s) were not applied because a secret reference or template variable could not be resolved.", skippedServers)
+		os.Exit(1)
+	}
+}
 
-		// Ask for confirmation
-		confirmMessage := fmt.Sprintf("This will apply ALL MCP server configurations to the following clients:\n%s\nThe following MCP servers will be applied:\n%s\nBackups will be created. Do you want to continue?", clientList, serverList)
-		var confirm bool
-		prompt := &survey.Confirm{
-			Message: confirmMessage,
-			Default: false, // Safer default - user must explicitly choose yes
+// promptClientSelection lets the user pick any subset of clients. Each option shows the
+// client's config path and sync state, and the previous selection is preselected and saved
+// back to config.yaml.
+func promptClientSelection(cfg *config.Config, trans *translator.Translator, clients map[string]config.Client) map[string]config.Client {
+	clientNames := sortedClientNames(clients)
+
+	options := make([]string, 0, len(clientNames))
+	optionClients := make(map[string]string, len(clientNames))
+	var defaults []string
+	for _, clientName := range clientNames {
+		option := fmt.Sprintf("%s  (%s) [%s]", clientName, clients[clientName].ConfigPath, clientSyncSummary(trans, clientName, clients[clientName]))
+		options = append(options, option)
+		optionClients[option] = clientName
+		if slices.Contains(cfg.LastClients, clientName) {
+			defaults = append(defaults, option)
 		}
-
-		err = survey.AskOne(prompt, &confirm)
+	}
+	if len(defaults) == 0 {
+		defaults = options // Nothing remembered yet, preselect every client
+	}
+
+	var selectedOptions []string
+	clientPrompt := &survey.MultiSelect{
+		Message: "Select clients to apply MCP configuration to:",
+		Options: options,
+		Default: defaults,
+	}
+
+	err := askOne(clientPrompt, &selectedOptions, survey.WithValidator(survey.Required))
+	if err != nil {
+		log.Fatal("Error during client selection: %v", err)
+	}
+
+	// Create a filtered client map
+	selectedClientMap := make(map[string]config.Client)
+	var selectedNames []string
+	for _, option := range selectedOptions {
+		clientName := optionClients[option]
+		selectedClientMap[clientName] = clients[clientName]
+		selectedNames = append(selectedNames, clientName)
+	}
+
+	// Remember the selection for next time
+	if !slices.Equal(cfg.LastClients, selectedNames) {
+		cfg.LastClients = selectedNames
+		err := config.UpdateConfig(func(latest *config.Config) error {
+			latest.LastClients = selectedNames
+			return nil
+		})
 		if err != nil {
-			log.Fatal("Error during confirmation: %v", err)
-		}
-
-		if !confirm {
-			log.Info("Operation cancelled by user.")
-			return
+			log.Warn("Failed to remember client selection: %v", err)
 		}
+	}
 
-		// Create Translator
-		trans := translator.NewTranslator(cfg, mcpCfg)
-
-		// Process all clients and all servers
-		log.Info("Processing clients and servers...")
-		clientSuccessCount := 0
-		clientFailureCount := 0
-		totalOperations := 0
-
-		// For each selected client
-		for clientName, clientConf := range selectedClientMap {
-			log.Printf(log.InfoColor, "- Processing client: %s\n", clientName)
+	return selectedClientMap
+}
 
-			// Backup client config once before making any changes
-			backupPath, err := trans.BackupClientConfig(clientName, clientConf)
-			if err != nil {
-				log.Error("  Error backing up config for %s: %v", clientName, err)
-				clientFailureCount++
-				continue // Skip this client if backup failed
-			}
-			log.Success("  Created backup at: %s", backupPath)
-
-			clientSuccess := true
-
-			// Apply each server configuration to this client
-			for serverName, serverConf := range mcpCfg.MCPServers {
-				log.Printf(log.InfoColor, "  - Applying server: %s\n", serverName)
-
-				// Translate and Apply
-				err = trans.TranslateAndApply(clientName, clientConf, serverConf)
-				if err != nil {
-					log.Error("    Error applying server %s to client %s: %v", serverName, clientName, err)
-					clientSuccess = false
-				} else {
-					log.Success("    Successfully applied server %s to client %s", serverName, clientName)
-					totalOperations++
-				}
-			}
+// clientSyncSummary returns a short description of how a client compares to mcp.json.
+func clientSyncSummary(trans *translator.Translator, clientName string, clientConf config.Client) string {
+	render, err := trans.RenderClientConfig(clientName, clientConf)
+	if err != nil {
+		return "error"
+	}
+	statuses, err := translator.ClientStatus(render)
+	if err != nil {
+		return "error"
+	}
+
+	drifting := 0
+	for _, status := range statuses {
+		if status.State != translator.StateInSync && !commandsNotRun(render.Skipped[status.Name]) {
+			drifting++
+		}
+	}
+	if drifting == 0 {
+		return "in sync"
+	}
+	return fmt.Sprintf("%d server(s) out of sync", drifting)
+}
 
-			// Remove servers that no longer exist in the MCP configuration
-			log.Printf(log.InfoColor, "  - Checking for obsolete servers\n")
-			err = trans.RemoveClientServers(clientName, clientConf)
-			if err != nil {
-				log.Error("    Error removing obsolete servers from client %s: %v", clientName, err)
-				clientSuccess = false
-			}
+// printPlan prints a colorized unified diff for every client that would change
+// and returns the number of changed clients.
+func printPlan(renders []*translator.ClientRender) int {
+	changed := 0
+	for _, render := range renders {
+		if !render.Changed() {
+			log.Detail("%s: no changes (%s)", render.ClientName, render.Path)
+			continue
+		}
+		changed++
 
-			if clientSuccess {
-				clientSuccessCount++
-			} else {
-				clientFailureCount++
-			}
+		fromName := render.Path
+		if !render.Exists {
+			fromName = "/dev/null"
 		}
+		log.Printf(log.InfoColor, "\n%s:\n", render.ClientName)
+		printUnifiedDiff(diff.Unified(fromName, render.Path, render.Current, render.Rendered, diff.DefaultContext))
+	}
+	return changed
+}
 
-		log.Info("\nApply operation finished.")
-		log.Success("Successfully applied %d server configurations across %d clients.", totalOperations, clientSuccessCount)
-		if clientFailureCount > 0 {
-			log.Error("Failed to apply to %d clients.", clientFailureCount)
-			os.Exit(1) // Exit with error if any client failed
+// printUnifiedDiff prints a unified diff, coloring additions, removals and hunk headers.
+func printUnifiedDiff(unified string) {
+	for _, line := range strings.SplitAfter(unified, "\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			log.Printf(log.DetailColor, "%s", line)
+		case strings.HasPrefix(line, "@@"):
+			log.Printf(log.InfoColor, "%s", line)
+		case strings.HasPrefix(line, "+"):
+			log.Printf(log.SuccessColor, "%s", line)
+		case strings.HasPrefix(line, "-"):
+			log.Printf(log.ErrorColor, "%s", line)
+		default:
+			fmt.Print(log.Redact(line))
 		}
-	},
+	}
 }
 
 func init() {
 	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(planCmd)
+
+	applyCmd.Flags().Bool("dry-run", false, "Print the changes as unified diffs without writing anything")
+	applyCmd.Flags().BoolP("yes", "y", false, "Apply without asking for confirmation")
+	applyCmd.Flags().StringArray("client", nil, "Apply only to the given client (can be repeated)")
+	applyCmd.Flags().Bool("all-clients", false, "Apply to all clients without prompting for a selection")
+	applyCmd.Flags().Bool("atomic", false, "All-or-nothing: roll back every client written in this run if any client fails")
+	applyCmd.Flags().Int("parallel", defaultParallelism, "Maximum number of clients processed concurrently")
+	applyCmd.Flags().String("on-conflict", conflictRetry, "When a client rewrites its config during apply: retry (merge again against the new content) or abort")
+
+	planCmd.Flags().StringArray("client", nil, "Only plan the given client (can be repeated)")
+	planCmd.Flags().Int("parallel", defaultParallelism, "Maximum number of clients rendered concurrently")
 }
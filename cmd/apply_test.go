@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/translator"
+)
+
+// fakeClientPicker answers the client picker with the options pick returns, after handing
+// the prompt to pick for inspection.
+func fakeClientPicker(t *testing.T, pick func(prompt *survey.MultiSelect) []string) {
+	t.Helper()
+	original := askOne
+	askOne = func(p survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+		prompt, ok := p.(*survey.MultiSelect)
+		if !ok {
+			t.Fatalf("expected a multi-select prompt, got %T", p)
+		}
+		*response.(*[]string) = pick(prompt)
+		return nil
+	}
+	t.Cleanup(func() { askOne = original })
+}
+
+// pickClients loads the configuration and runs the client picker over every client.
+func pickClients(t *testing.T) map[string]config.Client {
+	t.Helper()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mcpCfg, err := config.LoadMCPConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return promptClientSelection(cfg, translator.NewTranslator(cfg, mcpCfg), cfg.Clients)
+}
+
+// lastClients returns the client selection saved in config.yaml.
+func lastClients(t *testing.T) []string {
+	t.Helper()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg.LastClients
+}
+
+func TestPromptClientSelection(t *testing.T) {
+	clientPaths := statusFixture(t)
+	if err := os.WriteFile(clientPaths["cursor"], []byte(`{"mcpServers": {}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	claudeOption := "claude-desktop  (" + clientPaths["claude-desktop"] + ") [in sync]"
+	cursorOption := "cursor  (" + clientPaths["cursor"] + ") [1 server(s) out of sync]"
+
+	// Nothing remembered yet: every client is preselected
+	fakeClientPicker(t, func(prompt *survey.MultiSelect) []string {
+		if want := []string{claudeOption, cursorOption}; !reflect.DeepEqual(prompt.Options, want) {
+			t.Errorf("expected options %q, got %q", want, prompt.Options)
+		}
+		if !reflect.DeepEqual(prompt.Default, prompt.Options) {
+			t.Errorf("expected every client to be preselected, got %v", prompt.Default)
+		}
+		return []string{cursorOption}
+	})
+	selected := pickClients(t)
+	if len(selected) != 1 || selected["cursor"].ConfigPath != clientPaths["cursor"] {
+		t.Errorf("expected only cursor to be selected, got %+v", selected)
+	}
+	if last := lastClients(t); !slices.Equal(last, []string{"cursor"}) {
+		t.Errorf("expected the selection to be saved, got %v", last)
+	}
+
+	// The saved selection is preselected next time, and a new selection replaces it
+	fakeClientPicker(t, func(prompt *survey.MultiSelect) []string {
+		if !reflect.DeepEqual(prompt.Default, []string{cursorOption}) {
+			t.Errorf("expected the last selection to be preselected, got %v", prompt.Default)
+		}
+		return []string{claudeOption, cursorOption}
+	})
+	if selected := pickClients(t); len(selected) != 2 {
+		t.Errorf("expected both clients to be selected, got %+v", selected)
+	}
+	if last := lastClients(t); !slices.Equal(last, []string{"claude-desktop", "cursor"}) {
+		t.Errorf("expected the new selection to be saved, got %v", last)
+	}
+}
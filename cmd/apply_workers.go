@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/log"
+	"github.com/tuannvm/mcpenetes/internal/translator"
+)
+
+// defaultParallelism bounds how many clients are rendered and written at the same time.
+const defaultParallelism = 4
+
+// What to do when a client rewrote its config file between being read and being written
+const (
+	conflictRetry = "retry"
+	conflictAbort = "abort"
+)
+
+// writeClientConfig writes a client's render. Tests replace it to inject failures.
+var writeClientConfig = (*translator.Translator).WriteClientConfig
+
+// clientResult collects the outcome of applying to one client. Workers never print;
+// their messages are kept in Logs and printed in client order once every worker is done.
+type clientResult struct {
+	ClientName string
+	Render     *translator.ClientRender
+	BackupPath string
+	Written    bool
+	RolledBack bool
+	Logs       []string
+	Err        error
+}
+
+// logf records a message for the client's summary.
+func (r *clientResult) logf(format string, a ...interface{}) {
+	r.Logs = append(r.Logs, fmt.Sprintf(format, a...))
+}
+
+// runParallel calls fn for every index in [0, n) with at most parallel calls in flight.
+func runParallel(n, parallel int, fn func(i int)) {
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// renderClients renders every client concurrently. Results are sorted by client name.
+func renderClients(trans *translator.Translator, clients map[string]config.Client, parallel int) []*clientResult {
+	clientNames := sortedClientNames(clients)
+	results := make([]*clientResult, len(clientNames))
+	runParallel(len(clientNames), parallel, func(i int) {
+		result := &clientResult{ClientName: clientNames[i]}
+		result.Render, result.Err = trans.RenderClientConfig(clientNames[i], clients[clientNames[i]])
+		results[i] = result
+	})
+	return results
+}
+
+// writeClients backs up and writes every successfully rendered client concurrently.
+func writeClients(trans *translator.Translator, clients map[string]config.Client, results []*clientResult, parallel int, onConflict string) {
+	runParallel(len(results), parallel, func(i int) {
+		result := results[i]
+		if result.Err != nil {
+			return
+		}
+
+		if !result.Render.Changed() {
+			result.logf("Already up to date")
+			return
+		}
+
+		// Hold the client's lock while backing up and writing
+		l, err := config.LockClientConfig(result.Render.Path)
+		if err != nil {
+			result.Err = err
+			return
+		}
+		defer func() { _ = l.Release() }()
+
+		// Backup client config once before making any changes
+		backupPath, err := trans.BackupClientConfig(result.ClientName, clients[result.ClientName])
+		if err != nil {
+			result.Err = fmt.Errorf("error backing up config: %w", err)
+			return // Skip this client if backup failed
+		}
+		result.BackupPath = backupPath
+		if backupPath != "" {
+			result.logf("Created backup at: %s", backupPath)
+		}
+
+		written, err := writeRender(trans, clients[result.ClientName], result, onConflict)
+		if err != nil {
+			result.Err = err
+			return
+		}
+		if written {
+			result.Written = true
+			result.logf("Wrote %s", result.Render.Path)
+		}
+	})
+}
+
+// writeRender writes a client's render. If the client rewrote its config file since the render
+// read it, the render is merged again against the new content, backed up again and retried,
+// unless onConflict is conflictAbort. It reports whether anything was written.
+func writeRender(trans *translator.Translator, clientConf config.Client, result *clientResult, onConflict string) (bool, error) {
+	for attempt := 0; ; attempt++ {
+		err := writeClientConfig(trans, result.Render)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, translator.ErrConflict) {
+			return false, err
+		}
+		if onConflict == conflictAbort {
+			return false, fmt.Errorf("%w; not written (--on-conflict=abort)", err)
+		}
+		if attempt >= translator.MaxConflictRetries {
+			return false, fmt.Errorf("%w; gave up after %d retries", err, attempt)
+		}
+
+		if err := trans.Rerender(result.Render); err != nil {
+			return false, err
+		}
+		result.logf("Config changed on disk while applying; merged again against the new content")
+		if !result.Render.Changed() {
+			result.logf("Already up to date")
+			return false, nil
+		}
+
+		backupPath, err := trans.BackupClientConfig(result.ClientName, clientConf)
+		if err != nil {
+			return false, fmt.Errorf("error backing up config: %w", err)
+		}
+		if backupPath != "" {
+			result.BackupPath = backupPath
+			result.logf("Created backup at: %s", backupPath)
+		}
+	}
+}
+
+// writeClientsAtomically applies all-or-nothing: every changed client is backed up before
+// anything is written, and if any write fails the clients already written are rolled back
+// from the backups taken at the start of this run. It returns whether the changes were kept.
+func writeClientsAtomically(trans *translator.Translator, clients map[string]config.Client, results []*clientResult, parallel int, onConflict string) bool {
+	var staged []*clientResult
+	var failed []string
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result.ClientName)
+			continue
+		}
+		if !result.Render.Changed() {
+			result.logf("Already up to date")
+			continue
+		}
+		staged = append(staged, result)
+	}
+
+	if len(failed) > 0 {
+		abortStaged(staged, "rendering failed for %v", failed)
+		return false
+	}
+
+	// Lock every staged client for the whole transaction, in a fixed order to avoid deadlocks
+	sort.Slice(staged, func(i, j int) bool { return staged[i].Render.Path < staged[j].Render.Path })
+	for _, result := range staged {
+		l, err := config.LockClientConfig(result.Render.Path)
+		if err != nil {
+			result.Err = err
+			abortStaged(staged, "a lock could not be acquired for %v", []string{result.ClientName})
+			return false
+		}
+		defer func() { _ = l.Release() }()
+	}
+
+	// Back up every staged client first; a single failure aborts before anything is written
+	var mu sync.Mutex
+	runParallel(len(staged), parallel, func(i int) {
+		result := staged[i]
+		backupPath, err := trans.BackupClientConfig(result.ClientName, clients[result.ClientName])
+		if err != nil {
+			result.Err = fmt.Errorf("error backing up config: %w", err)
+			mu.Lock()
+			failed = append(failed, result.ClientName)
+			mu.Unlock()
+			return
+		}
+		result.BackupPath = backupPath
+		if backupPath != "" {
+			result.logf("Created backup at: %s", backupPath)
+		}
+	})
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		abortStaged(staged, "backups failed for %v", failed)
+		return false
+	}
+
+	runParallel(len(staged), parallel, func(i int) {
+		result := staged[i]
+		written, err := writeRender(trans, clients[result.ClientName], result, onConflict)
+		if err != nil {
+			result.Err = err
+			mu.Lock()
+			failed = append(failed, result.ClientName)
+			mu.Unlock()
+			return
+		}
+		if written {
+			result.Written = true
+			result.logf("Wrote %s", result.Render.Path)
+		}
+	})
+	if len(failed) == 0 {
+		return true
+	}
+	sort.Strings(failed)
+
+	// Roll back the clients that were written
+	runParallel(len(staged), parallel, func(i int) {
+		result := staged[i]
+		if !result.Written {
+			return
+		}
+		result.Err = fmt.Errorf("rolled back because writing failed for %v", failed)
+		if err := trans.RestoreClientConfig(result.Render, result.BackupPath); err != nil {
+			result.Err = fmt.Errorf("rollback failed: %w", err)
+			return
+		}
+		result.RolledBack = true
+		if result.BackupPath != "" {
+			result.logf("Rolled back from %s", result.BackupPath)
+		} else {
+			result.logf("Rolled back by removing %s, which did not exist before", result.Render.Path)
+		}
+	})
+	return false
+}
+
+// abortStaged marks the staged clients that have no error of their own as not written.
+func abortStaged(staged []*clientResult, reason string, failed []string) {
+	for _, result := range staged {
+		if result.Err == nil {
+			result.Err = fmt.Errorf("not written: transaction aborted because "+reason, failed)
+		}
+	}
+}
+
+// reportSkippedServers prints the servers each render had to skip because a secret reference
+// or template variable could not be resolved, and returns how many were skipped in total.
+// Servers skipped only because a read-only command doesn't run ${cmd:...} references are
+// mentioned but not counted.
+func reportSkippedServers(results []*clientResult) int {
+	skipped := 0
+	for _, result := range results {
+		if result.Render == nil {
+			continue
+		}
+		serverIDs := make([]string, 0, len(result.Render.Skipped))
+		for serverID := range result.Render.Skipped {
+			serverIDs = append(serverIDs, serverID)
+		}
+		sort.Strings(serverIDs)
+		for _, serverID := range serverIDs {
+			if err := result.Render.Skipped[serverID]; commandsNotRun(err) {
+				log.Info("Server '%s' not rendered for %s: %v", serverID, result.ClientName, err)
+				continue
+			}
+			log.Error("Server '%s' not applied to %s: %v", serverID, result.ClientName, result.Render.Skipped[serverID])
+			skipped++
+		}
+	}
+	return skipped
+}
+
+// printApplySummary prints the collected results, one block per client in client order.
+func printApplySummary(results []*clientResult) {
+	for _, result := range results {
+		if result.Err != nil {
+			log.Error("- %s: %v", result.ClientName, result.Err)
+		} else {
+			log.Success("- %s", result.ClientName)
+		}
+		for _, line := range result.Logs {
+			log.Detail("    %s", line)
+		}
+	}
+}
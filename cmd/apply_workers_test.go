@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/paths"
+	"github.com/tuannvm/mcpenetes/internal/translator"
+)
+
+// applyFixture sets up a config directory, a backup directory and one client config per entry
+// of existing (an empty value means the client has no config file yet).
+func applyFixture(t *testing.T, existing map[string]string) (*translator.Translator, map[string]config.Client) {
+	t.Helper()
+	dir := t.TempDir()
+	paths.SetConfigDir(filepath.Join(dir, "config"))
+	t.Cleanup(func() { paths.SetConfigDir("") })
+
+	appCfg := config.GetDefaultConfig()
+	appCfg.Backups.Path = filepath.Join(dir, "backups")
+	mcpCfg := &config.MCPConfig{MCPServers: map[string]config.MCPServer{
+		"fetch": {Command: "uvx", Args: []string{"mcp-server-fetch"}},
+	}}
+
+	clients := make(map[string]config.Client)
+	for name, content := range existing {
+		clientPath := filepath.Join(dir, name, "mcp.json")
+		if content != "" {
+			if err := os.MkdirAll(filepath.Dir(clientPath), 0750); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(clientPath, []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		clients[name] = config.Client{ConfigPath: clientPath}
+	}
+	return translator.NewTranslator(appCfg, mcpCfg), clients
+}
+
+// injectWrite replaces writeClientConfig for the test; fn is called with the real writer.
+func injectWrite(t *testing.T, fn func(write func() error, render *translator.ClientRender) error) {
+	t.Helper()
+	original := writeClientConfig
+	writeClientConfig = func(trans *translator.Translator, render *translator.ClientRender) error {
+		return fn(func() error { return original(trans, render) }, render)
+	}
+	t.Cleanup(func() { writeClientConfig = original })
+}
+
+func readFile(t *testing.T, path string) (string, bool) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data), true
+}
+
+func TestRunParallel(t *testing.T) {
+	for _, parallel := range []int{1, 2, 4, 16} {
+		var inFlight, maxInFlight int32
+		var mu sync.Mutex
+		seen := make(map[int]int)
+		runParallel(10, parallel, func(i int) {
+			n := atomic.AddInt32(&inFlight, 1)
+			mu.Lock()
+			if n > maxInFlight {
+				maxInFlight = n
+			}
+			seen[i]++
+			mu.Unlock()
+			atomic.AddInt32(&inFlight, -1)
+		})
+		if len(seen) != 10 {
+			t.Errorf("parallel %d: expected 10 calls, got %v", parallel, seen)
+		}
+		for i, count := range seen {
+			if count != 1 {
+				t.Errorf("parallel %d: index %d called %d times", parallel, i, count)
+			}
+		}
+		if int(maxInFlight) > parallel {
+			t.Errorf("parallel %d: %d calls were in flight at once", parallel, maxInFlight)
+		}
+	}
+}
+
+func TestWriteClientsAtomically(t *testing.T) {
+	const original = `{"mcpServers": {}, "theme": "dark"}`
+	tests := []struct {
+		name       string
+		renderFail string // Client whose render failed
+		writeFail  string // Client whose write fails
+		wantKept   bool
+	}{
+		{name: "all written", wantKept: true},
+		{name: "write fails", writeFail: "b"},
+		{name: "first write fails", writeFail: "a"},
+		{name: "client without a config fails", writeFail: "c"},
+		{name: "render fails", renderFail: "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trans, clients := applyFixture(t, map[string]string{"a": original, "b": original, "c": ""})
+			var mu sync.Mutex
+			var attempted []string
+			injectWrite(t, func(write func() error, render *translator.ClientRender) error {
+				mu.Lock()
+				attempted = append(attempted, render.ClientName)
+				mu.Unlock()
+				if render.ClientName == tt.writeFail {
+					return errors.New("disk full")
+				}
+				return write()
+			})
+
+			results := renderClients(trans, clients, 2)
+			for _, result := range results {
+				if result.ClientName == tt.renderFail {
+					result.Err = errors.New("render failed")
+				}
+			}
+			kept := writeClientsAtomically(trans, clients, results, 2, conflictRetry)
+			if kept != tt.wantKept {
+				t.Fatalf("expected kept=%v, got %v", tt.wantKept, kept)
+			}
+			if tt.renderFail != "" && len(attempted) > 0 {
+				t.Errorf("expected nothing to be written after a render failure, wrote %v", attempted)
+			}
+
+			for _, result := range results {
+				content, exists := readFile(t, clients[result.ClientName].ConfigPath)
+				if tt.wantKept {
+					if result.Err != nil || !result.Written || !strings.Contains(content, "mcp-server-fetch") {
+						t.Errorf("%s: expected to be written, got err %v and:\n%s", result.ClientName, result.Err, content)
+					}
+					continue
+				}
+
+				// Every client is back to what it was before the run
+				if result.ClientName == "c" {
+					if exists {
+						t.Errorf("c: expected the new config to be removed again, got:\n%s", content)
+					}
+				} else if content != original {
+					t.Errorf("%s: expected the original config, got:\n%s", result.ClientName, content)
+				}
+				if result.Err == nil {
+					t.Errorf("%s: expected an error explaining why it was not applied", result.ClientName)
+				}
+				if result.Written && !result.RolledBack {
+					t.Errorf("%s: written but not rolled back", result.ClientName)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteRenderConflicts(t *testing.T) {
+	tests := []struct {
+		name       string
+		conflicts  int // How many writes find the file changed by the client
+		onConflict string
+		wantWrites int
+		wantErr    string
+	}{
+		{name: "no conflict", onConflict: conflictRetry, wantWrites: 1},
+		{name: "retried once", conflicts: 1, onConflict: conflictRetry, wantWrites: 2},
+		{name: "retry limit", conflicts: 100, onConflict: conflictRetry, wantWrites: translator.MaxConflictRetries + 1, wantErr: "gave up after"},
+		{name: "abort", conflicts: 1, onConflict: conflictAbort, wantWrites: 1, wantErr: "--on-conflict=abort"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trans, clients := applyFixture(t, map[string]string{"a": `{"mcpServers": {}}`})
+			path := clients["a"].ConfigPath
+			writes := 0
+			injectWrite(t, func(write func() error, render *translator.ClientRender) error {
+				writes++
+				if writes <= tt.conflicts {
+					// The client rewrites its own config just before mcpenetes writes it
+					edit := `{"mcpServers": {}, "edits": ` + strings.Repeat("1", writes) + `}`
+					if err := os.WriteFile(path, []byte(edit), 0600); err != nil {
+						t.Fatal(err)
+					}
+				}
+				return write()
+			})
+
+			results := renderClients(trans, clients, 1)
+			writeClients(trans, clients, results, 1, tt.onConflict)
+			result := results[0]
+			if writes != tt.wantWrites {
+				t.Errorf("expected %d writes, got %d", tt.wantWrites, writes)
+			}
+			if tt.wantErr == "" {
+				content, _ := readFile(t, path)
+				if result.Err != nil || !result.Written || !strings.Contains(content, "mcp-server-fetch") {
+					t.Fatalf("expected the server to be written, got err %v and:\n%s", result.Err, content)
+				}
+				if tt.conflicts > 0 && !strings.Contains(content, `"edits"`) {
+					t.Errorf("expected the client's edit to be kept, got:\n%s", content)
+				}
+				return
+			}
+			if result.Err == nil || !errors.Is(result.Err, translator.ErrConflict) || !strings.Contains(result.Err.Error(), tt.wantErr) {
+				t.Errorf("expected a conflict error containing %q, got %v", tt.wantErr, result.Err)
+			}
+			if content, _ := readFile(t, path); strings.Contains(content, "mcp-server-fetch") {
+				t.Errorf("expected the client's config to be left alone, got:\n%s", content)
+			}
+		})
+	}
+}
+
+func TestAbortStaged(t *testing.T) {
+	own := errors.New("lock timeout")
+	staged := []*clientResult{{ClientName: "a"}, {ClientName: "b", Err: own}}
+	abortStaged(staged, "a lock could not be acquired for %v", []string{"b"})
+	if staged[0].Err == nil || !strings.Contains(staged[0].Err.Error(), "transaction aborted because a lock could not be acquired for [b]") {
+		t.Errorf("unexpected error for a: %v", staged[0].Err)
+	}
+	if staged[1].Err != own {
+		t.Errorf("expected b to keep its own error, got %v", staged[1].Err)
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/log"
+	"github.com/tuannvm/mcpenetes/internal/util"
+)
+
+// resolveClients returns the clients defined in config.yaml, falling back to
+// the clients detected on this system when none are defined.
+func resolveClients(cfg *config.Config) map[string]config.Client {
+	if len(cfg.Clients) > 0 {
+		return cfg.Clients
+	}
+
+	log.Info("No clients defined in config.yaml. Detecting installed clients...")
+	detectedClients, err := util.DetectMCPClients()
+	if err != nil {
+		log.Warn("Error detecting clients: %v", err)
+	}
+	if len(detectedClients) > 0 {
+		log.Success("Detected %d client(s) on your system!", len(detectedClients))
+	}
+	return detectedClients
+}
+
+// filterClients narrows clients down to the given names.
+// An empty list of names selects every client.
+func filterClients(clients map[string]config.Client, names []string) (map[string]config.Client, error) {
+	if len(names) == 0 {
+		return clients, nil
+	}
+
+	selected := make(map[string]config.Client)
+	var unknown []string
+	for _, name := range names {
+		client, ok := clients[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		selected[name] = client
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown client(s): %s (available: %s)", strings.Join(unknown, ", "), strings.Join(sortedClientNames(clients), ", "))
+	}
+	return selected, nil
+}
+
+// sortedClientNames returns the client names in a stable order.
+func sortedClientNames(clients map[string]config.Client) []string {
+	names := make([]string, 0, len(clients))
+	for name := range clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedServerNames returns the server names in a stable order.
+func sortedServerNames(servers map[string]config.MCPServer) []string {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
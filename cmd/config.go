@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/log"
+	"github.com/tuannvm/mcpenetes/internal/translator"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspects the layered configuration",
+	Long: `Parent command for inspecting the configuration merged from its layers:
+
+  system   /etc/mcpenetes (or $MCPENETES_SYSTEM_DIR), a machine-wide baseline
+  user     your config directory
+  project  .mcpenetes in the current directory or a parent, once trusted
+
+Later layers override earlier ones entry by entry, except for entries the
+system layer lists under 'locked' in its config.yaml. clients, backups and
+sync are only read from your own config.yaml.`,
+}
+
+// configExplainCmd represents the config explain command
+var configExplainCmd = &cobra.Command{
+	Use:   "explain [KEY]",
+	Short: "Shows which layer each configuration value comes from",
+	Long: `Shows the effective value of KEY and the value every layer gives it, with the file
+it is set in. KEY is a dotted path such as registries.glama, clients.cursor,
+backups.path or mcpServers.github; a prefix such as registries or mcpServers
+explains every key below it. Without KEY, every key is explained.`,
+	Example: `  mcpenetes config explain mcpServers.github
+  mcpenetes config explain registries`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		key := ""
+		if len(args) == 1 {
+			key = args[0]
+		}
+
+		eff := loadEffective()
+		keys := eff.Keys(key)
+		if len(keys) == 0 {
+			log.Fatal("No layer sets '%s'.", key)
+		}
+
+		for i, k := range keys {
+			if i > 0 {
+				log.Detail("")
+			}
+			settings := eff.Sources[k]
+			header := k
+			for _, setting := range settings {
+				if setting.Status == config.SettingEffective {
+					header = fmt.Sprintf("%s = %s  (%s)", k, setting.Value, setting.Layer)
+				}
+			}
+			if eff.IsLocked(k) {
+				header += "  [locked]"
+			}
+			if name, ok := strings.CutPrefix(k, "mcpServers."); ok {
+				if reason, excluded := eff.Excluded[name]; excluded {
+					header += fmt.Sprintf("  [skipped on this machine: %s]", reason)
+				}
+			}
+			log.Printf(log.InfoColor, "%s\n", header)
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, setting := range settings {
+				_, _ = fmt.Fprint(w, log.Redact(fmt.Sprintf("  %s\t%s\t%s\t%s\n", setting.Layer, setting.Status, setting.File, setting.Value)))
+			}
+			_ = w.Flush()
+		}
+	},
+}
+
+// loadEffective loads the configuration merged from every layer, warning about configured
+// values that are ignored (see warnIgnored).
+func loadEffective() *config.Effective {
+	eff, err := config.LoadEffective()
+	if err != nil {
+		log.Fatal("Error loading configuration: %v", err)
+	}
+	warnIgnored(eff)
+	return eff
+}
+
+// errCommandsReadOnly is why commands that only compare or preview don't run ${cmd:...} references.
+var errCommandsReadOnly = errors.New("they only run when client configs are written, not by status, diff or plan")
+
+// newTranslator returns a translator for the effective configuration. ${cmd:...} references
+// only run when client configs are written (not readOnly), if the user's config.yaml sets
+// allow_cmd_secrets, and only for servers defined in the user or system layer.
+func newTranslator(eff *config.Effective, readOnly bool) *translator.Translator {
+	trans := translator.NewTranslator(eff.Config, eff.MCP)
+	trans.Excluded = eff.Excluded
+	trans.Secrets.AllowCommands = !readOnly && eff.Config.AllowCmdSecrets
+	trans.CommandPolicy = func(serverID string) error {
+		if readOnly {
+			return errCommandsReadOnly
+		}
+		if !eff.Config.AllowCmdSecrets {
+			return errors.New("set 'allow_cmd_secrets: true' in your config.yaml to run them")
+		}
+		if layer := eff.EffectiveLayer("mcpServers." + serverID); layer != config.LayerUser && layer != config.LayerSystem {
+			return fmt.Errorf("server '%s' comes from the %s layer; they only run for servers from your own or the system configuration", serverID, layer)
+		}
+		return nil
+	}
+	return trans
+}
+
+// commandsNotRun reports whether a server was skipped only because a read-only command
+// does not run its ${cmd:...} references; it is not known to be out of sync.
+func commandsNotRun(err error) bool {
+	return errors.Is(err, errCommandsReadOnly)
+}
+
+// warnIgnored warns about every key whose user or project value was ignored because it is
+// locked, every value only the user layer may set that another layer tried to, and an
+// untrusted project layer.
+func warnIgnored(eff *config.Effective) {
+	for _, key := range eff.Ignored() {
+		log.Warn("'%s' is locked by the system configuration; the value set in your configuration is ignored (see 'mcpenetes config explain %s').", key, key)
+	}
+	for _, key := range eff.UserOnly() {
+		log.Warn("'%s' can only be set in your own config.yaml; the value from another layer is ignored (see 'mcpenetes config explain %s').", key, key)
+	}
+	if eff.Untrusted != nil {
+		log.Warn("Ignoring the project configuration in %s because %s. Review it, then run 'mcpenetes trust' to use it.", eff.Untrusted.Dir, eff.Untrusted.Reason)
+	}
+}
+
+// reportExcluded explains which servers are skipped on this machine because of their when condition.
+func reportExcluded(eff *config.Effective) {
+	names := make([]string, 0, len(eff.Excluded))
+	for name := range eff.Excluded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		log.Info("Skipping server '%s' on this machine: %s. Client entries named '%s' are left as they are.", name, eff.Excluded[name], name)
+	}
+}
+
+// fragmentFlag returns the mcp.d fragment named by --fragment, or "" when it is not set.
+func fragmentFlag(cmd *cobra.Command) string {
+	name, _ := cmd.Flags().GetString("fragment")
+	if name == "" {
+		return ""
+	}
+	fragment, err := config.FragmentName(name)
+	if err != nil {
+		log.Fatal("%v", err)
+	}
+	return fragment
+}
+
+// savedTo names the file servers are saved to: the fragment, or mcp.json without one.
+func savedTo(fragment string) string {
+	if fragment == "" {
+		return config.DefaultMCPFileName
+	}
+	return config.FragmentDirName + "/" + fragment
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configExplainCmd)
+}
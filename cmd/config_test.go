@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/paths"
+)
+
+func TestNewTranslatorCommandPolicy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	tests := []struct {
+		name     string
+		allow    bool
+		readOnly bool
+		ran      []string // Servers whose command runs
+	}{
+		{name: "not allowed", allow: false},
+		{name: "allowed, read-only", allow: true, readOnly: true},
+		{name: "allowed", allow: true, ran: []string{config.LayerSystem, config.LayerUser}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			paths.SetConfigDir(dir)
+			t.Cleanup(func() { paths.SetConfigDir("") })
+
+			// One server per layer, each touching a file named after its layer when its command runs
+			eff := &config.Effective{
+				Config:  &config.Config{AllowCmdSecrets: tt.allow},
+				MCP:     &config.MCPConfig{MCPServers: make(map[string]config.MCPServer)},
+				Sources: make(map[string][]config.Setting),
+			}
+			layers := []string{config.LayerSystem, config.LayerBaseline, config.LayerUser, config.LayerProject}
+			for _, layer := range layers {
+				eff.MCP.MCPServers[layer] = config.MCPServer{Command: "${cmd:touch " + filepath.Join(dir, layer) + " && echo x}"}
+				eff.Sources["mcpServers."+layer] = []config.Setting{{Layer: layer, Status: config.SettingEffective}}
+			}
+
+			trans := newTranslator(eff, tt.readOnly)
+			render, err := trans.RenderClientConfig("cursor", config.Client{ConfigPath: filepath.Join(dir, "cursor.json")})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, layer := range layers {
+				ran := false
+				for _, name := range tt.ran {
+					ran = ran || name == layer
+				}
+				if _, err := os.Stat(filepath.Join(dir, layer)); (err == nil) != ran {
+					t.Errorf("%s: expected the command to run: %v", layer, ran)
+				}
+				skipErr := render.Skipped[layer]
+				if (skipErr == nil) != ran {
+					t.Errorf("%s: expected to be skipped: %v, got %v", layer, !ran, skipErr)
+				}
+				if commandsNotRun(skipErr) != tt.readOnly {
+					t.Errorf("%s: expected the read-only reason: %v, got %v", layer, tt.readOnly, skipErr)
+				}
+			}
+		})
+	}
+}
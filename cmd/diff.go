@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tuannvm/mcpenetes/internal/log"
+	"github.com/tuannvm/mcpenetes/internal/translator"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Shows field-level differences between mcp.json and clients",
+	Long: `Compares every client's configuration with what 'apply' would write and prints
+the differences field by field:
+
+  + server   missing in the client, apply would add it
+  - server   not in mcp.json, apply would remove it
+  ~ server   modified, followed by each differing field
+  ! server   a secret reference or template variable could not be resolved, apply leaves it alone
+             (${cmd:...} references are never run by diff; such servers are not compared)
+
+Exit codes: 0 when every client is in sync, 2 when differences were found, 1 on errors.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		clientFilter, _ := cmd.Flags().GetStringArray("client")
+		os.Exit(runDiff(clientFilter))
+	},
+}
+
+// runDiff prints the differences of the selected clients and returns the exit code.
+func runDiff(clientFilter []string) int {
+	statuses, err := collectClientStatuses(clientFilter)
+	if err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+	if len(statuses) == 0 {
+		log.Warn("No clients found to compare.")
+		return 0
+	}
+
+	for _, status := range statuses {
+		if status.Err != nil {
+			log.Error("%s: %v", status.Name, status.Err)
+			continue
+		}
+		if !status.Drifting {
+			continue
+		}
+
+		log.Printf(log.InfoColor, "%s (%s)\n", status.Name, status.Render.Path)
+		for _, server := range status.Servers {
+			printServerDiff(server, status.Render.Skipped[server.Name])
+		}
+	}
+
+	exitCode := driftExitCode(statuses)
+	if exitCode == 0 {
+		log.Success("All clients are in sync with mcp.json.")
+	}
+	return exitCode
+}
+
+// printServerDiff prints the differences of a single server entry.
+// resolveErr is the reason an unresolved server was skipped.
+func printServerDiff(server translator.ServerStatus, resolveErr error) {
+	switch server.State {
+	case translator.StateUnresolved:
+		log.Printf(log.ErrorColor, "  ! %s (not applied: %v)\n", server.Name, resolveErr)
+	case translator.StateMissing:
+		log.Printf(log.SuccessColor, "  + %s (missing in client)\n", server.Name)
+		for _, field := range translator.DiffFields(nil, server.Desired) {
+			log.Printf(log.SuccessColor, "      %s: %s\n", field.Path, formatField(field.Path, field.Desired))
+		}
+	case translator.StateForeign:
+		log.Printf(log.ErrorColor, "  - %s (not in mcp.json, apply would remove it)\n", server.Name)
+	case translator.StateModified:
+		log.Printf(log.WarnColor, "  ~ %s\n", server.Name)
+		for _, field := range translator.DiffFields(server.Current, server.Desired) {
+			log.Printf(log.DetailColor, "      %s: ", field.Path)
+			log.Printf(log.ErrorColor, "%s", formatField(field.Path, field.Current))
+			log.Printf(log.DetailColor, " -> ")
+			log.Printf(log.SuccessColor, "%s\n", formatField(field.Path, field.Desired))
+		}
+	}
+}
+
+// formatField renders the value of a field for display, masking the secrets in it.
+func formatField(path string, value interface{}) string {
+	if log.ShowSecrets() {
+		return formatValue(value)
+	}
+	return formatValue(maskValue(path, value))
+}
+
+// maskValue returns a copy of value in which every string whose key path looks like a secret,
+// or which holds a resolved secret reference, is masked. Maps and lists are walked.
+func maskValue(path string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if v != "" && (log.IsSecretKey(path) || log.ContainsSecret(v)) {
+			return log.Mask
+		}
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			masked[key] = maskValue(path+"."+key, elem)
+		}
+		return masked
+	case []interface{}:
+		masked := make([]interface{}, len(v))
+		for i, elem := range v {
+			masked[i] = maskValue(path, elem)
+		}
+		return masked
+	}
+	return value
+}
+
+// formatValue renders a decoded JSON value for display.
+func formatValue(value interface{}) string {
+	if value == nil {
+		return "(unset)"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "?"
+	}
+	return string(data)
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringArray("client", nil, "Only compare the given client (can be repeated)")
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/diff"
+	"github.com/tuannvm/mcpenetes/internal/history"
+	"github.com/tuannvm/mcpenetes/internal/log"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Lists changes to config.yaml and mcp.json",
+	Long: `Lists the recorded changes to config.yaml and mcp.json, newest first. Every save
+by mcpenetes is recorded, as are edits made outside mcpenetes, which are noticed
+on the next save or undo. Use 'mcpenetes history show <id>' to see a change and
+'mcpenetes undo' to revert the most recent one.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		store, err := config.History()
+		if err != nil {
+			log.Fatal("Failed to open history: %v", err)
+		}
+		entries, err := store.Entries()
+		if err != nil {
+			log.Fatal("Failed to read history: %v", err)
+		}
+		if len(entries) == 0 {
+			log.Info("No changes recorded yet.")
+			return
+		}
+
+		undone := history.UndoneIDs(entries)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ID\tTIME\tFILE\tCHANGE\tCOMMAND")
+		for i, shown := len(entries)-1, 0; i >= 0 && (limit <= 0 || shown < limit); i, shown = i-1, shown+1 {
+			e := entries[i]
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.ID, e.Time.Local().Format("2006-01-02 15:04:05"), e.File, describeChange(e, undone), e.Command)
+		}
+		_ = w.Flush()
+	},
+}
+
+// historyShowCmd represents the history show command
+var historyShowCmd = &cobra.Command{
+	Use:   "show ID",
+	Short: "Shows one change to config.yaml or mcp.json",
+	Long: `Shows a recorded change as a unified diff. ID can be abbreviated to any unique
+prefix. With --content, the whole file as it was after the change is printed instead.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		showContent, _ := cmd.Flags().GetBool("content")
+
+		store, err := config.History()
+		if err != nil {
+			log.Fatal("Failed to open history: %v", err)
+		}
+		entry, err := store.Get(args[0])
+		if err != nil {
+			log.Fatal("%v", err)
+		}
+		entries, err := store.Entries()
+		if err != nil {
+			log.Fatal("Failed to read history: %v", err)
+		}
+
+		log.Info("%s: %s %s", entry.ID, entry.File, describeChange(*entry, history.UndoneIDs(entries)))
+		log.Detail("Time:    %s", entry.Time.Local().Format("2006-01-02 15:04:05"))
+		if entry.Command != "" {
+			log.Detail("Command: mcpenetes %s", entry.Command)
+		}
+		fmt.Println()
+
+		before, after := historyObject(store, entry.Previous), historyObject(store, entry.Hash)
+		if showContent {
+			if entry.Hash == "" {
+				log.Info("The file was removed by this change.")
+				return
+			}
+			_, _ = os.Stdout.Write([]byte(log.Redact(string(after))))
+			return
+		}
+		fromName, toName := entry.File, entry.File
+		if entry.Previous == "" {
+			fromName = "/dev/null"
+		}
+		if entry.Hash == "" {
+			toName = "/dev/null"
+		}
+		printUnifiedDiff(diff.Unified(fromName, toName, before, after, diff.DefaultContext))
+	},
+}
+
+// undoCmd represents the undo command
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Reverts the most recent change to config.yaml or mcp.json",
+	Long: `Reverts the most recent change to config.yaml or mcp.json recorded in the history,
+e.g. a server definition replaced by 'mcpenetes load'. Run it again to revert
+the change before that. Run 'mcpenetes apply' afterwards to update your clients.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entry, err := config.Undo()
+		if err != nil {
+			log.Fatal("Undo failed: %v", err)
+		}
+		summary := fmt.Sprintf("Reverted %s: %s of %s", entry.ID, entry.Action, entry.File)
+		if entry.Command != "" {
+			summary += fmt.Sprintf(" by 'mcpenetes %s'", entry.Command)
+		}
+		log.Success("%s (%s).", summary, entry.Time.Local().Format("2006-01-02 15:04:05"))
+		log.Info("Run 'mcpenetes apply' to update your clients.")
+	},
+}
+
+// describeChange names the kind of change an entry records.
+func describeChange(e history.Entry, undone map[string]bool) string {
+	description := e.Action
+	switch {
+	case e.Action == history.ActionUndo:
+		description = "undo of " + e.Undoes
+	case e.Action == history.ActionEdit:
+		description = "edited outside mcpenetes"
+	case e.Previous == "" && e.Action == history.ActionSave:
+		description = "created"
+	}
+	if undone[e.ID] {
+		description += " (undone)"
+	}
+	return description
+}
+
+// historyObject returns a recorded version of a file, or nothing for a missing file.
+func historyObject(store *history.Store, hash string) []byte {
+	if hash == "" {
+		return nil
+	}
+	data, err := store.Object(hash)
+	if err != nil {
+		log.Fatal("%v", err)
+	}
+	return data
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	rootCmd.AddCommand(undoCmd)
+
+	historyCmd.Flags().IntP("limit", "n", 20, "Number of changes to list; 0 lists all")
+	historyShowCmd.Flags().Bool("content", false, "Print the whole file after the change instead of a diff")
+}
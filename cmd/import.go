@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/log"
+	"github.com/tuannvm/mcpenetes/internal/translator"
+)
+
+// Conflict strategies accepted by the import command
+const (
+	strategyPrompt  = "prompt"
+	strategyKeep    = "keep"
+	strategyReplace = "replace"
+	strategyRename  = "rename"
+)
+
+// importCandidate is a server definition found in a client's configuration.
+type importCandidate struct {
+	Client string
+	Server config.MCPServer
+}
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Imports existing client configurations into mcp.json",
+	Long: `Reads the MCP servers already configured in each client's native format
+and merges them into mcp.json.
+
+Identical definitions found in several clients are imported once. When a server
+name has different definitions (across clients or compared to mcp.json), the
+--strategy flag decides what happens:
+
+  prompt   ask for each conflict (default)
+  keep     keep the definition that was seen first (mcp.json wins)
+  replace  use the definition that was seen last
+  rename   keep both, importing the new one as <name>-<client>
+
+With --fragment, imported servers are saved to that file in the mcp.d directory
+next to mcp.json instead.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		clientFilter, _ := cmd.Flags().GetStringArray("client")
+		strategy, _ := cmd.Flags().GetString("strategy")
+		fragment := fragmentFlag(cmd)
+
+		switch strategy {
+		case strategyPrompt, strategyKeep, strategyReplace, strategyRename:
+		default:
+			log.Fatal("Invalid strategy '%s'. Use one of: prompt, keep, replace, rename", strategy)
+		}
+
+		cfg, err := config.LoadEffectiveConfig()
+		if err != nil {
+			log.Fatal("Error loading config.yaml: %v", err)
+		}
+
+		clients, err := filterClients(resolveClients(cfg), clientFilter)
+		if err != nil {
+			log.Fatal("%v", err)
+		}
+		if len(clients) == 0 {
+			log.Warn("No clients found to import from.")
+			return
+		}
+
+		// Collect the servers of every client, in a stable order
+		candidates := make(map[string][]importCandidate)
+		for _, clientName := range sortedClientNames(clients) {
+			servers, err := translator.ReadClientServers(clientName, clients[clientName])
+			if err != nil {
+				log.Warn("Skipping %s: %v", clientName, err)
+				continue
+			}
+			log.Detail("Found %d server(s) in %s", len(servers), clientName)
+			for _, serverName := range sortedServerNames(servers) {
+				candidates[serverName] = append(candidates[serverName], importCandidate{Client: clientName, Server: servers[serverName]})
+			}
+		}
+
+		if len(candidates) == 0 {
+			log.Warn("No MCP servers found in any client configuration.")
+			return
+		}
+
+		// Merge candidates into mcp.json, holding the mcp.json lock until it is saved
+		added, replaced, renamed, identical := 0, 0, 0, 0
+		err = config.UpdateMCPConfig(func(mcpCfg *config.MCPConfig) error {
+			for _, serverName := range sortedCandidateNames(candidates) {
+				for _, candidate := range dedupeCandidates(candidates[serverName]) {
+					current, exists := mcpCfg.MCPServers[serverName]
+					if !exists {
+						mcpCfg.SetServer(serverName, candidate.Server, fragment)
+						log.Success("Imported server '%s' from %s", serverName, candidate.Client)
+						added++
+						continue
+					}
+					if current.Equal(candidate.Server) {
+						identical++
+						continue
+					}
+
+					resolution := strategy
+					if resolution == strategyPrompt {
+						if err := canPrompt(cmd, "pass --strategy keep, replace or rename"); err != nil {
+							return fmt.Errorf("cannot resolve conflict for server '%s': %w", serverName, err)
+						}
+						resolution, err = promptImportConflict(serverName, candidate)
+						if err != nil {
+							return fmt.Errorf("error during conflict resolution: %w", err)
+						}
+					}
+
+					switch resolution {
+					case strategyKeep:
+						log.Info("Kept existing definition of '%s' (ignored the one from %s)", serverName, candidate.Client)
+					case strategyReplace:
+						mcpCfg.SetServer(serverName, candidate.Server, fragment)
+						log.Success("Replaced server '%s' with the definition from %s", serverName, candidate.Client)
+						replaced++
+					case strategyRename:
+						newName := uniqueServerName(mcpCfg.MCPServers, fmt.Sprintf("%s-%s", serverName, candidate.Client))
+						mcpCfg.SetServer(newName, candidate.Server, fragment)
+						log.Success("Imported server '%s' from %s as '%s'", serverName, candidate.Client, newName)
+						renamed++
+					}
+				}
+			}
+
+			if added+replaced+renamed == 0 {
+				return config.ErrNoChange
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatal("Failed to import into mcp.json: %v", err)
+		}
+
+		if added+replaced+renamed == 0 {
+			log.Info("mcp.json already contains every imported server (%d identical). Nothing to do.", identical)
+			return
+		}
+
+		log.Success("Import finished: %d added, %d replaced, %d renamed, %d identical skipped.", added, replaced, renamed, identical)
+	},
+}
+
+// promptImportConflict asks the user how to resolve a conflicting server definition.
+func promptImportConflict(serverName string, candidate importCandidate) (string, error) {
+	keepOption := "Keep the existing definition"
+	replaceOption := fmt.Sprintf("Replace it with the definition from %s", candidate.Client)
+	renameOption := fmt.Sprintf("Keep both (import as '%s-%s')", serverName, candidate.Client)
+
+	var answer string
+	prompt := &survey.Select{
+		Message: fmt.Sprintf("Server '%s' from %s differs from the existing definition:", serverName, candidate.Client),
+		Options: []string{keepOption, replaceOption, renameOption},
+		Default: keepOption,
+	}
+	if err := survey.AskOne(prompt, &answer); err != nil {
+		return "", err
+	}
+
+	switch answer {
+	case replaceOption:
+		return strategyReplace, nil
+	case renameOption:
+		return strategyRename, nil
+	default:
+		return strategyKeep, nil
+	}
+}
+
+// uniqueServerName returns name, suffixed with a counter if it is already taken.
+func uniqueServerName(servers map[string]config.MCPServer, name string) string {
+	candidate := name
+	for i := 2; ; i++ {
+		if _, exists := servers[candidate]; !exists {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+}
+
+// sortedCandidateNames returns the imported server names in a stable order.
+func sortedCandidateNames(candidates map[string][]importCandidate) []string {
+	names := make([]string, 0, len(candidates))
+	for name := range candidates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dedupeCandidates drops definitions identical to one already seen in another client.
+func dedupeCandidates(candidates []importCandidate) []importCandidate {
+	var unique []importCandidate
+	for _, candidate := range candidates {
+		duplicate := false
+		for _, seen := range unique {
+			if seen.Server.Equal(candidate.Server) {
+				log.Detail("Server definition in %s is identical to the one in %s", candidate.Client, seen.Client)
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			unique = append(unique, candidate)
+		}
+	}
+	return unique
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringArray("client", nil, "Only import from the given client (can be repeated)")
+	importCmd.Flags().String("strategy", strategyPrompt, "How to resolve conflicting definitions: prompt, keep, replace or rename")
+	importCmd.Flags().String("fragment", "", "Save imported servers to this file in mcp.d (e.g. imported.json) instead of mcp.json")
+}
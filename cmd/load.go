@@ -16,8 +16,11 @@ import (
 var loadCmd = &cobra.Command{
 	Use:   "load",
 	Short: "Load MCP server configuration from clipboard",
-	Long:  `Loads MCP server configuration from the clipboard and adds it to mcp.json`,
+	Long: `Loads MCP server configuration from the clipboard and adds it to mcp.json, or with
+--fragment to a file in the mcp.d directory next to it.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		fragment := fragmentFlag(cmd)
+
 		log.Info("Reading configuration from clipboard...")
 
 		// Get clipboard content
@@ -32,6 +35,12 @@ var loadCmd = &cobra.Command{
 			return
 		}
 
+		// Check the servers before adding them
+		if err := config.ValidateMCPConfigData("clipboard", []byte(clipboardContent), config.ValidateOptions{}); err != nil {
+			count := printValidationErrors(err)
+			log.Fatal("The clipboard configuration has %d problem(s); nothing was added.", count)
+		}
+
 		// Parse clipboard content as JSON
 		var clipboardData map[string]interface{}
 		err = json.Unmarshal([]byte(clipboardContent), &clipboardData)
@@ -62,29 +71,20 @@ var loadCmd = &cobra.Command{
 			return
 		}
 
-		// Load existing config
-		existingConfig, err := config.LoadMCPConfig()
-		if err != nil {
-			// If error is because the file doesn't exist, create a new config
-			existingConfig = &config.MCPConfig{
-				MCPServers: make(map[string]config.MCPServer),
+		// Merge new servers into the existing config and save it, under the mcp.json lock
+		err = config.UpdateMCPConfig(func(existingConfig *config.MCPConfig) error {
+			for name, server := range mcpConfig.MCPServers {
+				existingConfig.SetServer(name, server, fragment)
+				log.Info("Added MCP server: %s", name)
 			}
-		}
-
-		// Merge new servers into existing config
-		for name, server := range mcpConfig.MCPServers {
-			existingConfig.MCPServers[name] = server
-			log.Info("Added MCP server: %s", name)
-		}
-
-		// Save the updated config
-		err = config.SaveMCPConfig(existingConfig)
+			return nil
+		})
 		if err != nil {
 			log.Fatal("Failed to save config: %v", err)
 			return
 		}
 
-		log.Info("Successfully loaded MCP configuration from clipboard")
+		log.Info("Successfully loaded MCP configuration from clipboard into %s", savedTo(fragment))
 	},
 }
 
@@ -121,4 +121,6 @@ func getClipboard() (string, error) {
 
 func init() {
 	rootCmd.AddCommand(loadCmd)
+
+	loadCmd.Flags().String("fragment", "", "Save the servers to this file in mcp.d (e.g. github.yaml) instead of mcp.json")
 }
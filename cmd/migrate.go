@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/diff"
+	"github.com/tuannvm/mcpenetes/internal/log"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrades config.yaml, mcp.json and mcp.d fragments to the current format",
+	Long: `Upgrades config.yaml, mcp.json and the mcp.d fragments written by older versions
+of mcpenetes to the current format, one version at a time. The original file is
+kept next to it as <file>.v<version>-<timestamp>.bak. Fragments that would only
+gain a version stamp are left as they are.
+
+Files are also migrated automatically when they are loaded; use --dry-run to see
+the steps and the resulting changes without writing anything.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		configPlan, err := config.PlanConfigMigration()
+		if err != nil {
+			log.Fatal("Failed to plan config.yaml migration: %v", err)
+		}
+		mcpPlan, err := config.PlanMCPMigration()
+		if err != nil {
+			log.Fatal("Failed to plan mcp.json migration: %v", err)
+		}
+		fragmentPlans, err := config.PlanFragmentMigrations()
+		if err != nil {
+			log.Fatal("Failed to plan mcp.d migrations: %v", err)
+		}
+
+		migrated := 0
+		for _, plan := range append([]*config.MigrationPlan{configPlan, mcpPlan}, fragmentPlans...) {
+			if !plan.Needed() {
+				continue
+			}
+			migrated++
+			log.Info("%s: version %d -> %d", plan.File, plan.FromVersion, plan.ToVersion)
+			for _, step := range plan.Steps {
+				log.Detail("  %s", step)
+			}
+
+			if dryRun {
+				printUnifiedDiff(diff.Unified(plan.File, plan.File, plan.Before, plan.After, diff.DefaultContext))
+				continue
+			}
+			backupPath, err := config.ApplyMigration(plan)
+			if err != nil {
+				log.Fatal("Failed to migrate %s: %v", plan.File, err)
+			}
+			log.Success("Migrated %s (backup: %s)", plan.File, backupPath)
+		}
+
+		switch {
+		case migrated == 0:
+			log.Success("config.yaml, mcp.json and mcp.d are already at the current version.")
+		case dryRun:
+			log.Info("Dry run: nothing was written. Run 'mcpenetes migrate' to apply.")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+	migrateCmd.Flags().Bool("dry-run", false, "Show the migration steps and changes without writing anything")
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// stdinIsTerminal reports whether stdin is attached to a terminal. Tests replace it.
+var stdinIsTerminal = func() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// askOne asks a single survey question. Tests replace it.
+var askOne = survey.AskOne
+
+// canPrompt returns an error explaining why the command may not prompt, or nil if it may.
+// hint tells the user which flags to pass instead of answering the prompt.
+func canPrompt(cmd *cobra.Command, hint string) error {
+	nonInteractive, _ := cmd.Flags().GetBool("non-interactive")
+	if nonInteractive {
+		return fmt.Errorf("a prompt is required but --non-interactive is set; %s", hint)
+	}
+	if !stdinIsTerminal() {
+		return fmt.Errorf("a prompt is required but stdin is not a terminal; %s", hint)
+	}
+	return nil
+}
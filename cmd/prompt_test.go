@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// fakeTerminal makes canPrompt see stdin as a terminal or not.
+func fakeTerminal(t *testing.T, terminal bool) {
+	t.Helper()
+	original := stdinIsTerminal
+	stdinIsTerminal = func() bool { return terminal }
+	t.Cleanup(func() { stdinIsTerminal = original })
+}
+
+func TestCanPrompt(t *testing.T) {
+	tests := []struct {
+		name           string
+		terminal       bool
+		nonInteractive bool
+		wantErr        string
+	}{
+		{"terminal", true, false, ""},
+		{"non-interactive on a terminal", true, true, "--non-interactive is set; pass --yes"},
+		{"no terminal", false, false, "stdin is not a terminal; pass --yes"},
+		{"non-interactive without a terminal", false, true, "--non-interactive is set; pass --yes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeTerminal(t, tt.terminal)
+			cmd := &cobra.Command{Use: "test"}
+			cmd.Flags().Bool("non-interactive", false, "")
+			if tt.nonInteractive {
+				if err := cmd.Flags().Set("non-interactive", "true"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := canPrompt(cmd, "pass --yes")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected to be allowed to prompt, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCanPromptInheritsRootFlag(t *testing.T) {
+	fakeTerminal(t, true)
+	if err := applyCmd.ParseFlags([]string{"--non-interactive"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = rootCmd.PersistentFlags().Set("non-interactive", "false") })
+
+	if err := canPrompt(applyCmd, "pass --yes"); err == nil {
+		t.Error("expected subcommands to honour the persistent --non-interactive flag")
+	}
+}
@@ -8,7 +8,7 @@ import (
 var removeCmd = &cobra.Command{
 	Use:   "remove",
 	Short: "Remove resources like registries.",
-	Long:  `Parent command for removing different types of resources managed by mcpetes.`,
+	Long:  `Parent command for removing different types of resources managed by mcpenetes.`,
 	Aliases: []string{"rm"}, // Add 'rm' as an alias
 	// Run: func(cmd *cobra.Command, args []string) { 
 	// 	 cmd.Help()
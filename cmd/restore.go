@@ -26,7 +26,7 @@ from the most recent backup found in the backup directory specified in config.ya
 		log.Info("Executing restore command...")
 
 		// 1. Load config
-		cfg, err := config.LoadConfig()
+		cfg, err := config.LoadEffectiveConfig()
 		if err != nil {
 			log.Fatal("Error loading config.yaml: %v", err)
 		}
@@ -100,8 +100,15 @@ from the most recent backup found in the backup directory specified in config.ya
 				continue
 			}
 
-			// Perform the restore (copy backup to original location)
+			// Perform the restore (copy backup to original location) under the client's lock
+			l, err := config.LockClientConfig(clientConfigPath)
+			if err != nil {
+				clientErrors[clientName] = err
+				failureCount++
+				continue
+			}
 			err = copyFile(latestBackupPath, clientConfigPath)
+			_ = l.Release()
 			if err != nil {
 				clientErrors[clientName] = fmt.Errorf("error restoring config from '%s': %w", latestBackupFileName, err)
 				failureCount++
@@ -164,7 +171,8 @@ func copyFile(src, dst string) error {
 		return fmt.Errorf("failed to create destination directory '%s': %w", dstDir, err)
 	}
 
-	destination, err := os.Create(dst)
+	// Like apply, create a missing client config readable by its owner only; an existing one keeps its mode
+	destination, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
 	}
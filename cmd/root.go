@@ -2,15 +2,22 @@ package cmd
 
 import (
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/history"
+	"github.com/tuannvm/mcpenetes/internal/lock"
+	"github.com/tuannvm/mcpenetes/internal/log"
+	"github.com/tuannvm/mcpenetes/internal/paths"
+	"github.com/tuannvm/mcpenetes/internal/secrets"
 )
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "mcpetes",
+	Use:   "mcpenetes",
 	Short: "A CLI tool to manage multiple MCP endpoint configurations.",
-	Long: `mcpetes helps you switch between different Model Context Protocol (MCP)
+	Long: `mcpenetes helps you switch between different Model Context Protocol (MCP)
 server configurations defined in a central mcp.json file or fetched from registries.
 It can update configuration files for various clients (like VS Code extensions)
 based on the selected MCP server.`,
@@ -19,6 +26,34 @@ based on the selected MCP server.`,
 		// verbose, _ := cmd.Flags().GetBool("verbose") // Flags can be checked in specific commands if needed
 		// debug, _ := cmd.Flags().GetBool("debug")
 		// log.Init(verbose, debug) // log package does not have Init function
+
+		// Where config.yaml, mcp.json and the other mcpenetes files live
+		configDir, _ := cmd.Flags().GetString("config-dir")
+		paths.SetConfigDir(configDir)
+		if err := config.MigrateLegacyConfigDir(); err != nil {
+			log.Fatal("Failed to migrate the legacy config directory: %v", err)
+		}
+
+		// Write client configs into another filesystem tree, e.g. when building images
+		rootDir, _ := cmd.Flags().GetString("root")
+		paths.SetRoot(rootDir)
+		homeDir, _ := cmd.Flags().GetString("home")
+		paths.SetHome(homeDir)
+
+		// Recorded with every change to config.yaml and mcp.json, e.g. "load"
+		history.Command = strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+
+		// How long to wait for other mcpenetes processes holding config or client file locks
+		config.LockTimeout, _ = cmd.Flags().GetDuration("lock-timeout")
+
+		// Secrets are masked in all output unless explicitly requested
+		showSecrets, _ := cmd.Flags().GetBool("show-secrets")
+		log.SetShowSecrets(showSecrets)
+
+		// Ask for the secret store passphrase only when prompting is possible
+		if canPrompt(cmd, "") == nil {
+			secrets.PromptPassphrase = promptPassphrase
+		}
 	},
 }
 
@@ -39,6 +74,12 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().String("config-dir", "", "Directory holding config.yaml and mcp.json (default $MCPENETES_HOME, $XDG_CONFIG_HOME/mcpenetes or ~/.config/mcpenetes)")
+	rootCmd.PersistentFlags().String("root", "", "Write client configs and backups under this directory instead of /, e.g. an image's root filesystem")
+	rootCmd.PersistentFlags().String("home", "", "Write client configs for this home directory (inside --root, if given) instead of $HOME")
+	rootCmd.PersistentFlags().Duration("lock-timeout", lock.DefaultTimeout, "How long to wait for another mcpenetes process to release a lock")
+	rootCmd.PersistentFlags().Bool("show-secrets", false, "Print secret values instead of masking them in output and diffs")
+	rootCmd.PersistentFlags().Bool("non-interactive", false, "Never prompt for input; fail instead (implied when stdin is not a terminal)")
 	// rootCmd.PersistentFlags().Bool("debug", false, "Enable debug output (more verbose)")
 
 	// Cobra also supports local flags, which will only run
@@ -31,6 +31,9 @@ If a server ID is provided as an argument, it will directly use that server with
 After selection, adds the server to the 'mcps' list in config.yaml.
 This determines which server configuration will be used by the 'reload' command.
 
+Without a server ID, search needs a terminal to prompt on. With --non-interactive,
+or when stdin is not a terminal, pass the server ID as an argument.
+
 By default, search results are cached to improve performance. Use the --refresh flag to force a refresh
 of the cache and fetch the latest data from the registries.`,
 	Args: func(cmd *cobra.Command, args []string) error {
@@ -46,7 +49,7 @@ of the cache and fetch the latest data from the registries.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Get the refresh flag value
 		forceRefresh, _ := cmd.Flags().GetBool("refresh")
-		cfg, err := config.LoadConfig()
+		cfg, err := config.LoadEffectiveConfig()
 		if err != nil {
 			log.Fatal("Error loading config: %v", err)
 		}
@@ -57,22 +60,22 @@ of the cache and fetch the latest data from the registries.`,
 			log.Info("Using provided server ID: %s", serverID)
 
 			// Add the selected server to the MCPs list in config
-			cfg.MCPs = append(cfg.MCPs, serverID)
-
-			// Save the updated config
-			if err := config.SaveConfig(cfg); err != nil {
+			if err := addMCP(serverID); err != nil {
 				log.Fatal("Error saving config: %v", err)
 			}
 
-			log.Success("Successfully added MCP '%s' to the list. Run 'mcpetes reload' to apply.", serverID)
+			log.Success("Successfully added MCP '%s' to the list. Run 'mcpenetes apply' to apply.", serverID)
 			return
 		}
 
 		// Interactive selection mode
+		if err := canPrompt(cmd, "pass the server ID as an argument"); err != nil {
+			log.Fatal("Cannot start interactive search: %v", err)
+		}
 		log.Info("Starting interactive search...")
 
 		if len(cfg.Registries) == 0 {
-			log.Warn("No registries configured. Use 'mcpetes add registry <n> <url>' to add one.")
+			log.Warn("No registries configured. Use 'mcpenetes add registry <n> <url>' to add one.")
 			return
 		}
 
@@ -173,23 +176,23 @@ of the cache and fetch the latest data from the registries.`,
 		serverID := selectedServer.Name
 		log.Info("Using server ID: %s", serverID)
 
-		cfg, err = config.LoadConfig()
-		if err != nil {
-			log.Fatal("Error loading config: %v", err)
-		}
-
 		// Add the selected server to the MCPs list in config
-		cfg.MCPs = append(cfg.MCPs, serverID)
-
-		// Save the updated config
-		if err := config.SaveConfig(cfg); err != nil {
+		if err := addMCP(serverID); err != nil {
 			log.Fatal("Error saving config: %v", err)
 		}
 
-		log.Success("Successfully added MCP '%s' to the list. Run 'mcpetes reload' to apply.", serverID)
+		log.Success("Successfully added MCP '%s' to the list. Run 'mcpenetes apply' to apply.", serverID)
 	},
 }
 
+// addMCP appends a server ID to the MCPs list in config.yaml under the config lock.
+func addMCP(serverID string) error {
+	return config.UpdateConfig(func(cfg *config.Config) error {
+		cfg.MCPs = append(cfg.MCPs, serverID)
+		return nil
+	})
+}
+
 // openBrowser opens the specified URL in the default browser
 func openBrowser(url string) error {
 	var err error
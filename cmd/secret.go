@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/lock"
+	"github.com/tuannvm/mcpenetes/internal/log"
+	"github.com/tuannvm/mcpenetes/internal/secrets"
+)
+
+// secretCmd represents the secret command
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manages the encrypted secret store",
+	Long: `Manages secrets kept in an encrypted file (secrets.enc) in the mcpenetes config
+directory, so mcp.json can be shared without them. Reference a secret from a
+server's env, headers or other fields as ${secret:NAME}; it is decrypted only
+when client configs are rendered by apply.
+
+The store is encrypted with AES-256-GCM using a key derived (PBKDF2-SHA256) from
+the content of a key file (--key-file or MCPENETES_SECRET_KEY_FILE) or from a
+passphrase (MCPENETES_SECRET_PASSPHRASE, or asked for when running in a terminal).`,
+}
+
+// secretSetCmd represents the secret set command
+var secretSetCmd = &cobra.Command{
+	Use:   "set NAME [VALUE]",
+	Short: "Adds or replaces a secret",
+	Long: `Adds or replaces a secret. Without VALUE, the value is asked for in a terminal or
+read from stdin, which keeps it out of your shell history.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if err := secrets.ValidateName(name); err != nil {
+			log.Fatal("%v", err)
+		}
+
+		var value string
+		if len(args) == 2 {
+			value = args[1]
+		} else {
+			var err error
+			value, err = readSecretValue(cmd, name)
+			if err != nil {
+				log.Fatal("Failed to read the value of %s: %v", name, err)
+			}
+		}
+
+		err := updateSecretStore(cmd, func(store *secrets.Store) error {
+			return store.Set(name, value)
+		})
+		if err != nil {
+			log.Fatal("Failed to set secret %s: %v", name, err)
+		}
+		log.Success("Secret %s saved. Reference it as ${secret:%s}.", name, name)
+	},
+}
+
+// secretGetCmd represents the secret get command
+var secretGetCmd = &cobra.Command{
+	Use:   "get NAME",
+	Short: "Prints the value of a secret",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openSecretStore(cmd)
+		value, ok := store.Get(args[0])
+		if !ok {
+			log.Fatal("Secret %s not found.", args[0])
+		}
+		fmt.Println(value)
+	},
+}
+
+// secretListCmd represents the secret list command
+var secretListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the names of all secrets",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openSecretStore(cmd)
+		names := store.Names()
+		if len(names) == 0 {
+			log.Info("The secret store is empty.")
+			return
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+	},
+}
+
+// secretRmCmd represents the secret rm command
+var secretRmCmd = &cobra.Command{
+	Use:     "rm NAME",
+	Aliases: []string{"remove"},
+	Short:   "Removes a secret",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		err := updateSecretStore(cmd, func(store *secrets.Store) error {
+			if !store.Delete(name) {
+				return fmt.Errorf("secret %s not found", name)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatal("Failed to remove secret: %v", err)
+		}
+		log.Success("Secret %s removed.", name)
+	},
+}
+
+// secretStorePath returns the path of the encrypted secret store.
+func secretStorePath() (string, error) {
+	configDir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, secrets.DefaultStoreFileName), nil
+}
+
+// openSecretStore decrypts the secret store for reading, or exits.
+func openSecretStore(cmd *cobra.Command) *secrets.Store {
+	storePath, err := secretStorePath()
+	if err != nil {
+		log.Fatal("Failed to determine secret store path: %v", err)
+	}
+	if _, err := os.Stat(storePath); os.IsNotExist(err) {
+		log.Fatal("No secret store at %s. Add a secret with 'mcpenetes secret set NAME'.", storePath)
+	}
+
+	keyFile, _ := cmd.Flags().GetString("key-file")
+	secret, err := secrets.KeyMaterial(keyFile, false)
+	if err != nil {
+		log.Fatal("%v", err)
+	}
+	store, err := secrets.OpenStore(storePath, secret)
+	if err != nil {
+		log.Fatal("%v", err)
+	}
+	return store
+}
+
+// updateSecretStore decrypts the secret store, applies update and saves it, holding the store's lock.
+func updateSecretStore(cmd *cobra.Command, update func(store *secrets.Store) error) error {
+	storePath, err := secretStorePath()
+	if err != nil {
+		return fmt.Errorf("failed to determine secret store path: %w", err)
+	}
+
+	l, err := lock.Acquire(storePath+".lock", config.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Release() }()
+
+	_, statErr := os.Stat(storePath)
+	keyFile, _ := cmd.Flags().GetString("key-file")
+	secret, err := secrets.KeyMaterial(keyFile, os.IsNotExist(statErr))
+	if err != nil {
+		return err
+	}
+	store, err := secrets.OpenStore(storePath, secret)
+	if err != nil {
+		return err
+	}
+	if err := update(store); err != nil {
+		return err
+	}
+	return store.Save()
+}
+
+// readSecretValue asks for a secret's value in a terminal, or reads it from stdin.
+func readSecretValue(cmd *cobra.Command, name string) (string, error) {
+	if canPrompt(cmd, "") != nil {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	var value string
+	err := survey.AskOne(&survey.Password{Message: fmt.Sprintf("Value of %s:", name)}, &value, survey.WithValidator(survey.Required))
+	return value, err
+}
+
+// promptPassphrase asks for the secret store passphrase, twice when the store is being created.
+func promptPassphrase(confirm bool) (string, error) {
+	var passphrase string
+	if err := survey.AskOne(&survey.Password{Message: "Secret store passphrase:"}, &passphrase, survey.WithValidator(survey.Required)); err != nil {
+		return "", err
+	}
+	if !confirm {
+		return passphrase, nil
+	}
+
+	var repeated string
+	if err := survey.AskOne(&survey.Password{Message: "Repeat the passphrase:"}, &repeated); err != nil {
+		return "", err
+	}
+	if repeated != passphrase {
+		return "", fmt.Errorf("the passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+func init() {
+	rootCmd.AddCommand(secretCmd)
+	secretCmd.AddCommand(secretSetCmd, secretGetCmd, secretListCmd, secretRmCmd)
+
+	secretCmd.PersistentFlags().String("key-file", "", "File whose content protects the secret store (default $"+secrets.KeyFileEnv+")")
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/tuannvm/mcpenetes/internal/log"
+	"github.com/tuannvm/mcpenetes/internal/translator"
+)
+
+// exitDrift is the exit code used by status and diff when clients are out of sync.
+const exitDrift = 2
+
+// clientStatus is the comparison result for one client.
+type clientStatus struct {
+	Name     string
+	Render   *translator.ClientRender
+	Servers  []translator.ServerStatus
+	Err      error
+	Drifting bool
+}
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Shows which clients are in sync with mcp.json",
+	Long: `Compares every client's configuration with what 'apply' would write and prints
+a servers x clients matrix. Each cell is one of:
+
+  in-sync   the client entry matches mcp.json
+  missing   the server is in mcp.json but not in the client
+  modified  the client entry differs from mcp.json
+  foreign   the client has a server that is not in mcp.json (apply removes it)
+  unresolved  a secret reference or template variable of the server could not be resolved (apply skips it),
+              or it uses ${cmd:...}, which status does not run
+
+Exit codes: 0 when every client is in sync, 2 when drift was found, 1 on errors.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		clientFilter, _ := cmd.Flags().GetStringArray("client")
+		os.Exit(runStatus(clientFilter))
+	},
+}
+
+// runStatus prints the status matrix of the selected clients and returns the exit code.
+func runStatus(clientFilter []string) int {
+	statuses, err := collectClientStatuses(clientFilter)
+	if err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+	if len(statuses) == 0 {
+		log.Warn("No clients found to compare.")
+		return 0
+	}
+
+	// Gather every server name across clients
+	serverSet := make(map[string]bool)
+	for _, status := range statuses {
+		for _, server := range status.Servers {
+			serverSet[server.Name] = true
+		}
+	}
+	serverNames := make([]string, 0, len(serverSet))
+	for name := range serverSet {
+		serverNames = append(serverNames, name)
+	}
+	sort.Strings(serverNames)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprint(w, "SERVER")
+	for _, status := range statuses {
+		_, _ = fmt.Fprintf(w, "\t%s", status.Name)
+	}
+	_, _ = fmt.Fprintln(w)
+
+	for _, serverName := range serverNames {
+		_, _ = fmt.Fprint(w, serverName)
+		for _, status := range statuses {
+			_, _ = fmt.Fprintf(w, "\t%s", serverState(status, serverName))
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+	_ = w.Flush()
+
+	fmt.Println()
+	for _, status := range statuses {
+		switch {
+		case status.Err != nil:
+			log.Error("%s: %v", status.Name, status.Err)
+		case status.Drifting:
+			log.Warn("%s is out of sync (%s)", status.Name, status.Render.Path)
+		default:
+			log.Success("%s is in sync (%s)", status.Name, status.Render.Path)
+		}
+	}
+	return driftExitCode(statuses)
+}
+
+// driftExitCode returns the exit code of status and diff: 1 if any client had an error,
+// otherwise exitDrift if any client is out of sync, otherwise 0.
+func driftExitCode(statuses []clientStatus) int {
+	exitCode := 0
+	for _, status := range statuses {
+		if status.Err != nil {
+			return 1
+		}
+		if status.Drifting {
+			exitCode = exitDrift
+		}
+	}
+	return exitCode
+}
+
+// collectClientStatuses loads the configuration and compares every selected client with mcp.json.
+// The result is sorted by client name, and empty if there are no clients.
+func collectClientStatuses(clientFilter []string) ([]clientStatus, error) {
+	eff := loadEffective()
+
+	clients, err := filterClients(resolveClients(eff.Config), clientFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	trans := newTranslator(eff, true)
+	var statuses []clientStatus
+	for _, clientName := range sortedClientNames(clients) {
+		status := clientStatus{Name: clientName}
+		status.Render, status.Err = trans.RenderClientConfig(clientName, clients[clientName])
+		if status.Err == nil {
+			status.Servers, status.Err = translator.ClientStatus(status.Render)
+		}
+		for _, server := range status.Servers {
+			if server.State != translator.StateInSync && !commandsNotRun(status.Render.Skipped[server.Name]) {
+				status.Drifting = true
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// serverState returns the matrix cell for a server in a client.
+func serverState(status clientStatus, serverName string) string {
+	if status.Err != nil {
+		return "error"
+	}
+	for _, server := range status.Servers {
+		if server.Name == serverName {
+			return string(server.State)
+		}
+	}
+	return "-"
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().StringArray("client", nil, "Only check the given client (can be repeated)")
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/paths"
+	"github.com/tuannvm/mcpenetes/internal/translator"
+)
+
+// statusFixture sets up a config directory with two clients, cursor and claude-desktop, and an
+// mcp.json with one server. Both clients are written in sync; their paths are returned.
+func statusFixture(t *testing.T) map[string]string {
+	t.Helper()
+	dir := t.TempDir()
+	clientPaths := map[string]string{
+		"cursor":         filepath.Join(dir, "cursor", "mcp.json"),
+		"claude-desktop": filepath.Join(dir, "claude", "claude_desktop_config.json"),
+	}
+	configYAML := "version: 1\nbackups:\n  path: " + filepath.Join(dir, "backups") + "\nclients:\n"
+	for _, name := range []string{"claude-desktop", "cursor"} {
+		configYAML += "  " + name + ":\n    config_path: " + clientPaths[name] + "\n"
+	}
+	files := map[string]string{
+		"config.yaml": configYAML,
+		"mcp.json":    `{"version": 1, "mcpServers": {"fetch": {"command": "uvx", "args": ["mcp-server-fetch"]}}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv(paths.SystemDirEnv, filepath.Join(dir, "no-system-layer"))
+	t.Chdir(dir)
+	paths.SetConfigDir(dir)
+	t.Cleanup(func() { paths.SetConfigDir("") })
+
+	eff, err := config.LoadEffective()
+	if err != nil {
+		t.Fatal(err)
+	}
+	trans := translator.NewTranslator(eff.Config, eff.MCP)
+	for name, client := range eff.Config.Clients {
+		render, err := trans.RenderClientConfig(name, client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := trans.WriteClientConfig(render); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return clientPaths
+}
+
+func TestStatusAndDiffExitCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		edit   map[string]string // Client config content to write over the in-sync one
+		filter []string
+		want   int
+	}{
+		{name: "in sync", want: 0},
+		{name: "drift", edit: map[string]string{"cursor": `{"mcpServers": {}}`}, want: exitDrift},
+		{name: "foreign server", edit: map[string]string{"cursor": `{"mcpServers": {"fetch": {"command": "uvx", "args": ["mcp-server-fetch"]}, "old": {"command": "old"}}}`}, want: exitDrift},
+		{name: "drift in a filtered out client", edit: map[string]string{"cursor": `{"mcpServers": {}}`}, filter: []string{"claude-desktop"}, want: 0},
+		{name: "drift in a selected client", edit: map[string]string{"cursor": `{"mcpServers": {}}`}, filter: []string{"cursor"}, want: exitDrift},
+		{name: "unreadable client config", edit: map[string]string{"cursor": `{not json`}, want: 1},
+		{name: "error wins over drift", edit: map[string]string{"cursor": `{not json`, "claude-desktop": `{"mcpServers": {}}`}, want: 1},
+		{name: "unknown client", filter: []string{"nope"}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientPaths := statusFixture(t)
+			for name, content := range tt.edit {
+				if err := os.WriteFile(clientPaths[name], []byte(content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := runStatus(tt.filter); got != tt.want {
+				t.Errorf("status: expected exit code %d, got %d", tt.want, got)
+			}
+			if got := runDiff(tt.filter); got != tt.want {
+				t.Errorf("diff: expected exit code %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCollectClientStatusesFilter(t *testing.T) {
+	statusFixture(t)
+
+	statuses, err := collectClientStatuses([]string{"cursor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Name != "cursor" {
+		t.Errorf("expected only cursor, got %+v", statuses)
+	}
+
+	statuses, err = collectClientStatuses(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || statuses[0].Name != "claude-desktop" || statuses[1].Name != "cursor" {
+		t.Errorf("expected every client in name order, got %+v", statuses)
+	}
+}
+
+func TestDriftExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []clientStatus
+		want     int
+	}{
+		{"no clients", nil, 0},
+		{"in sync", []clientStatus{{Name: "a"}, {Name: "b"}}, 0},
+		{"drift", []clientStatus{{Name: "a"}, {Name: "b", Drifting: true}}, exitDrift},
+		{"error after drift", []clientStatus{{Name: "a", Drifting: true}, {Name: "b", Err: os.ErrNotExist}}, 1},
+	}
+	for _, tt := range tests {
+		if got := driftExitCode(tt.statuses); got != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tuannvm/mcpenetes/internal/baseline"
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/lock"
+	"github.com/tuannvm/mcpenetes/internal/log"
+	"github.com/tuannvm/mcpenetes/internal/paths"
+	"gopkg.in/yaml.v3"
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync [SOURCE]",
+	Short: "Fetches the team baseline of MCP servers",
+	Long: `Fetches a shared baseline mcp.json (and optionally config.yaml) and installs it
+as the baseline configuration layer, below your own configuration: your servers,
+registries and clients override the baseline's entries with the same name.
+
+SOURCE is a git repository, as a local path or any URL git understands, or an
+http(s) URL of an mcp.json file. It is saved in config.yaml, so later runs only
+need 'mcpenetes sync'. For git, --ref selects the branch, tag or commit and
+--path the directory of the bundle inside the repository.
+
+Baselines are only fetched over encrypted transports: https, ssh or a local
+path. Plain http:// and git:// sources are refused unless --insecure is given,
+on every run, since anyone on the network could swap the servers they define.
+
+The fetched revision is recorded in baseline.lock.json. With --check, nothing is
+installed; the command reports whether the installed baseline is behind its
+source or was modified locally.
+Exit codes: 0 when up to date, 2 when the baseline is outdated, 1 on errors.
+
+Run 'mcpenetes apply' afterwards to update your clients.`,
+	Example: `  mcpenetes sync git@github.com:acme/mcp-baseline.git
+  mcpenetes sync https://github.com/acme/mcp-baseline.git --ref v2 --path engineering
+  mcpenetes sync https://example.com/mcp-baseline.json
+  mcpenetes sync --check`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		check, _ := cmd.Flags().GetBool("check")
+
+		src := syncSource(cmd, args)
+		configDir, err := config.ConfigDir()
+		if err != nil {
+			log.Fatal("Failed to determine config directory: %v", err)
+		}
+		baselineDir := filepath.Join(configDir, config.BaselineDirName)
+		lockPath := filepath.Join(configDir, config.BaselineLockFileName)
+		cacheDir, err := paths.CacheDir()
+		if err != nil {
+			log.Fatal("Failed to determine cache directory: %v", err)
+		}
+
+		log.Info("Fetching baseline from %s...", src.URL)
+		fetched, err := baseline.Fetch(src, cacheDir)
+		if err != nil {
+			log.Fatal("%v", err)
+		}
+
+		if check {
+			os.Exit(checkBaseline(src, fetched, baselineDir, lockPath))
+		}
+
+		if err := config.ValidateMCPConfigData(src.URL+" ("+baseline.MCPFileName+")", fetched.Files[baseline.MCPFileName], config.ValidateOptions{}); err != nil {
+			count := printValidationErrors(err)
+			log.Fatal("The baseline has %d problem(s); not installed.", count)
+		}
+		if data, ok := fetched.Files[baseline.ConfigFileName]; ok {
+			var cfg config.Config
+			if err := yaml.Unmarshal(data, &cfg); err != nil {
+				log.Fatal("The baseline's %s is invalid; not installed: %v", baseline.ConfigFileName, err)
+			}
+		}
+
+		// Keep concurrent syncs from interleaving the files and the lock
+		l, err := lock.Acquire(baselineDir+".lock", config.LockTimeout)
+		if err != nil {
+			log.Fatal("%v", err)
+		}
+		defer func() { _ = l.Release() }()
+
+		previous, err := baseline.ReadLock(lockPath)
+		if err != nil {
+			log.Fatal("%v", err)
+		}
+		if previous != nil && previous.SameSource(src) && previous.Revision == fetched.Revision && len(previous.Modified(baselineDir)) == 0 {
+			log.Success("Baseline is already at %s.", shortRevision(fetched.Revision))
+			return
+		}
+
+		if err := baseline.Install(baselineDir, fetched); err != nil {
+			log.Fatal("Failed to install baseline: %v", err)
+		}
+		if err := baseline.NewLock(src, fetched).Write(lockPath); err != nil {
+			log.Fatal("%v", err)
+		}
+		if previous != nil && previous.Revision != fetched.Revision {
+			log.Success("Updated baseline from %s to %s.", shortRevision(previous.Revision), shortRevision(fetched.Revision))
+		} else {
+			log.Success("Installed baseline %s in %s.", shortRevision(fetched.Revision), baselineDir)
+		}
+		log.Info("Run 'mcpenetes apply' to update your clients.")
+	},
+}
+
+// syncSource returns the source to sync from: the SOURCE argument and flags, saved to
+// config.yaml unless checking, or the source configured in config.yaml.
+func syncSource(cmd *cobra.Command, args []string) baseline.Source {
+	check, _ := cmd.Flags().GetBool("check")
+	ref, _ := cmd.Flags().GetString("ref")
+	bundlePath, _ := cmd.Flags().GetString("path")
+	insecure, _ := cmd.Flags().GetBool("insecure")
+
+	if len(args) == 0 {
+		cfg, err := config.LoadEffectiveConfig()
+		if err != nil {
+			log.Fatal("Error loading config.yaml: %v", err)
+		}
+		if cfg.Sync == nil || cfg.Sync.Source == "" {
+			log.Fatal("No baseline source configured. Run 'mcpenetes sync <git repository or URL>' first.")
+		}
+		src := baseline.Source{URL: cfg.Sync.Source, Ref: cfg.Sync.Ref, Path: cfg.Sync.Path, Insecure: insecure}
+		if cmd.Flags().Changed("ref") {
+			src.Ref = ref
+		}
+		if cmd.Flags().Changed("path") {
+			src.Path = bundlePath
+		}
+		return src
+	}
+
+	src := baseline.Source{URL: args[0], Ref: ref, Path: bundlePath, Insecure: insecure}
+	// Local repositories are saved as absolute paths so sync works from any directory
+	if _, err := os.Stat(src.URL); err == nil {
+		if abs, err := filepath.Abs(src.URL); err == nil {
+			src.URL = abs
+		}
+	}
+	if src.IsHTTP() && (src.Ref != "" || src.Path != "") {
+		log.Fatal("--ref and --path only apply to git repositories")
+	}
+	if err := src.Check(); err != nil {
+		log.Fatal("%v", err)
+	}
+
+	if !check {
+		err := config.UpdateConfig(func(cfg *config.Config) error {
+			cfg.Sync = &config.SyncConfig{Source: src.URL, Ref: src.Ref, Path: src.Path}
+			return nil
+		})
+		if err != nil {
+			log.Fatal("Error saving config.yaml: %v", err)
+		}
+	}
+	return src
+}
+
+// checkBaseline compares the installed baseline with the one just fetched and returns the exit code.
+func checkBaseline(src baseline.Source, fetched *baseline.Fetched, baselineDir, lockPath string) int {
+	installed, err := baseline.ReadLock(lockPath)
+	if err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+
+	outdated := false
+	switch {
+	case installed == nil:
+		log.Warn("No baseline installed yet; %s is at %s. Run 'mcpenetes sync'.", src.URL, shortRevision(fetched.Revision))
+		return 2
+	case !installed.SameSource(src):
+		log.Warn("The installed baseline comes from %s, not %s. Run 'mcpenetes sync'.", installed.Source, src.URL)
+		outdated = true
+	case installed.Revision != fetched.Revision:
+		log.Warn("The installed baseline (%s) is behind %s (%s). Run 'mcpenetes sync'.", shortRevision(installed.Revision), src.URL, shortRevision(fetched.Revision))
+		outdated = true
+	}
+	for _, name := range installed.Modified(baselineDir) {
+		log.Warn("%s was modified locally since it was synced. Run 'mcpenetes sync' to restore it.", filepath.Join(baselineDir, name))
+		outdated = true
+	}
+	if outdated {
+		return 2
+	}
+	log.Success("Baseline is up to date (%s).", shortRevision(fetched.Revision))
+	return 0
+}
+
+// shortRevision abbreviates a git commit or content hash for display.
+func shortRevision(revision string) string {
+	n := 12
+	if strings.HasPrefix(revision, "sha256:") {
+		n += len("sha256:")
+	}
+	if len(revision) > n {
+		return revision[:n]
+	}
+	return revision
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().String("ref", "", "Git branch, tag or commit to fetch (default: the repository's HEAD)")
+	syncCmd.Flags().String("path", "", "Directory of mcp.json and config.yaml inside the repository")
+	syncCmd.Flags().Bool("insecure", false, "Allow fetching the baseline over unencrypted http:// or git://")
+	syncCmd.Flags().Bool("check", false, "Only report whether the installed baseline is behind its source")
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/log"
+	"github.com/tuannvm/mcpenetes/internal/paths"
+)
+
+// trustCmd represents the trust command
+var trustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "Trusts the project configuration in the current directory",
+	Long: `A project can bring its own configuration in a .mcpenetes directory, found in the
+current directory or a parent. Its servers are commands your clients will run, so
+the project layer is only used once you trust it.
+
+Review the files listed, then run 'mcpenetes trust' to trust them as they are now.
+Any later change to them, such as a pull that edits mcp.json or adds a fragment,
+needs trusting again. Trusted projects are recorded in trusted_projects.json in
+your config directory.
+
+A project layer can add servers and registries, but clients, backups and sync are
+only read from your own config.yaml.`,
+	Example: `  mcpenetes trust
+  mcpenetes trust --list
+  mcpenetes trust --revoke`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		list, _ := cmd.Flags().GetBool("list")
+		revoke, _ := cmd.Flags().GetBool("revoke")
+
+		if list {
+			listTrustedProjects()
+			return
+		}
+
+		projectDir, err := paths.ProjectConfigDir()
+		if err != nil {
+			log.Fatal("%v", err)
+		}
+		if projectDir == "" {
+			log.Fatal("No %s directory found in the current directory or its parents.", paths.ProjectDirName)
+		}
+
+		if revoke {
+			found, err := config.UntrustProject(projectDir)
+			if err != nil {
+				log.Fatal("Failed to update trusted projects: %v", err)
+			}
+			if !found {
+				log.Warn("%s was not trusted.", projectDir)
+				return
+			}
+			log.Success("%s is no longer trusted.", projectDir)
+			return
+		}
+
+		files, err := config.ProjectFiles(projectDir)
+		if err != nil {
+			log.Fatal("Failed to list the project configuration: %v", err)
+		}
+		log.Info("Trusting the project configuration in %s:", projectDir)
+		for _, file := range files {
+			log.Detail("  %s", file)
+		}
+		if err := config.TrustProject(projectDir); err != nil {
+			log.Fatal("Failed to trust %s: %v", projectDir, err)
+		}
+		log.Success("Trusted. Run 'mcpenetes apply' to apply its servers.")
+	},
+}
+
+// listTrustedProjects prints every trusted project and whether it changed since.
+func listTrustedProjects() {
+	projects, err := config.TrustedProjects()
+	if err != nil {
+		log.Fatal("Failed to read trusted projects: %v", err)
+	}
+	if len(projects) == 0 {
+		log.Info("No trusted projects.")
+		return
+	}
+	dirs := make([]string, 0, len(projects))
+	for dir := range projects {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PROJECT\tTRUSTED\tSTATE")
+	for _, dir := range dirs {
+		state := "unchanged"
+		if hash, err := config.ProjectHash(dir); err != nil {
+			state = "unreadable"
+		} else if hash != projects[dir].Hash {
+			state = "changed, trust again to use it"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", dir, projects[dir].TrustedAt.Local().Format("2006-01-02 15:04"), state)
+	}
+	_ = w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(trustCmd)
+
+	trustCmd.Flags().Bool("list", false, "List the trusted projects")
+	trustCmd.Flags().Bool("revoke", false, "Stop trusting the project configuration in the current directory")
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/log"
+	"github.com/tuannvm/mcpenetes/internal/secrets"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate [FILE]",
+	Short: "Checks mcp.json for errors",
+	Long: `Checks mcp.json and the mcp.d fragments in every configuration layer (system,
+baseline, user and project), or FILE, and reports every problem with its JSON
+path and line:column:
+
+  - each server must have either command or url, not both
+  - URLs must be valid http(s) or ws(s) URLs
+  - env keys must be valid environment variable names
+  - fields must have the right types (e.g. args is a list of strings)
+  - {{...}} templates and ${...} references must be well formed
+  - ${secret:NAME} references must exist in the secret store
+  - a server name must not be defined in more than one of mcp.json and mcp.d
+
+apply and load run the same checks, except for the secret store lookup.
+Exit codes: 0 when the file is valid, 1 otherwise.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		storeDir := ""
+		if configDir, err := config.ConfigDir(); err == nil {
+			storeDir = configDir
+		}
+
+		if len(args) == 0 {
+			// Every layer's mcp.json: system, user and project
+			if storeDir == "" {
+				log.Fatal("Failed to determine config directory")
+			}
+			err := config.ValidateMCPConfigFile(config.ValidateOptions{
+				SecretExists: secretExistsFunc(filepath.Join(storeDir, secrets.DefaultStoreFileName)),
+			})
+			if err != nil {
+				count := printValidationErrors(err)
+				log.Fatal("Found %d problem(s).", count)
+			}
+			log.Success("mcp.json is valid.")
+			return
+		}
+
+		path := args[0]
+		if _, err := os.Stat(path); err != nil {
+			log.Fatal("Failed to read %s: %v", path, err)
+		}
+
+		if storeDir == "" {
+			storeDir = filepath.Dir(path)
+		}
+		err := config.ValidateMCPFile(path, config.ValidateOptions{
+			SecretExists: secretExistsFunc(filepath.Join(storeDir, secrets.DefaultStoreFileName)),
+		})
+		if err != nil {
+			count := printValidationErrors(err)
+			log.Fatal("Found %d problem(s) in %s.", count, path)
+		}
+		log.Success("%s is valid.", path)
+	},
+}
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Prints the JSON Schema of mcp.json",
+	Long: `Prints the JSON Schema of mcp.json, for editor integration. For example, save it
+and reference it from mcp.json with "$schema", or map it to mcp.json in your
+editor's JSON schema settings:
+
+  mcpenetes schema > ~/.config/mcpenetes/mcp.schema.json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		_, _ = os.Stdout.Write(config.MCPSchema)
+	},
+}
+
+// secretExistsFunc returns a lookup of secrets in the store at storePath. The store is only
+// decrypted when the first secret reference is checked; if that fails, the existence checks
+// are skipped with a warning.
+func secretExistsFunc(storePath string) func(name string) (bool, error) {
+	var store *secrets.Store
+	opened, skip := false, false
+	return func(name string) (bool, error) {
+		if !opened {
+			opened = true
+			if _, err := os.Stat(storePath); err == nil {
+				secret, err := secrets.KeyMaterial("", false)
+				if err == nil {
+					store, err = secrets.OpenStore(storePath, secret)
+				}
+				if err != nil {
+					log.Warn("Not checking ${secret:...} references: %v", err)
+					skip = true
+				}
+			}
+		}
+		if skip {
+			return true, nil
+		}
+		if store == nil {
+			return false, nil
+		}
+		_, ok := store.Get(name)
+		return ok, nil
+	}
+}
+
+// printValidationErrors prints the problems of a validation error and returns how many there were.
+func printValidationErrors(err error) int {
+	var errs config.ValidationErrors
+	if !errors.As(err, &errs) {
+		log.Error("%v", err)
+		return 1
+	}
+	for _, validationErr := range errs {
+		log.Fprintf(os.Stderr, log.ErrorColor, "%s\n", validationErr)
+	}
+	return len(errs)
+}
+
+// validateMCPFile exits with the problems in mcp.json, if there are any.
+func validateMCPFile() {
+	if err := config.ValidateMCPConfigFile(config.ValidateOptions{}); err != nil {
+		count := printValidationErrors(err)
+		log.Fatal("mcp.json has %d problem(s); fix them and run 'mcpenetes validate' to check.", count)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(schemaCmd)
+}
@@ -0,0 +1,429 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/log"
+	"github.com/tuannvm/mcpenetes/internal/paths"
+	"github.com/tuannvm/mcpenetes/internal/translator"
+	"github.com/tuannvm/mcpenetes/internal/util"
+)
+
+// serviceName is the name of the systemd user unit written by watch --install-service.
+const serviceName = "mcpenetes-watch.service"
+
+// fileState is what watch compares between polls to notice that a file changed.
+type fileState struct {
+	Exists  bool
+	Size    int64
+	ModTime time.Time
+}
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Keeps clients in sync with mcp.json as files change",
+	Long: `Watches mcp.json, config.yaml and every client's config file, and re-applies the
+configuration once changes settle for the debounce period:
+
+  - when mcp.json or config.yaml change in any layer (system, user or project),
+    every client is re-applied
+  - when a client overwrites or drops servers managed by mcpenetes in its own
+    config file, those servers are enforced again in that client
+
+Clients are updated as with 'apply --all-clients --yes', including backups.
+Files are polled every --interval. Stop watching with Ctrl+C.
+
+With --install-service, a systemd user unit running 'mcpenetes watch' is written
+instead, so watching can start at login.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		clientFilter, _ := cmd.Flags().GetStringArray("client")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		debounce, _ := cmd.Flags().GetDuration("debounce")
+		parallel, _ := cmd.Flags().GetInt("parallel")
+		onConflict, _ := cmd.Flags().GetString("on-conflict")
+		installService, _ := cmd.Flags().GetBool("install-service")
+		if interval <= 0 {
+			log.Fatal("--interval must be positive")
+		}
+		if parallel < 1 {
+			parallel = 1
+		}
+		if onConflict != conflictRetry && onConflict != conflictAbort {
+			log.Fatal("Invalid --on-conflict value '%s': must be %s or %s", onConflict, conflictRetry, conflictAbort)
+		}
+
+		if installService {
+			unitPath, err := installWatchService(watchServiceArgs(cmd))
+			if err != nil {
+				log.Fatal("Failed to install service: %v", err)
+			}
+			log.Success("Wrote %s", unitPath)
+			log.Info("Enable and start it with:")
+			log.Detail("  systemctl --user daemon-reload")
+			log.Detail("  systemctl --user enable --now %s", serviceName)
+			return
+		}
+
+		runWatch(clientFilter, interval, debounce, parallel, onConflict)
+	},
+}
+
+// runWatch polls the watched files until interrupted, syncing clients after changes settle.
+func runWatch(clientFilter []string, interval, debounce time.Duration, parallel int, onConflict string) {
+	configFiles, err := config.LayerFiles()
+	if err != nil {
+		log.Fatal("Failed to determine config paths: %v", err)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Info("Watching %s (every %s, debounce %s). Press Ctrl+C to stop.", strings.Join(configFiles, ", "), interval, debounce)
+
+	// Start from a full sync; it also tells us which client files to watch
+	w := newWatcher(configFiles, watchSync(clientFilter, nil, parallel, onConflict))
+	for {
+		select {
+		case <-stop:
+			log.Info("Stopped watching.")
+			return
+		case <-ticker.C:
+		}
+
+		// Fragments come and go in mcp.d; a new one is a change like any other
+		if files, err := config.LayerFiles(); err == nil {
+			w.setConfigFiles(files)
+		}
+
+		w.observe(w.snapshot(), time.Now())
+		syncAll, pendingClients, ok := w.due(time.Now(), debounce)
+		if !ok {
+			continue
+		}
+
+		if syncAll {
+			log.Info("\nConfiguration changed, re-applying to all clients...")
+			if paths := watchSync(clientFilter, nil, parallel, onConflict); paths != nil {
+				w.clientPaths = paths
+			}
+		} else {
+			watchSync(clientFilter, pendingClients, parallel, onConflict)
+		}
+
+		// Our own writes must not trigger another sync
+		w.synced(w.snapshot())
+	}
+}
+
+// watcher tracks the watched files between polls and decides when to sync what.
+type watcher struct {
+	configFiles  []string
+	isConfigFile map[string]bool
+	clientPaths  map[string]string // Client name to config path
+	states       map[string]fileState
+
+	syncAll        bool
+	pendingClients map[string]bool
+	lastChange     time.Time
+}
+
+// newWatcher starts watching configFiles and the clients in clientPaths from their current state.
+func newWatcher(configFiles []string, clientPaths map[string]string) *watcher {
+	w := &watcher{isConfigFile: make(map[string]bool), clientPaths: clientPaths, pendingClients: make(map[string]bool)}
+	w.setConfigFiles(configFiles)
+	w.states = w.snapshot()
+	return w
+}
+
+// setConfigFiles replaces the watched config files. Files that are new since the last
+// snapshot are seen as changed by the next observe, if they exist.
+func (w *watcher) setConfigFiles(configFiles []string) {
+	w.configFiles = configFiles
+	for _, path := range configFiles {
+		w.isConfigFile[path] = true
+	}
+}
+
+// snapshot records the state of every watched file.
+func (w *watcher) snapshot() map[string]fileState {
+	return snapshotFiles(w.configFiles, w.clientPaths)
+}
+
+// observe compares current with the previous snapshot: a changed config file calls for
+// syncing every client, a changed client file for syncing that client.
+func (w *watcher) observe(current map[string]fileState, now time.Time) {
+	for path, state := range current {
+		if state == w.states[path] {
+			continue
+		}
+		w.lastChange = now
+		if w.isConfigFile[path] {
+			w.syncAll = true
+			continue
+		}
+		for clientName, clientPath := range w.clientPaths {
+			if clientPath == path {
+				w.pendingClients[clientName] = true
+			}
+		}
+	}
+	w.states = current
+}
+
+// due reports whether changes are pending and have settled for debounce, and if so whether
+// every client must be synced or only the returned ones.
+func (w *watcher) due(now time.Time, debounce time.Duration) (bool, map[string]bool, bool) {
+	if (!w.syncAll && len(w.pendingClients) == 0) || now.Sub(w.lastChange) < debounce {
+		return false, nil, false
+	}
+	return w.syncAll, w.pendingClients, true
+}
+
+// synced clears the pending changes after a sync; states is the snapshot taken after it.
+func (w *watcher) synced(states map[string]fileState) {
+	w.syncAll = false
+	w.pendingClients = make(map[string]bool)
+	w.states = states
+}
+
+// watchSync re-applies the configuration to the given clients, or to every client when only
+// is nil. It returns the config path of every watched client. Errors are reported, not fatal,
+// so watching continues, e.g. while mcp.json is being edited and briefly invalid.
+func watchSync(clientFilter []string, only map[string]bool, parallel int, onConflict string) map[string]string {
+	if err := config.ValidateMCPConfigFile(config.ValidateOptions{}); err != nil {
+		printValidationErrors(err)
+		log.Error("mcp.json is invalid; waiting for it to be fixed.")
+		return nil
+	}
+	eff, err := config.LoadEffective()
+	if err != nil {
+		log.Error("Error loading configuration: %v", err)
+		return nil
+	}
+	warnIgnored(eff)
+	reportExcluded(eff)
+	cfg, mcpCfg := eff.Config, eff.MCP
+
+	clients, err := filterClients(resolveClients(cfg), clientFilter)
+	if err != nil {
+		log.Error("%v", err)
+		return nil
+	}
+
+	clientPaths := make(map[string]string, len(clients))
+	for clientName, client := range clients {
+		clientPath, err := util.ExpandPath(client.ConfigPath)
+		if err != nil {
+			log.Error("Failed to expand config path for %s: %v", clientName, err)
+			continue
+		}
+		clientPaths[clientName] = clientPath
+	}
+
+	if len(mcpCfg.MCPServers) == 0 {
+		log.Warn("No MCP servers found in mcp.json. Nothing to apply.")
+		return clientPaths
+	}
+
+	selected := clients
+	if only != nil {
+		selected = make(map[string]config.Client)
+		for clientName := range only {
+			if client, ok := clients[clientName]; ok {
+				selected[clientName] = client
+			}
+		}
+	}
+
+	trans := newTranslator(eff, false)
+	results := renderClients(trans, selected, parallel)
+	reportSkippedServers(results)
+
+	// Name the managed servers a client overwrote or dropped before enforcing them again
+	if only != nil {
+		for _, result := range results {
+			if result.Err != nil || !result.Render.Changed() {
+				continue
+			}
+			statuses, err := translator.ClientStatus(result.Render)
+			if err != nil {
+				continue
+			}
+			var drifted []string
+			for _, status := range statuses {
+				if status.State != translator.StateInSync {
+					drifted = append(drifted, fmt.Sprintf("%s (%s)", status.Name, status.State))
+				}
+			}
+			log.Warn("%s changed managed servers in its config, enforcing them again: %s", result.ClientName, strings.Join(drifted, ", "))
+		}
+	}
+
+	writeClients(trans, selected, results, parallel, onConflict)
+
+	// Only report clients that were written or failed
+	var reported []*clientResult
+	for _, result := range results {
+		if result.Written || result.Err != nil {
+			reported = append(reported, result)
+		}
+	}
+	if len(reported) == 0 {
+		log.Detail("All clients are in sync.")
+	}
+	printApplySummary(reported)
+	return clientPaths
+}
+
+// snapshotFiles records the state of every layer's config.yaml and mcp.json and of the client config files.
+func snapshotFiles(configFiles []string, clientPaths map[string]string) map[string]fileState {
+	states := make(map[string]fileState, len(configFiles)+len(clientPaths))
+	for _, path := range configFiles {
+		states[path] = statFile(path)
+	}
+	for _, clientPath := range clientPaths {
+		states[clientPath] = statFile(clientPath)
+	}
+	return states
+}
+
+// statFile returns the state of the file at path; a missing or unreadable file does not exist.
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{Exists: true, Size: info.Size(), ModTime: info.ModTime()}
+}
+
+// watchServiceArgs returns the watch flags the user set, to pass them on to the service.
+func watchServiceArgs(cmd *cobra.Command) []string {
+	args := []string{"watch", "--non-interactive"}
+	for _, name := range []string{"interval", "debounce", "parallel", "on-conflict", "lock-timeout"} {
+		if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+			args = append(args, fmt.Sprintf("--%s=%s", name, flag.Value.String()))
+		}
+	}
+	clientFilter, _ := cmd.Flags().GetStringArray("client")
+	for _, clientName := range clientFilter {
+		args = append(args, "--client="+clientName)
+	}
+
+	// Relative directories are made absolute, except a --home that is relative to --root
+	rootFlag := cmd.Flags().Lookup("root")
+	for _, name := range []string{"root", "home"} {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || !flag.Changed {
+			continue
+		}
+		dir := flag.Value.String()
+		if name == "root" || !rootFlag.Changed {
+			if abs, err := filepath.Abs(dir); err == nil {
+				dir = abs
+			}
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", name, dir))
+	}
+
+	// The service doesn't inherit this shell's environment, so pin a relocated config directory
+	if flag := cmd.Flags().Lookup("config-dir"); (flag != nil && flag.Changed) || os.Getenv(paths.HomeEnv) != "" {
+		if configDir, err := config.ConfigDir(); err == nil {
+			args = append(args, "--config-dir="+configDir)
+		}
+	}
+	return args
+}
+
+// installWatchService writes a systemd user unit that runs this executable with args.
+func installWatchService(args []string) (string, error) {
+	if runtime.GOOS != "linux" {
+		return "", fmt.Errorf("systemd services are only supported on Linux")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the mcpenetes executable: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	unitDir, err := systemdUserUnitDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(unitDir, 0750); err != nil {
+		return "", fmt.Errorf("failed to create directory '%s': %w", unitDir, err)
+	}
+
+	unitPath := filepath.Join(unitDir, serviceName)
+	if err := os.WriteFile(unitPath, []byte(watchServiceUnit(executable, args)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write '%s': %w", unitPath, err)
+	}
+	return unitPath, nil
+}
+
+// systemdUserUnitDir returns the directory of the user's systemd units, under $XDG_CONFIG_HOME or ~/.config.
+func systemdUserUnitDir() (string, error) {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get user home directory: %w", err)
+		}
+		configHome = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configHome, "systemd", "user"), nil
+}
+
+// watchServiceUnit returns the systemd unit running executable with args.
+func watchServiceUnit(executable string, args []string) string {
+	execStart := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{executable}, args...) {
+		execStart = append(execStart, systemdQuote(arg))
+	}
+	return fmt.Sprintf(`[Unit]
+Description=mcpenetes: keep MCP clients in sync with mcp.json
+
+[Service]
+Type=simple
+ExecStart=%s
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=default.target
+`, strings.Join(execStart, " "))
+}
+
+// systemdQuote quotes a command line argument for ExecStart when needed.
+func systemdQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\%$;") {
+		return arg
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, `%`, `%%`, `$`, `$$`)
+	return `"` + replacer.Replace(arg) + `"`
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().StringArray("client", nil, "Only watch and apply to the given client (can be repeated)")
+	watchCmd.Flags().Duration("interval", time.Second, "How often to check the watched files for changes")
+	watchCmd.Flags().Duration("debounce", 2*time.Second, "How long changes must settle before re-applying")
+	watchCmd.Flags().Int("parallel", defaultParallelism, "Maximum number of clients processed concurrently")
+	watchCmd.Flags().String("on-conflict", conflictRetry, "When a client rewrites its config during apply: retry or abort")
+	watchCmd.Flags().Bool("install-service", false, "Write a systemd user unit that runs 'mcpenetes watch' instead of watching")
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestWatcherDebounce(t *testing.T) {
+	dir := t.TempDir()
+	mcpFile := filepath.Join(dir, "mcp.json")
+	clientFile := filepath.Join(dir, "cursor.json")
+	otherClient := filepath.Join(dir, "claude.json")
+	for _, path := range []string{mcpFile, clientFile, otherClient} {
+		if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := newWatcher([]string{mcpFile}, map[string]string{"cursor": clientFile, "claude-desktop": otherClient})
+	start := time.Now()
+	const debounce = 2 * time.Second
+
+	// Nothing changed
+	w.observe(w.snapshot(), start)
+	if _, _, ok := w.due(start.Add(time.Hour), debounce); ok {
+		t.Fatal("expected no sync without changes")
+	}
+
+	// A client rewrites its config: only that client is synced, once changes settle
+	if err := os.WriteFile(clientFile, []byte(`{"mcpServers": {}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	w.observe(w.snapshot(), start)
+	if _, _, ok := w.due(start.Add(time.Second), debounce); ok {
+		t.Error("expected the sync to wait for the debounce period")
+	}
+	syncAll, clients, ok := w.due(start.Add(debounce), debounce)
+	if !ok || syncAll || !reflect.DeepEqual(clients, map[string]bool{"cursor": true}) {
+		t.Errorf("expected cursor alone to be synced, got ok=%v syncAll=%v clients=%v", ok, syncAll, clients)
+	}
+	w.synced(w.snapshot())
+	if _, _, ok := w.due(start.Add(time.Hour), debounce); ok {
+		t.Error("expected nothing pending after a sync")
+	}
+
+	// Changes keep coming: the debounce period restarts with each one
+	if err := os.WriteFile(mcpFile, []byte(`{"mcpServers": {}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	w.observe(w.snapshot(), start)
+	if err := os.WriteFile(mcpFile, []byte(`{"mcpServers": {"a": {}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	w.observe(w.snapshot(), start.Add(time.Second))
+	if _, _, ok := w.due(start.Add(debounce), debounce); ok {
+		t.Error("expected the debounce period to restart with the second change")
+	}
+	if syncAll, _, ok := w.due(start.Add(time.Second+debounce), debounce); !ok || !syncAll {
+		t.Errorf("expected a config change to sync every client, got ok=%v syncAll=%v", ok, syncAll)
+	}
+	w.synced(w.snapshot())
+
+	// A fragment that appears later counts as a config change
+	fragment := filepath.Join(dir, "mcp.d", "team.json")
+	w.setConfigFiles([]string{mcpFile, fragment})
+	w.observe(w.snapshot(), start)
+	if _, _, ok := w.due(start.Add(time.Hour), debounce); ok {
+		t.Error("expected a missing fragment not to be a change")
+	}
+	if err := os.MkdirAll(filepath.Dir(fragment), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fragment, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	w.observe(w.snapshot(), start)
+	if syncAll, _, ok := w.due(start.Add(debounce), debounce); !ok || !syncAll {
+		t.Errorf("expected a new fragment to sync every client, got ok=%v syncAll=%v", ok, syncAll)
+	}
+}
+
+func TestWatchSyncClientFilter(t *testing.T) {
+	clientPaths := statusFixture(t)
+	for _, path := range clientPaths {
+		if err := os.WriteFile(path, []byte(`{"mcpServers": {}}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	watched := watchSync([]string{"claude-desktop"}, nil, 1, conflictRetry)
+	if !reflect.DeepEqual(watched, map[string]string{"claude-desktop": clientPaths["claude-desktop"]}) {
+		t.Errorf("expected only claude-desktop to be watched, got %v", watched)
+	}
+	if data, _ := os.ReadFile(clientPaths["claude-desktop"]); !strings.Contains(string(data), "mcp-server-fetch") {
+		t.Errorf("expected claude-desktop to be synced, got:\n%s", data)
+	}
+	if data, _ := os.ReadFile(clientPaths["cursor"]); string(data) != `{"mcpServers": {}}` {
+		t.Errorf("expected cursor to be left alone, got:\n%s", data)
+	}
+
+	// Syncing only the clients that changed leaves the others alone too
+	watchSync(nil, map[string]bool{"cursor": true}, 1, conflictRetry)
+	if data, _ := os.ReadFile(clientPaths["cursor"]); !strings.Contains(string(data), "mcp-server-fetch") {
+		t.Errorf("expected cursor to be synced, got:\n%s", data)
+	}
+}
+
+func TestSystemdQuote(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"watch", "watch"},
+		{"--interval=5s", "--interval=5s"},
+		{"", `""`},
+		{"/home/me/My Configs", `"/home/me/My Configs"`},
+		{"--client=50%", `"--client=50%%"`},
+		{"$HOME/.config", `"$$HOME/.config"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"it's", `"it's"`},
+		{`C:\mcp`, `"C:\\mcp"`},
+		{"a;b", `"a;b"`},
+		{"tab\there", `"tab\there"`},
+		{"line\nbreak", `"line\nbreak"`},
+		{`%h/$USER "x"`, `"%%h/$$USER \"x\""`},
+	}
+	for _, tt := range tests {
+		if got := systemdQuote(tt.arg); got != tt.want {
+			t.Errorf("systemdQuote(%q) = %s, expected %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestWatchServiceUnit(t *testing.T) {
+	unit := watchServiceUnit("/opt/mcp tools/mcpenetes", []string{"watch", "--non-interactive", "--config-dir=/home/me/100%"})
+	for _, expected := range []string{
+		"[Service]\nType=simple\n",
+		`ExecStart="/opt/mcp tools/mcpenetes" watch --non-interactive "--config-dir=/home/me/100%%"` + "\n",
+		"Restart=on-failure\n",
+		"[Install]\nWantedBy=default.target\n",
+	} {
+		if !strings.Contains(unit, expected) {
+			t.Errorf("expected %q in the unit:\n%s", expected, unit)
+		}
+	}
+}
+
+func TestSystemdUserUnitDir(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	if dir, err := systemdUserUnitDir(); err != nil || dir != filepath.Join("/xdg", "systemd", "user") {
+		t.Errorf("expected the unit under XDG_CONFIG_HOME, got %s, %v", dir, err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/home/me")
+	if dir, err := systemdUserUnitDir(); err != nil || dir != filepath.Join("/home/me", ".config", "systemd", "user") {
+		t.Errorf("expected the unit under ~/.config, got %s, %v", dir, err)
+	}
+}
+
+func TestWatchServiceArgs(t *testing.T) {
+	t.Setenv("MCPENETES_HOME", "")
+	cmd := &cobra.Command{Use: "watch"}
+	cmd.Flags().Duration("interval", time.Second, "")
+	cmd.Flags().Duration("debounce", 2*time.Second, "")
+	cmd.Flags().StringArray("client", nil, "")
+	cmd.Flags().String("root", "", "")
+	cmd.Flags().String("home", "", "")
+	if err := cmd.ParseFlags([]string{"--interval=5s", "--client=cursor", "--client=vscode", "--root=image", "--home=/home/me"}); err != nil {
+		t.Fatal(err)
+	}
+	root, err := filepath.Abs("image")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only flags the user set are passed on; --home stays relative to --root
+	args := watchServiceArgs(cmd)
+	want := []string{"watch", "--non-interactive", "--interval=5s", "--client=cursor", "--client=vscode", "--root=" + root, "--home=/home/me"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("expected %v, got %v", want, args)
+	}
+}
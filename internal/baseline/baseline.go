@@ -0,0 +1,312 @@
+// Package baseline fetches a team's shared mcp.json (and optionally config.yaml) from a git
+// repository or an HTTP URL, and records the fetched revision in a lock file.
+package baseline
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Files a baseline can contain. mcp.json is required, config.yaml is optional.
+const (
+	MCPFileName    = "mcp.json"
+	ConfigFileName = "config.yaml"
+)
+
+// BundleFiles lists the files of a baseline, in the order they are fetched.
+var BundleFiles = []string{MCPFileName, ConfigFileName}
+
+// httpTimeout bounds fetching a baseline over HTTP.
+const httpTimeout = 30 * time.Second
+
+// Source is where a baseline is fetched from. URL is a git repository (a local path or any
+// URL git understands) or an http(s) URL of an mcp.json file. Ref and Path only apply to git:
+// the commit-ish to fetch (default HEAD) and the directory holding the files (default the root).
+// Insecure allows fetching over unencrypted http:// and git:// URLs.
+type Source struct {
+	URL      string
+	Ref      string
+	Path     string
+	Insecure bool
+}
+
+// insecureSchemes are the URL schemes that fetch without encryption.
+var insecureSchemes = []string{"http://", "git://"}
+
+// Check returns an error if the source must not be fetched: a URL or ref that git would take
+// for an option, or an unencrypted URL unless Insecure is set. A fetched baseline can add
+// servers that run on this machine, so it must not be open to tampering on the way.
+func (s Source) Check() error {
+	if strings.HasPrefix(s.URL, "-") {
+		return fmt.Errorf("invalid baseline source '%s': must not start with '-'", s.URL)
+	}
+	if strings.HasPrefix(s.Ref, "-") {
+		return fmt.Errorf("invalid baseline ref '%s': must not start with '-'", s.Ref)
+	}
+	if s.Insecure {
+		return nil
+	}
+	for _, scheme := range insecureSchemes {
+		if strings.HasPrefix(strings.ToLower(s.URL), scheme) {
+			return fmt.Errorf("baseline source '%s' is not encrypted; use https, or pass --insecure to sync from it anyway", s.URL)
+		}
+	}
+	return nil
+}
+
+// IsHTTP reports whether the source is fetched over HTTP rather than with git: an http(s)
+// URL of a .json file.
+func (s Source) IsHTTP() bool {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return strings.HasSuffix(strings.ToLower(u.Path), ".json")
+}
+
+// Fetched is a baseline as fetched from its source.
+type Fetched struct {
+	Revision string            // Git commit, or sha256:<hex> of the content fetched over HTTP
+	Files    map[string][]byte // Bundle file name -> content
+}
+
+// Fetch fetches the baseline. Git repositories are mirrored under cacheDir, so later fetches
+// only transfer what changed.
+func Fetch(src Source, cacheDir string) (*Fetched, error) {
+	if src.URL == "" {
+		return nil, errors.New("no baseline source configured")
+	}
+	if err := src.Check(); err != nil {
+		return nil, err
+	}
+	if src.IsHTTP() {
+		return fetchHTTP(src.URL, src.Insecure)
+	}
+	return fetchGit(src, cacheDir)
+}
+
+// fetchHTTP downloads an mcp.json file. Redirects to plain http are only followed if insecure.
+func fetchHTTP(rawURL string, insecure bool) (*Fetched, error) {
+	client := &http.Client{
+		Timeout: httpTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" && !insecure {
+				return fmt.Errorf("refusing to follow a redirect to unencrypted %s", req.URL)
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch baseline from %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch baseline from %s: %s", rawURL, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline from %s: %w", rawURL, err)
+	}
+	return &Fetched{Revision: "sha256:" + hashBytes(data), Files: map[string][]byte{MCPFileName: data}}, nil
+}
+
+// fetchGit updates a mirror of the repository and reads the bundle files at the ref.
+func fetchGit(src Source, cacheDir string) (*Fetched, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, errors.New("git is required to sync from a git repository, but it was not found in PATH")
+	}
+
+	// Never let git use a transport the source doesn't allow, e.g. one set up by a redirect
+	protocols := "file:ssh:https"
+	if src.Insecure {
+		protocols += ":http:git"
+	}
+	remote := []string{"GIT_ALLOW_PROTOCOL=" + protocols}
+
+	mirror := filepath.Join(cacheDir, "baseline", hashBytes([]byte(src.URL))[:16]+".git")
+	if _, err := os.Stat(mirror); err == nil {
+		if _, err := runGit(remote, mirror, "fetch", "--quiet", "--prune", "origin"); err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", src.URL, err)
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(mirror), 0750); err != nil {
+			return nil, fmt.Errorf("failed to create directory '%s': %w", filepath.Dir(mirror), err)
+		}
+		if _, err := runGit(remote, "", "clone", "--quiet", "--mirror", "--", src.URL, mirror); err != nil {
+			_ = os.RemoveAll(mirror)
+			return nil, fmt.Errorf("failed to clone %s: %w", src.URL, err)
+		}
+	}
+
+	ref := src.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	out, err := git(mirror, "rev-parse", "--verify", "--quiet", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("ref '%s' not found in %s", ref, src.URL)
+	}
+	fetched := &Fetched{Revision: strings.TrimSpace(string(out)), Files: make(map[string][]byte)}
+
+	dir := strings.Trim(path.Clean("/"+filepath.ToSlash(src.Path)), "/")
+	for _, name := range BundleFiles {
+		object := fetched.Revision + ":" + path.Join(dir, name)
+		if _, err := git(mirror, "cat-file", "-e", object); err != nil {
+			continue // Not part of this bundle
+		}
+		content, err := git(mirror, "show", object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from %s: %w", path.Join(dir, name), src.URL, err)
+		}
+		fetched.Files[name] = content
+	}
+	if _, ok := fetched.Files[MCPFileName]; !ok {
+		return nil, fmt.Errorf("%s has no %s at %s", src.URL, path.Join(dir, MCPFileName), ref)
+	}
+	return fetched, nil
+}
+
+// git runs git in dir (or the current directory) without prompting, returning its stdout.
+func git(dir string, args ...string) ([]byte, error) {
+	return runGit(nil, dir, args...)
+}
+
+// runGit runs git like git does, with env added to its environment.
+func runGit(env []string, dir string, args ...string) ([]byte, error) {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0"), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// hashBytes returns the hex sha256 of data.
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Lock records the baseline that was installed, so later syncs and checks can tell whether
+// it is current and unmodified.
+type Lock struct {
+	Source    string            `json:"source"`
+	Ref       string            `json:"ref,omitempty"`
+	Path      string            `json:"path,omitempty"`
+	Revision  string            `json:"revision"`
+	FetchedAt time.Time         `json:"fetched_at"`
+	Files     map[string]string `json:"files"` // Bundle file name -> sha256 of its content
+}
+
+// NewLock returns the lock for a fetched baseline.
+func NewLock(src Source, fetched *Fetched) *Lock {
+	files := make(map[string]string, len(fetched.Files))
+	for name, content := range fetched.Files {
+		files[name] = hashBytes(content)
+	}
+	return &Lock{Source: src.URL, Ref: src.Ref, Path: src.Path, Revision: fetched.Revision, FetchedAt: time.Now().UTC(), Files: files}
+}
+
+// ReadLock reads a lock file. It returns nil if the file does not exist.
+func ReadLock(lockPath string) (*Lock, error) {
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read baseline lock '%s': %w", lockPath, err)
+	}
+	var l Lock
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline lock '%s': %w", lockPath, err)
+	}
+	return &l, nil
+}
+
+// Write saves the lock file.
+func (l *Lock) Write(lockPath string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal baseline lock: %w", err)
+	}
+	return writeFile(lockPath, data)
+}
+
+// SameSource reports whether the lock was made from src.
+func (l *Lock) SameSource(src Source) bool {
+	return l.Source == src.URL && l.Ref == src.Ref && l.Path == src.Path
+}
+
+// Modified returns the bundle files in dir whose content no longer matches the lock, sorted.
+func (l *Lock) Modified(dir string) []string {
+	var modified []string
+	for _, name := range BundleFiles {
+		want, locked := l.Files[name]
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		switch {
+		case err != nil && locked, err == nil && !locked, err == nil && hashBytes(data) != want:
+			modified = append(modified, name)
+		}
+	}
+	return modified
+}
+
+// Install writes the fetched files into dir and removes bundle files the baseline no longer has.
+func Install(dir string, fetched *Fetched) error {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("failed to create baseline directory '%s': %w", dir, err)
+	}
+	for _, name := range BundleFiles {
+		target := filepath.Join(dir, name)
+		content, ok := fetched.Files[name]
+		if !ok {
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove '%s': %w", target, err)
+			}
+			continue
+		}
+		if err := writeFile(target, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile replaces a file atomically through a temporary file in the same directory.
+func writeFile(target string, data []byte) error {
+	tmp := target + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write '%s': %w", tmp, err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write '%s': %w", target, err)
+	}
+	return nil
+}
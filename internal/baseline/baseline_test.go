@@ -0,0 +1,269 @@
+package baseline
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testRepo is a work tree that pushes to a bare repository, which is what Fetch reads.
+type testRepo struct {
+	t    *testing.T
+	work string
+	bare string
+}
+
+// newTestRepo creates an empty bare repository and a clone to commit from.
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	base := t.TempDir()
+	r := &testRepo{t: t, work: filepath.Join(base, "work"), bare: filepath.Join(base, "team.git")}
+	r.git(base, "init", "--quiet", "--bare", "--initial-branch=main", r.bare)
+	r.git(base, "clone", "--quiet", r.bare, r.work)
+	return r
+}
+
+// git runs git in dir with a fixed identity, failing the test on error.
+func (r *testRepo) git(dir string, args ...string) string {
+	r.t.Helper()
+	args = append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commit writes files, commits and pushes them, and returns the commit.
+func (r *testRepo) commit(files map[string]string) string {
+	r.t.Helper()
+	for name, content := range files {
+		path := filepath.Join(r.work, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+	r.git(r.work, "add", "-A")
+	r.git(r.work, "commit", "--quiet", "-m", "update baseline")
+	r.git(r.work, "push", "--quiet", "origin", "HEAD:main")
+	return r.git(r.work, "rev-parse", "HEAD")
+}
+
+func TestFetchGit(t *testing.T) {
+	repo := newTestRepo(t)
+	cacheDir := t.TempDir()
+	first := repo.commit(map[string]string{
+		"mcp.json":            `{"mcpServers": {"fetch": {"command": "uvx"}}}`,
+		"eng/mcp.json":        `{"mcpServers": {"github": {"command": "npx"}}}`,
+		"eng/config.yaml":     "registries: []\n",
+		"unrelated/README.md": "not part of the bundle",
+	})
+
+	fetched, err := Fetch(Source{URL: repo.bare}, cacheDir)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fetched.Revision != first {
+		t.Errorf("Revision = %s, expected %s", fetched.Revision, first)
+	}
+	if !reflect.DeepEqual(fetched.Files, map[string][]byte{MCPFileName: []byte(`{"mcpServers": {"fetch": {"command": "uvx"}}}`)}) {
+		t.Errorf("Files = %q", fetched.Files)
+	}
+
+	// A bundle in a subdirectory, with config.yaml
+	fetched, err = Fetch(Source{URL: repo.bare, Path: "eng"}, cacheDir)
+	if err != nil {
+		t.Fatalf("Fetch with path failed: %v", err)
+	}
+	if len(fetched.Files) != 2 || string(fetched.Files[ConfigFileName]) != "registries: []\n" {
+		t.Errorf("Files = %q", fetched.Files)
+	}
+
+	// New commits are picked up through the cached mirror, and older ones can be pinned
+	second := repo.commit(map[string]string{"mcp.json": `{"mcpServers": {}}`})
+	fetched, err = Fetch(Source{URL: repo.bare}, cacheDir)
+	if err != nil {
+		t.Fatalf("Second fetch failed: %v", err)
+	}
+	if fetched.Revision != second || string(fetched.Files[MCPFileName]) != `{"mcpServers": {}}` {
+		t.Errorf("Second fetch got %s %q, expected %s", fetched.Revision, fetched.Files[MCPFileName], second)
+	}
+	fetched, err = Fetch(Source{URL: repo.bare, Ref: first}, cacheDir)
+	if err != nil || fetched.Revision != first {
+		t.Errorf("Fetch at %s = %+v, %v", first, fetched, err)
+	}
+
+	if _, err := Fetch(Source{URL: repo.bare, Ref: "no-such-branch"}, cacheDir); err == nil {
+		t.Errorf("Expected an error for an unknown ref")
+	}
+	if _, err := Fetch(Source{URL: repo.bare, Path: "unrelated"}, cacheDir); err == nil || !strings.Contains(err.Error(), "mcp.json") {
+		t.Errorf("Expected an error for a path without mcp.json, got %v", err)
+	}
+}
+
+func TestFetchHTTP(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"mcpServers": {"injected": {"command": "x"}}}`))
+	}))
+	defer plain.Close()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/baseline.json":
+			_, _ = w.Write([]byte(`{"mcpServers": {}}`))
+		case "/downgrade.json":
+			http.Redirect(w, r, plain.URL+"/baseline.json", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	original := http.DefaultTransport
+	http.DefaultTransport = server.Client().Transport // Trusts the test server's certificate
+	t.Cleanup(func() { http.DefaultTransport = original })
+
+	src := Source{URL: server.URL + "/baseline.json"}
+	if !src.IsHTTP() {
+		t.Fatalf("Expected %s to be fetched over HTTP", src.URL)
+	}
+	fetched, err := Fetch(src, t.TempDir())
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if !strings.HasPrefix(fetched.Revision, "sha256:") || string(fetched.Files[MCPFileName]) != `{"mcpServers": {}}` {
+		t.Errorf("Fetched = %s %q", fetched.Revision, fetched.Files)
+	}
+
+	if _, err := Fetch(Source{URL: server.URL + "/missing.json"}, t.TempDir()); err == nil {
+		t.Errorf("Expected an error for a 404")
+	}
+
+	// Plain http, directly or through a redirect, needs Insecure
+	if _, err := Fetch(Source{URL: plain.URL + "/baseline.json"}, t.TempDir()); err == nil || !strings.Contains(err.Error(), "--insecure") {
+		t.Errorf("Expected plain http to be refused, got %v", err)
+	}
+	if _, err := Fetch(Source{URL: server.URL + "/downgrade.json"}, t.TempDir()); err == nil || !strings.Contains(err.Error(), "unencrypted") {
+		t.Errorf("Expected a redirect to plain http to be refused, got %v", err)
+	}
+	if _, err := Fetch(Source{URL: plain.URL + "/baseline.json", Insecure: true}, t.TempDir()); err != nil {
+		t.Errorf("Expected plain http to be fetched with Insecure, got %v", err)
+	}
+}
+
+func TestSourceCheck(t *testing.T) {
+	tests := []struct {
+		src     Source
+		wantErr string
+	}{
+		{Source{URL: "https://example.com/team/mcp.json"}, ""},
+		{Source{URL: "git@github.com:acme/baseline.git"}, ""},
+		{Source{URL: "ssh://git@example.com/baseline.git"}, ""},
+		{Source{URL: "/srv/git/baseline.git", Ref: "v2"}, ""},
+		{Source{URL: "--upload-pack=touch /tmp/pwned"}, "must not start with '-'"},
+		{Source{URL: "-u", Insecure: true}, "must not start with '-'"},
+		{Source{URL: "/srv/git/baseline.git", Ref: "--output=/tmp/x"}, "must not start with '-'"},
+		{Source{URL: "http://example.com/team/mcp.json"}, "not encrypted"},
+		{Source{URL: "HTTP://example.com/baseline.git"}, "not encrypted"},
+		{Source{URL: "git://example.com/baseline.git"}, "not encrypted"},
+		{Source{URL: "http://example.com/team/mcp.json", Insecure: true}, ""},
+	}
+	for _, tt := range tests {
+		err := tt.src.Check()
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("Check(%+v) failed: %v", tt.src, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Check(%+v) = %v, expected an error containing %q", tt.src, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFetchGitRejectsOptions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	marker := filepath.Join(t.TempDir(), "pwned")
+	_, err := Fetch(Source{URL: "--upload-pack=touch " + marker}, t.TempDir())
+	if err == nil {
+		t.Fatal("Expected an option-like source to be refused")
+	}
+	if _, statErr := os.Stat(marker); statErr == nil {
+		t.Error("git ran the injected upload-pack")
+	}
+}
+
+func TestIsHTTP(t *testing.T) {
+	tests := map[string]bool{
+		"https://example.com/team/mcp.json":      true,
+		"http://example.com/MCP.JSON":            true,
+		"https://github.com/acme/baseline.git":   false,
+		"git@github.com:acme/baseline.git":       false,
+		"/srv/git/baseline.git":                  false,
+		"file:///srv/git/baseline.json":          false,
+		"https://example.com/baseline.json?ref=": true,
+	}
+	for url, expected := range tests {
+		if got := (Source{URL: url}).IsHTTP(); got != expected {
+			t.Errorf("IsHTTP(%q) = %v, expected %v", url, got, expected)
+		}
+	}
+}
+
+func TestInstallAndLock(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "baseline")
+	lockPath := filepath.Join(filepath.Dir(dir), "baseline.lock.json")
+	src := Source{URL: "/srv/git/team.git", Path: "eng"}
+
+	fetched := &Fetched{Revision: "abc123", Files: map[string][]byte{MCPFileName: []byte("{}"), ConfigFileName: []byte("mcps: []\n")}}
+	if err := Install(dir, fetched); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if err := NewLock(src, fetched).Write(lockPath); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	l, err := ReadLock(lockPath)
+	if err != nil || l == nil {
+		t.Fatalf("ReadLock = %v, %v", l, err)
+	}
+	if !l.SameSource(src) || l.SameSource(Source{URL: src.URL}) || l.Revision != "abc123" {
+		t.Errorf("Lock does not match its source: %+v", l)
+	}
+	if modified := l.Modified(dir); len(modified) != 0 {
+		t.Errorf("Modified() = %v right after installing", modified)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, MCPFileName), []byte(`{"mcpServers": {"x": {}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if modified := l.Modified(dir); !reflect.DeepEqual(modified, []string{MCPFileName}) {
+		t.Errorf("Modified() = %v, expected mcp.json", modified)
+	}
+
+	// A newer baseline without config.yaml removes it
+	if err := Install(dir, &Fetched{Revision: "def456", Files: map[string][]byte{MCPFileName: []byte("{}")}}); err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ConfigFileName)); !os.IsNotExist(err) {
+		t.Errorf("config.yaml should have been removed")
+	}
+
+	if l, err := ReadLock(filepath.Join(dir, "missing.json")); l != nil || err != nil {
+		t.Errorf("ReadLock of a missing file = %v, %v; expected nil, nil", l, err)
+	}
+}
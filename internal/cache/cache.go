@@ -5,13 +5,12 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"log" // Import standard log package
 	"net/url"
 	"os"
 	"path/filepath"
 	"time"
-	// Keep internal log if needed elsewhere, otherwise remove
-	// internalLog "github.com/tuannvm/mcpenetes/internal/log"
+
+	"github.com/tuannvm/mcpenetes/internal/paths"
 )
 
 const (
@@ -25,19 +24,24 @@ type CacheEntry struct {
 	Versions  []string  `json:"versions"`
 }
 
-// cacheDirPath stores the path to the cache directory. Initialized by init().
+// cacheDirPath stores the path to the cache directory. Resolved on first use by ensureCacheDir,
+// so that --config-dir and the environment are taken into account.
 var cacheDirPath string
 
-// init ensures the cache directory exists.
-func init() {
-	homeDir, err := os.UserHomeDir()
+// ensureCacheDir resolves and creates the cache directory if that wasn't done yet.
+func ensureCacheDir() error {
+	if cacheDirPath != "" {
+		return nil
+	}
+	dir, err := paths.CacheDir()
 	if err != nil {
-		log.Fatalf("failed to get user home directory: %v", err) // Use standard log.Fatalf
+		return fmt.Errorf("failed to determine cache directory: %w", err)
 	}
-	cacheDirPath = filepath.Join(homeDir, ".config/mcpetes/cache")
-	if err := os.MkdirAll(cacheDirPath, 0755); err != nil {
-		log.Fatalf("failed to create cache directory '%s': %v", cacheDirPath, err) // Use standard log.Fatalf
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory '%s': %w", dir, err)
 	}
+	cacheDirPath = dir
+	return nil
 }
 
 // getCachePath generates a unique and safe file path for a given registry URL.
@@ -56,6 +60,10 @@ var getCachePath = func(registryURL string) (string, error) {
 	cacheKey := hex.EncodeToString(hasher.Sum(nil))
 	cacheFileName := cacheKey + ".json"
 
+	if err := ensureCacheDir(); err != nil {
+		return "", err
+	}
+
 	return filepath.Join(cacheDirPath, cacheFileName), nil
 }
 
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"strings"
+)
+
+// Condition limits a server to the machines it applies to. Every field that is set must hold:
+// the OS is one of OS, the hostname matches one of the Hostname globs, and every command in
+// CommandExists is found in PATH.
+type Condition struct {
+	OS            StringList `json:"os,omitempty"`
+	Hostname      StringList `json:"hostname,omitempty"`
+	CommandExists StringList `json:"command_exists,omitempty"`
+}
+
+// knownConditionFields lists the JSON keys of a Condition.
+var knownConditionFields = map[string]bool{
+	"os":             true,
+	"hostname":       true,
+	"command_exists": true,
+}
+
+// KnownOSes are the values accepted for the os condition, as reported by Go's runtime.GOOS.
+var KnownOSes = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+	"illumos": true, "ios": true, "linux": true, "netbsd": true, "openbsd": true,
+	"plan9": true, "solaris": true, "windows": true,
+}
+
+// StringList is a list of strings that may be written as a single string in mcp.json.
+type StringList []string
+
+// UnmarshalJSON accepts a string or a list of strings.
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = StringList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("expected a string or a list of strings: %w", err)
+	}
+	*l = list
+	return nil
+}
+
+// MarshalJSON writes a single string as it was written.
+func (l StringList) MarshalJSON() ([]byte, error) {
+	if len(l) == 1 {
+		return json.Marshal(l[0])
+	}
+	return json.Marshal([]string(l))
+}
+
+// HostInfo is what conditions and overlays are checked against.
+type HostInfo struct {
+	OS       string
+	Hostname func() (string, error)
+	LookPath func(file string) (string, error)
+}
+
+// host is the machine mcpenetes runs on; tests replace it.
+var host = HostInfo{OS: runtime.GOOS, Hostname: os.Hostname, LookPath: exec.LookPath}
+
+// hostnames returns the lowercased hostname and, if it is qualified, its first label.
+func (h HostInfo) hostnames() ([]string, error) {
+	name, err := h.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine the hostname: %w", err)
+	}
+	name = strings.ToLower(name)
+	names := []string{name}
+	if short, _, qualified := strings.Cut(name, "."); qualified && short != "" {
+		names = append(names, short)
+	}
+	return names, nil
+}
+
+// Check returns nil if the condition holds on h, or an error explaining why it does not.
+func (c *Condition) Check(h HostInfo) error {
+	if c == nil {
+		return nil
+	}
+	if len(c.OS) > 0 && !containsFold(c.OS, h.OS) {
+		return fmt.Errorf("requires os %s, this is %s", strings.Join(c.OS, " or "), h.OS)
+	}
+	if len(c.Hostname) > 0 {
+		names, err := h.hostnames()
+		if err != nil {
+			return err
+		}
+		if !matchesAny(c.Hostname, names) {
+			return fmt.Errorf("requires hostname %s, this is %s", strings.Join(c.Hostname, " or "), names[0])
+		}
+	}
+	for _, command := range c.CommandExists {
+		if _, err := h.LookPath(command); err != nil {
+			return fmt.Errorf("requires command '%s', which is not in PATH", command)
+		}
+	}
+	return nil
+}
+
+// containsFold reports whether list contains value, ignoring case.
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAny reports whether any of the globs matches any of the names, ignoring case.
+func matchesAny(globs, names []string) bool {
+	for _, glob := range globs {
+		for _, name := range names {
+			if ok, _ := path.Match(strings.ToLower(glob), name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
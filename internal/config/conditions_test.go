@@ -0,0 +1,169 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeHost makes conditions and overlays see a machine with the given OS, hostname and commands.
+func fakeHost(t *testing.T, osName, hostname string, commands ...string) {
+	t.Helper()
+	original := host
+	host = HostInfo{
+		OS:       osName,
+		Hostname: func() (string, error) { return hostname, nil },
+		LookPath: func(file string) (string, error) {
+			for _, command := range commands {
+				if command == file {
+					return "/usr/bin/" + file, nil
+				}
+			}
+			return "", errors.New("not found")
+		},
+	}
+	t.Cleanup(func() { host = original })
+}
+
+func TestConditionCheck(t *testing.T) {
+	fakeHost(t, "darwin", "Work-Laptop.corp.example.com", "docker")
+	tests := []struct {
+		name      string
+		condition *Condition
+		reason    string
+	}{
+		{"no condition", nil, ""},
+		{"os matches", &Condition{OS: StringList{"linux", "darwin"}}, ""},
+		{"os differs", &Condition{OS: StringList{"linux"}}, "requires os linux, this is darwin"},
+		{"short hostname glob", &Condition{Hostname: StringList{"work-*"}}, ""},
+		{"full hostname glob", &Condition{Hostname: StringList{"*.corp.example.com"}}, ""},
+		{"hostname differs", &Condition{Hostname: StringList{"build-?"}}, "requires hostname build-?, this is work-laptop.corp.example.com"},
+		{"command exists", &Condition{CommandExists: StringList{"docker"}}, ""},
+		{"command missing", &Condition{CommandExists: StringList{"docker", "podman"}}, "requires command 'podman', which is not in PATH"},
+		{"all must hold", &Condition{OS: StringList{"darwin"}, CommandExists: StringList{"kubectl"}}, "requires command 'kubectl', which is not in PATH"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.condition.Check(host)
+			reason := ""
+			if err != nil {
+				reason = err.Error()
+			}
+			if reason != tt.reason {
+				t.Errorf("expected %q, got %q", tt.reason, reason)
+			}
+		})
+	}
+}
+
+func TestServerWhenRoundTrip(t *testing.T) {
+	data := `{"command":"docker","when":{"os":"linux","command_exists":["docker","jq"]}}`
+	var server MCPServer
+	if err := json.Unmarshal([]byte(data), &server); err != nil {
+		t.Fatal(err)
+	}
+	if server.When == nil || len(server.When.OS) != 1 || len(server.When.CommandExists) != 2 || server.Extras != nil {
+		t.Fatalf("when not decoded: %+v", server)
+	}
+	out, err := json.Marshal(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != data {
+		t.Errorf("expected %s, got %s", data, out)
+	}
+}
+
+func TestLoadEffectiveOverlaysAndConditions(t *testing.T) {
+	fakeHost(t, "darwin", "laptop.example.com")
+	dir := setupFragments(t, map[string]string{
+		"mcp.json": `{"mcpServers": {
+  "github": {"command": "/usr/bin/github-mcp", "args": ["stdio"]},
+  "docker": {"command": "docker-mcp", "when": {"os": "linux"}},
+  "work": {"url": "https://mcp.work.example.com", "when": {"hostname": "work-*"}},
+  "legacy": {"command": "legacy-mcp"}
+}}`,
+		"mcp.darwin.json": `{"mcpServers": {
+  "github": {"command": "/opt/homebrew/bin/github-mcp"},
+  "legacy": null
+}}`,
+		"mcp.laptop.json": `{"mcpServers": {"notes": {"command": "notes-mcp"}}}`,
+		"mcp.linux.json":  `{"mcpServers": {"github": {"command": "/snap/bin/github-mcp"}}}`,
+	})
+
+	eff, err := LoadEffective()
+	if err != nil {
+		t.Fatalf("LoadEffective failed: %v", err)
+	}
+	github := eff.MCP.MCPServers["github"]
+	if github.Command != "/opt/homebrew/bin/github-mcp" || len(github.Args) != 1 {
+		t.Errorf("expected the darwin overlay to replace only the command, got %+v", github)
+	}
+	if _, ok := eff.MCP.MCPServers["legacy"]; ok {
+		t.Error("expected the overlay to remove legacy")
+	}
+	if _, ok := eff.MCP.MCPServers["notes"]; !ok {
+		t.Error("expected the hostname overlay to add notes")
+	}
+	if eff.Excluded["docker"] != "requires os linux, this is darwin" || !strings.Contains(eff.Excluded["work"], "requires hostname work-*") {
+		t.Errorf("expected docker and work to be excluded, got %v", eff.Excluded)
+	}
+	if _, ok := eff.MCP.MCPServers["docker"]; ok {
+		t.Error("expected docker to be left out")
+	}
+	sources := eff.Sources["mcpServers.github"]
+	if len(sources) != 1 || sources[0].File != filepath.Join(dir, "mcp.darwin.json") {
+		t.Errorf("expected github to come from the darwin overlay, got %+v", sources)
+	}
+
+	// Overlays are read, never written: mcp.json keeps its own definitions
+	mcpCfg, err := LoadMCPConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mcpCfg.MCPServers["github"].Command != "/usr/bin/github-mcp" || len(mcpCfg.MCPServers) != 4 {
+		t.Errorf("expected LoadMCPConfig to ignore overlays, got %v", mcpCfg.MCPServers)
+	}
+}
+
+func TestValidateConditionsAndOverlays(t *testing.T) {
+	fakeHost(t, "linux", "box")
+	setupFragments(t, map[string]string{
+		"mcp.json": `{"mcpServers": {
+  "a": {"command": "a", "when": {"os": "macos", "hostname": "[", "arch": "arm64"}},
+  "b": {"command": "b", "when": {"command_exists": []}}
+}}`,
+		"mcp.linux.json": `{"mcpServers": {"a": {"args": ["--linux"]}, "b": null, "c": {"env": {"1BAD": "x"}}}}`,
+	})
+
+	err := ValidateMCPConfigFile(ValidateOptions{})
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	var messages []string
+	for _, e := range errs {
+		messages = append(messages, e.Path+": "+e.Message)
+	}
+	all := strings.Join(messages, "\n")
+	for _, expected := range []string{
+		`.mcpServers.a.when.arch: unknown condition "arch"`,
+		`.mcpServers.a.when.hostname: invalid hostname glob "["`,
+		`.mcpServers.a.when.os: unknown os "macos"`,
+		`.mcpServers.b.when.command_exists: must be a string or a non-empty list of strings`,
+		`.mcpServers.c.env.1BAD: invalid environment variable name`,
+	} {
+		if !strings.Contains(all, expected) {
+			t.Errorf("expected %q in:\n%s", expected, all)
+		}
+	}
+	if len(errs) != 5 {
+		t.Errorf("expected 5 problems, got %d:\n%s", len(errs), all)
+	}
+	if _, err := os.Stat("mcp.linux.json"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,299 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FragmentDirName is the drop-in directory next to mcp.json. Each *.json, *.yaml or *.yml
+// file in it holds some of the servers, in the same format as mcp.json.
+const FragmentDirName = "mcp.d"
+
+// DuplicateServerError reports servers defined in more than one of mcp.json and its fragments.
+// Servers maps each such server to the files defining it, in load order.
+type DuplicateServerError struct {
+	Servers map[string][]string
+}
+
+// Error names every duplicated server and the files defining it.
+func (e *DuplicateServerError) Error() string {
+	names := make([]string, 0, len(e.Servers))
+	for name := range e.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	problems := make([]string, len(names))
+	for i, name := range names {
+		problems[i] = fmt.Sprintf("server '%s' is defined in more than one file (%s)", name, strings.Join(e.Servers[name], ", "))
+	}
+	return strings.Join(problems, "; ") + "; server names must be unique across mcp.json and " + FragmentDirName
+}
+
+// FragmentName checks the name of an mcp.d fragment given on the command line. A name without
+// a .json, .yaml or .yml extension gets .json.
+func FragmentName(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid fragment name '%s': expected a file name such as github.json", name)
+	}
+	if !isFragmentFile(name) {
+		name += ".json"
+	}
+	return name, nil
+}
+
+// SetServer adds or replaces a server. With a fragment name, the server is saved to that
+// mcp.d fragment; otherwise a replaced server stays where it was defined and a new one is
+// saved to mcp.json.
+func (c *MCPConfig) SetServer(name string, server MCPServer, fragment string) {
+	if c.MCPServers == nil {
+		c.MCPServers = make(map[string]MCPServer)
+	}
+	c.MCPServers[name] = server
+	if fragment == "" {
+		return
+	}
+	if c.Sources == nil {
+		c.Sources = make(map[string]string)
+	}
+	c.Sources[name] = fragment
+}
+
+// FragmentFiles returns the paths of the fragments in the mcp.d directory next to mcpFile,
+// in lexical order. A missing directory has no fragments.
+func FragmentFiles(mcpFile string) ([]string, error) {
+	dir := filepath.Join(filepath.Dir(mcpFile), FragmentDirName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read fragment directory '%s': %w", dir, err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || !isFragmentFile(entry.Name()) {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(files) // os.ReadDir sorts already; lexical order is part of the contract
+	return files, nil
+}
+
+// isFragmentFile reports whether name is a fragment, i.e. a visible JSON or YAML file.
+func isFragmentFile(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// isYAMLFile reports whether a fragment is written in YAML.
+func isYAMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// fragmentJSON returns the content of a fragment as JSON; YAML fragments are converted.
+func fragmentJSON(path string, data []byte) ([]byte, error) {
+	if !isYAMLFile(path) {
+		return data, nil
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse fragment '%s': %w", path, err)
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+	converted, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert fragment '%s' to JSON: %w", path, err)
+	}
+	return converted, nil
+}
+
+// yamlFromJSON converts JSON content written for a YAML fragment to YAML.
+func yamlFromJSON(path string, data []byte) ([]byte, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to convert fragment '%s' to YAML: %w", path, err)
+	}
+	converted, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert fragment '%s' to YAML: %w", path, err)
+	}
+	return converted, nil
+}
+
+// readMCPFile reads an mcp.json or a fragment, migrating it in memory only. It returns nil
+// if the file does not exist.
+func readMCPFile(path string) (*MCPConfig, error) {
+	plan, err := planMCPFile(path)
+	if err != nil || plan == nil {
+		return nil, err
+	}
+	return parseMCPFile(path, plan.After)
+}
+
+// parseMCPFile parses the content of an mcp.json or a fragment.
+func parseMCPFile(path string, data []byte) (*MCPConfig, error) {
+	data, err := fragmentJSON(path, data)
+	if err != nil {
+		return nil, err
+	}
+	var mcpCfg MCPConfig
+	if err := json.Unmarshal(data, &mcpCfg); err != nil {
+		return nil, fmt.Errorf("failed to parse mcp config file '%s': %w", path, err)
+	}
+	return &mcpCfg, nil
+}
+
+// mergeFragments adds the servers of every fragment next to mcpFile to mcpCfg and records
+// where each came from. A server defined in more than one file is a DuplicateServerError.
+// Fragments of an older version are migrated by migrate, which returns the content to use;
+// without it, or when only the version stamp would change, they are migrated in memory only.
+func mergeFragments(mcpCfg *MCPConfig, mcpFile string, migrate func(plan *MigrationPlan) ([]byte, error)) error {
+	files, err := FragmentFiles(mcpFile)
+	if err != nil || len(files) == 0 {
+		return err
+	}
+
+	definedIn := make(map[string][]string)
+	for name := range mcpCfg.MCPServers {
+		definedIn[name] = []string{filepath.Base(mcpFile)}
+	}
+	for _, file := range files {
+		plan, err := planMCPFile(file)
+		if err != nil {
+			return err
+		}
+		if plan == nil {
+			continue // Removed since the directory was read
+		}
+		data := plan.After
+		if migrate != nil && !stampOnly(plan) {
+			if data, err = migrate(plan); err != nil {
+				return err
+			}
+		}
+		fragment, err := parseMCPFile(file, data)
+		if err != nil {
+			return err
+		}
+		name := filepath.Base(file)
+		mcpCfg.Fragments = append(mcpCfg.Fragments, name)
+		for serverName, server := range fragment.MCPServers {
+			definedIn[serverName] = append(definedIn[serverName], filepath.Join(FragmentDirName, name))
+			mcpCfg.SetServer(serverName, server, name)
+		}
+	}
+
+	duplicates := make(map[string][]string)
+	for serverName, files := range definedIn {
+		if len(files) > 1 {
+			duplicates[serverName] = files
+		}
+	}
+	if len(duplicates) > 0 {
+		return &DuplicateServerError{Servers: duplicates}
+	}
+	return nil
+}
+
+// stampOnly reports whether migrating a fragment only adds the version stamp. It is read
+// the same either way, so the file is left as written.
+func stampOnly(plan *MigrationPlan) bool {
+	before, err := parseMCPFile(plan.File, plan.Before)
+	if err != nil {
+		return false
+	}
+	after, err := parseMCPFile(plan.File, plan.After)
+	return err == nil && sameServers(before.MCPServers, after.MCPServers)
+}
+
+// serverFile returns the file a server of mcpCfg, loaded from mcpFile, is defined in.
+func serverFile(mcpCfg *MCPConfig, mcpFile, serverName string) string {
+	if fragment, ok := mcpCfg.Sources[serverName]; ok {
+		return filepath.Join(filepath.Dir(mcpFile), FragmentDirName, fragment)
+	}
+	return mcpFile
+}
+
+// splitFragments returns the servers that are saved to mcp.json itself and, for every
+// fragment that was loaded or is a server's source, the servers saved to it.
+func splitFragments(mcpCfg *MCPConfig) (map[string]MCPServer, map[string]map[string]MCPServer) {
+	main := make(map[string]MCPServer)
+	fragments := make(map[string]map[string]MCPServer)
+	for _, name := range mcpCfg.Fragments {
+		fragments[name] = make(map[string]MCPServer)
+	}
+	for serverName, server := range mcpCfg.MCPServers {
+		fragment, ok := mcpCfg.Sources[serverName]
+		if !ok {
+			main[serverName] = server
+			continue
+		}
+		if fragments[fragment] == nil {
+			fragments[fragment] = make(map[string]MCPServer)
+		}
+		fragments[fragment][serverName] = server
+	}
+	return main, fragments
+}
+
+// saveFragment writes the servers of a fragment. A fragment that already holds exactly
+// these servers is left untouched, so hand-written files keep their formatting.
+func saveFragment(path string, servers map[string]MCPServer) error {
+	current, err := readMCPFile(path)
+	if err != nil {
+		return err
+	}
+	if current != nil && sameServers(current.MCPServers, servers) {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(&MCPConfig{Version: CurrentMCPVersion, MCPServers: servers}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fragment '%s': %w", path, err)
+	}
+	if isYAMLFile(path) {
+		if data, err = yamlFromJSON(path, data); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create fragment directory '%s': %w", filepath.Dir(path), err)
+	}
+	before, beforeExists := readForHistory(path)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write fragment '%s': %w", path, err)
+	}
+	recordHistory(path, before, beforeExists, data)
+	return nil
+}
+
+// sameServers reports whether two sets of servers have the same names and definitions.
+func sameServers(a, b map[string]MCPServer) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, server := range a {
+		other, ok := b[name]
+		if !ok || !server.Equal(other) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tuannvm/mcpenetes/internal/paths"
+)
+
+// setupFragments makes a temporary directory the only configuration layer and writes the
+// given files into it.
+func setupFragments(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv(paths.SystemDirEnv, filepath.Join(dir, "no-system-layer"))
+	t.Chdir(dir)
+	paths.SetConfigDir(dir)
+	t.Cleanup(func() { paths.SetConfigDir("") })
+	return dir
+}
+
+func TestLoadMCPConfigMergesFragments(t *testing.T) {
+	setupFragments(t, map[string]string{
+		"mcp.json":             `{"version": 1, "mcpServers": {"fetch": {"command": "uvx"}}}`,
+		"mcp.d/10-github.json": `{"mcpServers": {"github": {"command": "github-mcp"}}}`,
+		"mcp.d/20-team.yaml": `# Managed by config management
+mcpServers:
+  search:
+    url: https://search.example.com/mcp
+    headers:
+      X-Team: platform
+`,
+		"mcp.d/README.md":    "not a fragment",
+		"mcp.d/.hidden.json": `{"mcpServers": {"hidden": {"command": "x"}}}`,
+	})
+
+	mcpCfg, err := LoadMCPConfig()
+	if err != nil {
+		t.Fatalf("LoadMCPConfig failed: %v", err)
+	}
+	if len(mcpCfg.MCPServers) != 3 {
+		t.Fatalf("expected 3 servers, got %v", mcpCfg.MCPServers)
+	}
+	if mcpCfg.MCPServers["search"].URL != "https://search.example.com/mcp" || mcpCfg.MCPServers["search"].Extras["headers"] == nil {
+		t.Errorf("YAML fragment not decoded: %+v", mcpCfg.MCPServers["search"])
+	}
+	expectedSources := map[string]string{"github": "10-github.json", "search": "20-team.yaml"}
+	if !reflect.DeepEqual(mcpCfg.Sources, expectedSources) {
+		t.Errorf("expected sources %v, got %v", expectedSources, mcpCfg.Sources)
+	}
+	if !reflect.DeepEqual(mcpCfg.Fragments, []string{"10-github.json", "20-team.yaml"}) {
+		t.Errorf("expected fragments in lexical order, got %v", mcpCfg.Fragments)
+	}
+}
+
+func TestLoadMCPConfigDuplicateServers(t *testing.T) {
+	setupFragments(t, map[string]string{
+		"mcp.json":     `{"mcpServers": {"github": {"command": "a"}}}`,
+		"mcp.d/a.json": `{"mcpServers": {"github": {"command": "b"}, "fetch": {"command": "uvx"}}}`,
+		"mcp.d/b.yml":  "mcpServers:\n  fetch:\n    command: uvx\n",
+		"mcp.d/c.json": `{"mcpServers": {"unique": {"command": "c"}}}`,
+	})
+
+	_, err := LoadMCPConfig()
+	var duplicates *DuplicateServerError
+	if !errors.As(err, &duplicates) {
+		t.Fatalf("expected a DuplicateServerError, got %v", err)
+	}
+	expected := map[string][]string{
+		"github": {"mcp.json", filepath.Join("mcp.d", "a.json")},
+		"fetch":  {filepath.Join("mcp.d", "a.json"), filepath.Join("mcp.d", "b.yml")},
+	}
+	if !reflect.DeepEqual(duplicates.Servers, expected) {
+		t.Errorf("expected duplicates %v, got %v", expected, duplicates.Servers)
+	}
+
+	err = ValidateMCPConfigFile(ValidateOptions{})
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expected 2 validation errors, got %v", err)
+	}
+	if !strings.HasSuffix(errs[0].File, filepath.Join("mcp.d", "b.yml")) || errs[0].Path != ".mcpServers.fetch" {
+		t.Errorf("expected the duplicate to be reported at its second definition, got %v", errs[0])
+	}
+}
+
+func TestSaveMCPConfigRoutesFragments(t *testing.T) {
+	const teamFragment = "# Managed by config management\nmcpServers:\n  search:\n    url: https://search.example.com/mcp\n"
+	dir := setupFragments(t, map[string]string{
+		"mcp.json":           `{"version": 1, "mcpServers": {"fetch": {"command": "uvx"}}}`,
+		"mcp.d/github.json":  `{"mcpServers": {"github": {"command": "github-mcp"}}}`,
+		"mcp.d/20-team.yaml": teamFragment,
+	})
+
+	err := UpdateMCPConfig(func(mcpCfg *MCPConfig) error {
+		mcpCfg.SetServer("github", MCPServer{Command: "github-mcp", Args: []string{"stdio"}}, "")
+		mcpCfg.SetServer("notes", MCPServer{Command: "notes-mcp"}, "notes.yaml")
+		mcpCfg.SetServer("time", MCPServer{Command: "time-mcp"}, "")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateMCPConfig failed: %v", err)
+	}
+
+	// A replaced server stays in its fragment, a new one goes where it was routed
+	github, err := readMCPFile(filepath.Join(dir, "mcp.d", "github.json"))
+	if err != nil || len(github.MCPServers) != 1 || len(github.MCPServers["github"].Args) != 1 {
+		t.Errorf("expected github.json to hold the updated server, got %+v, %v", github, err)
+	}
+	notes, err := os.ReadFile(filepath.Join(dir, "mcp.d", "notes.yaml"))
+	if err != nil || !strings.Contains(string(notes), "command: notes-mcp") {
+		t.Errorf("expected notes.yaml to be written as YAML, got %q, %v", notes, err)
+	}
+	main, err := readMCPFile(filepath.Join(dir, "mcp.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(main.MCPServers) != 2 || main.MCPServers["time"].Command != "time-mcp" {
+		t.Errorf("expected mcp.json to hold fetch and time only, got %v", main.MCPServers)
+	}
+
+	// An unchanged fragment is not rewritten
+	team, err := os.ReadFile(filepath.Join(dir, "mcp.d", "20-team.yaml"))
+	if err != nil || string(team) != teamFragment {
+		t.Errorf("expected the untouched fragment to keep its content, got %q, %v", team, err)
+	}
+
+	mcpCfg, err := LoadMCPConfig()
+	if err != nil {
+		t.Fatalf("LoadMCPConfig failed: %v", err)
+	}
+	if len(mcpCfg.MCPServers) != 5 || mcpCfg.Sources["notes"] != "notes.yaml" {
+		t.Errorf("expected 5 servers with notes from notes.yaml, got %v, %v", mcpCfg.MCPServers, mcpCfg.Sources)
+	}
+}
+
+func TestFragmentName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		wantErr  bool
+	}{
+		{"github", "github.json", false},
+		{"team.yaml", "team.yaml", false},
+		{"10-tools.yml", "10-tools.yml", false},
+		{"", "", true},
+		{"../mcp.json", "", true},
+		{"sub/x.json", "", true},
+		{".hidden.json", "", true},
+	}
+	for _, tt := range tests {
+		got, err := FragmentName(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.expected {
+			t.Errorf("FragmentName(%q) = %q, %v; expected %q, error %v", tt.name, got, err, tt.expected, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tuannvm/mcpenetes/internal/history"
+)
+
+// historyFile returns the history kept next to the config file at path and the file's name
+// in it. Fragments are recorded in the history of mcp.json as mcp.d/<name>.
+func historyFile(path string) (*history.Store, string) {
+	dir, name := filepath.Dir(path), filepath.Base(path)
+	if filepath.Base(dir) == FragmentDirName {
+		dir, name = filepath.Dir(dir), FragmentDirName+"/"+name
+	}
+	return history.NewStore(filepath.Join(dir, history.DirName), LockTimeout), name
+}
+
+// readForHistory returns the content of a file about to be overwritten, and whether it exists.
+func readForHistory(path string) ([]byte, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// recordHistory snapshots a write of path into the history. A failure to record is reported
+// but does not fail the save, which has already happened.
+func recordHistory(path string, before []byte, beforeExists bool, after []byte) {
+	store, name := historyFile(path)
+	if err := store.Record(name, before, beforeExists, after, true); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record %s in the history: %v\n", path, err)
+	}
+}
+
+// History returns the history of config.yaml, mcp.json and its fragments in the config directory.
+func History() (*history.Store, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return history.NewStore(filepath.Join(configDir, history.DirName), LockTimeout), nil
+}
+
+// Undo reverts the most recent change to config.yaml, mcp.json or a fragment recorded in the
+// history, including changes made outside mcpenetes, and returns the change it reverted.
+func Undo() (*history.Entry, error) {
+	configFilePath, mcpFilePath, err := getConfigPaths()
+	if err != nil {
+		return nil, err
+	}
+	store, err := History()
+	if err != nil {
+		return nil, err
+	}
+	filePaths := map[string]string{
+		filepath.Base(configFilePath): configFilePath,
+		filepath.Base(mcpFilePath):    mcpFilePath,
+	}
+	fragments, err := FragmentFiles(mcpFilePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range fragments {
+		_, name := historyFile(path)
+		filePaths[name] = path
+	}
+
+	// Hand edits since the last save are changes too, and the most recent ones
+	for name, path := range filePaths {
+		data, exists := readForHistory(path)
+		if err := store.Observe(name, data, exists); err != nil {
+			return nil, err
+		}
+	}
+
+	target, err := store.UndoTarget()
+	if err != nil {
+		return nil, err
+	}
+	path, ok := filePaths[target.File]
+	if !ok && strings.HasPrefix(target.File, FragmentDirName+"/") {
+		// A fragment that was removed since
+		path, ok = filepath.Join(filepath.Dir(mcpFilePath), filepath.FromSlash(target.File)), true
+	}
+	if !ok {
+		return nil, fmt.Errorf("cannot undo change %s of unknown file '%s'", target.ID, target.File)
+	}
+
+	// Fragments are saved together with mcp.json, under its lock
+	lockPath := path
+	if path != configFilePath {
+		lockPath = mcpFilePath
+	}
+	l, err := lockFile(lockPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = l.Release() }()
+
+	// Make sure nothing changed the file since it was observed
+	data, exists := readForHistory(path)
+	if err := store.Observe(target.File, data, exists); err != nil {
+		return nil, err
+	}
+	if latest, err := store.UndoTarget(); err != nil || latest.ID != target.ID {
+		return nil, errors.New("the configuration changed while undoing; run undo again")
+	}
+
+	if target.Previous == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove '%s': %w", path, err)
+		}
+	} else {
+		previous, err := store.Object(target.Previous)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			return nil, fmt.Errorf("failed to create directory '%s': %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, previous, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write '%s': %w", path, err)
+		}
+	}
+	if err := store.RecordUndo(target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tuannvm/mcpenetes/internal/history"
+	"github.com/tuannvm/mcpenetes/internal/paths"
+)
+
+func TestUndo(t *testing.T) {
+	dir := t.TempDir()
+	paths.SetConfigDir(dir)
+	defer paths.SetConfigDir("")
+	mcpPath := filepath.Join(dir, DefaultMCPFileName)
+
+	first := &MCPConfig{MCPServers: map[string]MCPServer{"fetch": {Command: "uvx"}}}
+	if err := SaveMCPConfig(first); err != nil {
+		t.Fatal(err)
+	}
+	second := &MCPConfig{MCPServers: map[string]MCPServer{"fetch": {Command: "npx"}}}
+	if err := SaveMCPConfig(second); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := Undo()
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if entry.File != DefaultMCPFileName || entry.Action != history.ActionSave {
+		t.Errorf("expected to undo the last save of mcp.json, got %+v", entry)
+	}
+	loaded, err := LoadMCPConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.MCPServers["fetch"].Command != "uvx" {
+		t.Errorf("expected the first version after undo, got %+v", loaded.MCPServers["fetch"])
+	}
+
+	// A hand edit is the most recent change and is undone first
+	if err := os.WriteFile(mcpPath, []byte(`{"mcpServers": {}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	entry, err = Undo()
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if entry.Action != history.ActionEdit {
+		t.Errorf("expected to undo the hand edit, got %+v", entry)
+	}
+	loaded, err = LoadMCPConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.MCPServers["fetch"].Command != "uvx" {
+		t.Errorf("expected the first version after undoing the edit, got %+v", loaded.MCPServers)
+	}
+
+	// Undoing the creation removes the file; then there is nothing left
+	if _, err := Undo(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if _, err := os.Stat(mcpPath); !os.IsNotExist(err) {
+		t.Errorf("expected mcp.json to be removed, got %v", err)
+	}
+	if _, err := Undo(); !errors.Is(err, history.ErrNothingToUndo) {
+		t.Errorf("expected ErrNothingToUndo, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+)
+
+// identifierPattern matches object keys that can be written as .key in a path.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// jsonPositions maps the path of every value in a JSON document (see joinPath) to the
+// byte offset where the value starts. The root value has the empty path.
+func jsonPositions(data []byte) (map[string]int64, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	positions := make(map[string]int64)
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		start := skipSeparators(data, dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		positions[path] = start
+
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				key, _ := keyTok.(string)
+				if err := walk(joinPath(path, key)); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token() // Closing brace
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if err := walk(fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token() // Closing bracket
+		}
+		return err
+	}
+
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// joinPath appends an object key to a path: .key, or ["key"] for keys that need quoting.
+func joinPath(path, key string) string {
+	if identifierPattern.MatchString(key) {
+		return path + "." + key
+	}
+	return path + "[" + strconv.Quote(key) + "]"
+}
+
+// skipSeparators returns the offset of the first byte at or after offset that is not
+// whitespace, a comma or a colon, i.e. the start of the next value or key.
+func skipSeparators(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// lineColumn converts a byte offset into a 1-based line and column (in characters).
+func lineColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return line, utf8.RuneCount(before[lineStart:]) + 1
+}
@@ -0,0 +1,492 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tuannvm/mcpenetes/internal/paths"
+	"gopkg.in/yaml.v3"
+)
+
+// Names of the configuration layers, from lowest to highest precedence.
+const (
+	LayerSystem   = "system"   // Machine-wide baseline, /etc/mcpenetes
+	LayerBaseline = "baseline" // Team baseline fetched by 'mcpenetes sync'
+	LayerUser     = "user"     // The user's config directory
+	LayerProject  = "project"  // .mcpenetes in the current directory or a parent
+)
+
+// Status of a layer's value for a key.
+const (
+	SettingEffective  = "effective"
+	SettingOverridden = "overridden"
+	SettingIgnored    = "ignored (locked)"
+	SettingUserOnly   = "ignored (user only)"
+)
+
+// userOnlyKeys are the keys only the user layer may set: they decide which files mcpenetes
+// writes and where it fetches configuration from.
+var userOnlyKeys = []string{"clients", "backups", "sync"}
+
+// Layer is a directory that may hold config.yaml and mcp.json.
+type Layer struct {
+	Name string
+	Dir  string
+}
+
+// Setting is the value one layer gives a key.
+type Setting struct {
+	Layer  string
+	File   string
+	Value  string
+	Status string
+}
+
+// Effective is the configuration merged from every layer. Sources records, for every key such
+// as registries.glama or mcpServers.github, the value each layer gave it in precedence order.
+// Excluded holds the servers left out of MCP because their when condition fails on this
+// machine, with the reason. Untrusted is the project layer left out because it is not trusted.
+type Effective struct {
+	Layers    []Layer
+	Config    *Config
+	MCP       *MCPConfig
+	Sources   map[string][]Setting
+	Excluded  map[string]string
+	Untrusted *UntrustedLayer
+}
+
+// BaselineDirName is the directory in the config directory that 'mcpenetes sync' installs
+// the team baseline into.
+const BaselineDirName = "baseline"
+
+// BaselineLockFileName records the revision of the installed baseline, next to BaselineDirName.
+const BaselineLockFileName = "baseline.lock.json"
+
+// Layers returns the configuration layers, lowest precedence first. The system, baseline and
+// project layers are only included when their directory exists, and the project layer only
+// when the user trusts it as it is (see TrustProject).
+func Layers() ([]Layer, error) {
+	layers, _, err := findLayers()
+	return layers, err
+}
+
+// findLayers returns the configuration layers like Layers, and the project layer left out
+// because it is not trusted, if any.
+func findLayers() ([]Layer, *UntrustedLayer, error) {
+	var layers []Layer
+	if dir := paths.SystemConfigDir(); isDir(dir) {
+		layers = append(layers, Layer{Name: LayerSystem, Dir: dir})
+	}
+	userDir, err := getConfigDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	if dir := filepath.Join(userDir, BaselineDirName); isDir(dir) {
+		layers = append(layers, Layer{Name: LayerBaseline, Dir: dir})
+	}
+	layers = append(layers, Layer{Name: LayerUser, Dir: userDir})
+	projectDir, err := paths.ProjectConfigDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	if projectDir == "" {
+		return layers, nil, nil
+	}
+	reason, err := projectTrust(projectDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if reason != "" {
+		return layers, &UntrustedLayer{Dir: projectDir, Reason: reason}, nil
+	}
+	return append(layers, Layer{Name: LayerProject, Dir: projectDir}), nil, nil
+}
+
+// LayerFiles returns the config.yaml and mcp.json paths of every layer, lowest precedence first,
+// each mcp.json followed by its mcp.d directory, the fragments in it and its overlays. The
+// trust file comes last, since trusting a project adds its layer.
+func LayerFiles() ([]string, error) {
+	layers, err := Layers()
+	if err != nil {
+		return nil, err
+	}
+	trustPath, err := trustFilePath()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, layer := range layers {
+		configFilePath := filepath.Join(layer.Dir, DefaultConfigFileName)
+		mcpFilePath := filepath.Join(layer.Dir, DefaultMCPFileName)
+		if layer.Name == LayerUser {
+			if configFilePath, mcpFilePath, err = getConfigPaths(); err != nil {
+				return nil, err
+			}
+		}
+		fragments, err := FragmentFiles(mcpFilePath)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, configFilePath, mcpFilePath, filepath.Join(filepath.Dir(mcpFilePath), FragmentDirName))
+		files = append(files, fragments...)
+		files = append(files, OverlayFiles(mcpFilePath)...)
+	}
+	return append(files, trustPath), nil
+}
+
+// isDir reports whether path is an existing directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// LoadEffective loads config.yaml and mcp.json from every layer and merges them. Later layers
+// override earlier ones entry by entry (a registry, client or server by name, a backup setting),
+// except for entries the system layer locks; mcps are combined. The user layer is loaded with
+// LoadConfig and LoadMCPConfig, so it is migrated and created like it is on its own.
+func LoadEffective() (*Effective, error) {
+	layers, untrusted, err := findLayers()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &merger{eff: &Effective{
+		Layers:    layers,
+		Config:    &Config{Clients: make(map[string]Client)},
+		MCP:       &MCPConfig{Version: CurrentMCPVersion, MCPServers: make(map[string]MCPServer)},
+		Sources:   make(map[string][]Setting),
+		Untrusted: untrusted,
+	}}
+	for _, layer := range layers {
+		configFile := filepath.Join(layer.Dir, DefaultConfigFileName)
+		mcpFile := filepath.Join(layer.Dir, DefaultMCPFileName)
+
+		var cfg *Config
+		var mcpCfg *MCPConfig
+		if layer.Name == LayerUser {
+			if configFile, err = getConfigPath(); err != nil {
+				return nil, fmt.Errorf("failed to determine config path: %w", err)
+			}
+			if cfg, err = LoadConfig(); err != nil {
+				return nil, err
+			}
+			if mcpCfg, err = LoadMCPConfig(); err != nil {
+				return nil, err
+			}
+
+			// State that only the user layer keeps
+			m.eff.Config.Version = cfg.Version
+			m.eff.Config.LastClients = cfg.LastClients
+			m.eff.Config.AllowCmdSecrets = cfg.AllowCmdSecrets
+		} else {
+			if cfg, err = readLayerConfig(configFile); err != nil {
+				return nil, err
+			}
+			if mcpCfg, err = readLayerMCPConfig(mcpFile); err != nil {
+				return nil, err
+			}
+		}
+
+		// Overlays for this machine apply on top of the layer's own servers
+		if mcpCfg == nil {
+			mcpCfg = &MCPConfig{MCPServers: make(map[string]MCPServer)}
+		}
+		overlaid, err := applyOverlays(mcpCfg, mcpFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if layer.Name == LayerSystem && cfg != nil {
+			m.eff.Config.Locked = cfg.Locked
+		}
+		m.mergeConfig(layer.Name, configFile, cfg)
+		if err := m.mergeMCP(layer.Name, mcpFile, mcpCfg, overlaid); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(m.eff.Config.Registries) == 0 {
+		m.eff.Config.Registries = GetDefaultConfig().Registries
+	}
+	m.eff.excludeServers(host)
+	return m.eff, nil
+}
+
+// excludeServers moves the servers whose when condition fails on h from MCP to Excluded.
+func (e *Effective) excludeServers(h HostInfo) {
+	e.Excluded = make(map[string]string)
+	for name, server := range e.MCP.MCPServers {
+		if err := server.When.Check(h); err != nil {
+			e.Excluded[name] = err.Error()
+			delete(e.MCP.MCPServers, name)
+		}
+	}
+}
+
+// LoadEffectiveConfig returns config.yaml merged from every layer.
+func LoadEffectiveConfig() (*Config, error) {
+	eff, err := LoadEffective()
+	if err != nil {
+		return nil, err
+	}
+	return eff.Config, nil
+}
+
+// LoadEffectiveMCPConfig returns mcp.json merged from every layer.
+func LoadEffectiveMCPConfig() (*MCPConfig, error) {
+	eff, err := LoadEffective()
+	if err != nil {
+		return nil, err
+	}
+	return eff.MCP, nil
+}
+
+// readLayerConfig reads a system or project config.yaml, migrating it in memory only.
+// It returns nil if the file does not exist.
+func readLayerConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read config file '%s': %w", path, err)
+	}
+	plan, err := planConfigMigration(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(plan.After, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%s': %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// readLayerMCPConfig reads a system or project mcp.json and its mcp.d fragments, migrating
+// them in memory only. It returns nil if there is neither.
+func readLayerMCPConfig(path string) (*MCPConfig, error) {
+	mcpCfg, err := readMCPFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if mcpCfg == nil {
+		mcpCfg = &MCPConfig{}
+	}
+	if mcpCfg.MCPServers == nil {
+		mcpCfg.MCPServers = make(map[string]MCPServer)
+	}
+	if err := mergeFragments(mcpCfg, path, nil); err != nil {
+		return nil, err
+	}
+	if len(mcpCfg.MCPServers) == 0 && len(mcpCfg.Fragments) == 0 {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+	return mcpCfg, nil
+}
+
+// merger folds layers into an Effective configuration.
+type merger struct {
+	eff *Effective
+}
+
+// set records a layer's value for key and reports whether it becomes the effective value.
+// A value the system layer set for a locked key is kept, and the new one is recorded as ignored.
+// So is a value for a user-only key from another layer.
+func (m *merger) set(key, layer, file, value string) bool {
+	settings := m.eff.Sources[key]
+	if layer != LayerUser && isUserOnly(key) {
+		m.eff.Sources[key] = append(settings, Setting{Layer: layer, File: file, Value: value, Status: SettingUserOnly})
+		return false
+	}
+	for i := range settings {
+		if settings[i].Status != SettingEffective {
+			continue
+		}
+		if settings[i].Layer == LayerSystem && layer != LayerSystem && m.eff.IsLocked(key) {
+			m.eff.Sources[key] = append(settings, Setting{Layer: layer, File: file, Value: value, Status: SettingIgnored})
+			return false
+		}
+		settings[i].Status = SettingOverridden
+	}
+	m.eff.Sources[key] = append(settings, Setting{Layer: layer, File: file, Value: value, Status: SettingEffective})
+	return true
+}
+
+// mergeConfig merges one layer's config.yaml; cfg may be nil.
+func (m *merger) mergeConfig(layer, file string, cfg *Config) {
+	if cfg == nil {
+		return
+	}
+	merged := m.eff.Config
+
+	for _, registry := range cfg.Registries {
+		if !m.set("registries."+registry.Name, layer, file, registry.URL) {
+			continue
+		}
+		replaced := false
+		for i := range merged.Registries {
+			if merged.Registries[i].Name == registry.Name {
+				merged.Registries[i] = registry
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged.Registries = append(merged.Registries, registry)
+		}
+	}
+
+	for _, serverID := range cfg.MCPs {
+		key := "mcps." + serverID
+		if _, seen := m.eff.Sources[key]; seen {
+			continue
+		}
+		m.set(key, layer, file, "selected")
+		merged.MCPs = append(merged.MCPs, serverID)
+	}
+
+	clientNames := make([]string, 0, len(cfg.Clients))
+	for name := range cfg.Clients {
+		clientNames = append(clientNames, name)
+	}
+	sort.Strings(clientNames)
+	for _, name := range clientNames {
+		if m.set("clients."+name, layer, file, cfg.Clients[name].ConfigPath) {
+			merged.Clients[name] = cfg.Clients[name]
+		}
+	}
+
+	if cfg.Backups.Path != "" && m.set("backups.path", layer, file, cfg.Backups.Path) {
+		merged.Backups.Path = cfg.Backups.Path
+	}
+	if cfg.Backups.Retention != 0 && m.set("backups.retention", layer, file, strconv.Itoa(cfg.Backups.Retention)) {
+		merged.Backups.Retention = cfg.Backups.Retention
+	}
+
+	if cfg.Sync != nil {
+		if merged.Sync == nil {
+			merged.Sync = &SyncConfig{}
+		}
+		if cfg.Sync.Source != "" && m.set("sync.source", layer, file, cfg.Sync.Source) {
+			merged.Sync.Source = cfg.Sync.Source
+		}
+		if cfg.Sync.Ref != "" && m.set("sync.ref", layer, file, cfg.Sync.Ref) {
+			merged.Sync.Ref = cfg.Sync.Ref
+		}
+		if cfg.Sync.Path != "" && m.set("sync.path", layer, file, cfg.Sync.Path) {
+			merged.Sync.Path = cfg.Sync.Path
+		}
+	}
+}
+
+// mergeMCP merges one layer's mcp.json, fragments and overlays; mcpCfg may be nil. Servers are
+// replaced as a whole. overlaid names the overlay file that last changed a server.
+func (m *merger) mergeMCP(layer, file string, mcpCfg *MCPConfig, overlaid map[string]string) error {
+	if mcpCfg == nil {
+		return nil
+	}
+	serverNames := make([]string, 0, len(mcpCfg.MCPServers))
+	for name := range mcpCfg.MCPServers {
+		serverNames = append(serverNames, name)
+	}
+	sort.Strings(serverNames)
+
+	for _, name := range serverNames {
+		server := mcpCfg.MCPServers[name]
+		source := serverFile(mcpCfg, file, name)
+		if overlay, ok := overlaid[name]; ok {
+			source = overlay
+		}
+		value, err := json.Marshal(server)
+		if err != nil {
+			return fmt.Errorf("failed to marshal server '%s' from '%s': %w", name, source, err)
+		}
+		if m.set("mcpServers."+name, layer, source, string(value)) {
+			m.eff.MCP.MCPServers[name] = server
+		}
+	}
+	return nil
+}
+
+// isUserOnly reports whether only the user layer may set key.
+func isUserOnly(key string) bool {
+	for _, userOnly := range userOnlyKeys {
+		if key == userOnly || strings.HasPrefix(key, userOnly+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLocked reports whether the system layer locks key, directly or through a parent key.
+func (e *Effective) IsLocked(key string) bool {
+	for _, locked := range e.Config.Locked {
+		if key == locked || strings.HasPrefix(key, locked+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys returns the keys that are key itself or below it (e.g. registries for every
+// registry), sorted. An empty key returns every key.
+func (e *Effective) Keys(key string) []string {
+	var keys []string
+	for k := range e.Sources {
+		if key == "" || k == key || strings.HasPrefix(k, key+".") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Ignored returns the keys for which a user or project value was ignored because the
+// system layer locks them, sorted.
+func (e *Effective) Ignored() []string {
+	var keys []string
+	for key, settings := range e.Sources {
+		for _, setting := range settings {
+			if setting.Status == SettingIgnored {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// EffectiveLayer returns the layer that sets the effective value of key, or "" if no layer sets it.
+func (e *Effective) EffectiveLayer(key string) string {
+	for _, setting := range e.Sources[key] {
+		if setting.Status == SettingEffective {
+			return setting.Layer
+		}
+	}
+	return ""
+}
+
+// UserOnly returns the keys for which a system, baseline or project value was ignored because
+// only the user layer may set them, sorted.
+func (e *Effective) UserOnly() []string {
+	var keys []string
+	for key, settings := range e.Sources {
+		for _, setting := range settings {
+			if setting.Status == SettingUserOnly {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
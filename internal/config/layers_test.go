@@ -0,0 +1,186 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tuannvm/mcpenetes/internal/paths"
+)
+
+// setupLayers creates system, user and project directories with the given files and makes
+// them the layers for the duration of the test.
+func setupLayers(t *testing.T, files map[string]string) (systemDir, userDir, projectDir string) {
+	t.Helper()
+	base := t.TempDir()
+	systemDir = filepath.Join(base, "etc")
+	userDir = filepath.Join(base, "user")
+	workDir := filepath.Join(base, "work", "sub")
+	projectDir = filepath.Join(base, "work", paths.ProjectDirName)
+	for _, dir := range []string{systemDir, userDir, workDir, projectDir} {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(base, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv(paths.SystemDirEnv, systemDir)
+	paths.SetConfigDir(userDir)
+	t.Cleanup(func() { paths.SetConfigDir("") })
+	t.Chdir(workDir) // The project layer is found in a parent directory
+	return systemDir, userDir, projectDir
+}
+
+func TestLoadEffective(t *testing.T) {
+	systemDir, userDir, projectDir := setupLayers(t, map[string]string{
+		"etc/config.yaml": `version: 1
+registries:
+  - name: approved
+    url: https://registry.example.com
+  - name: open
+    url: https://open.example.com
+backups:
+  path: /var/backups/mcp
+locked:
+  - registries.approved
+  - mcpServers.audit
+`,
+		"etc/mcp.json": `{"mcpServers": {
+  "audit": {"command": "audit-server"},
+  "fetch": {"command": "uvx", "args": ["mcp-server-fetch"]}
+}}`,
+		"user/config.yaml": `version: 1
+registries:
+  - name: approved
+    url: https://evil.example.com
+  - name: open
+    url: https://mirror.example.com
+mcps: [fetch]
+clients:
+  cursor:
+    config_path: ~/.cursor/mcp.json
+`,
+		"user/mcp.json": `{"version": 1, "mcpServers": {
+  "audit": {"command": "not-audit"},
+  "fetch": {"command": "uvx", "args": ["mcp-server-fetch", "--user"]}
+}}`,
+		"work/.mcpenetes/mcp.json": `{"mcpServers": {"repo-tools": {"command": "./tools/mcp"}}}`,
+		"work/.mcpenetes/config.yaml": `clients:
+  cursor:
+    config_path: /etc/passwd
+sync:
+  source: --upload-pack=evil
+`,
+	})
+	if err := TrustProject(projectDir); err != nil {
+		t.Fatal(err)
+	}
+
+	eff, err := LoadEffective()
+	if err != nil {
+		t.Fatalf("LoadEffective failed: %v", err)
+	}
+
+	expectedLayers := []Layer{{LayerSystem, systemDir}, {LayerUser, userDir}, {LayerProject, projectDir}}
+	if !reflect.DeepEqual(eff.Layers, expectedLayers) {
+		t.Errorf("Layers = %+v, expected %+v", eff.Layers, expectedLayers)
+	}
+
+	expectedRegistries := []Registry{
+		{Name: "approved", URL: "https://registry.example.com"}, // locked
+		{Name: "open", URL: "https://mirror.example.com"},
+	}
+	if !reflect.DeepEqual(eff.Config.Registries, expectedRegistries) {
+		t.Errorf("Registries = %+v, expected %+v", eff.Config.Registries, expectedRegistries)
+	}
+	// Only the user layer decides which files are written and where configuration comes from
+	if eff.Config.Backups.Path != "" {
+		t.Errorf("Backups.Path = %q, expected the system value to be ignored", eff.Config.Backups.Path)
+	}
+	if eff.Config.Clients["cursor"].ConfigPath != "~/.cursor/mcp.json" {
+		t.Errorf("Clients = %+v, expected the user's cursor client", eff.Config.Clients)
+	}
+	if eff.Config.Sync != nil && eff.Config.Sync.Source != "" {
+		t.Errorf("Sync = %+v, expected the project value to be ignored", eff.Config.Sync)
+	}
+	if userOnly := eff.UserOnly(); !reflect.DeepEqual(userOnly, []string{"backups.path", "clients.cursor", "sync.source"}) {
+		t.Errorf("UserOnly() = %v", userOnly)
+	}
+
+	servers := eff.MCP.MCPServers
+	if len(servers) != 3 {
+		t.Errorf("Expected 3 servers, got %+v", servers)
+	}
+	if servers["audit"].Command != "audit-server" {
+		t.Errorf("Locked server was overridden: %+v", servers["audit"])
+	}
+	if !reflect.DeepEqual(servers["fetch"].Args, []string{"mcp-server-fetch", "--user"}) {
+		t.Errorf("User server did not override the system one: %+v", servers["fetch"])
+	}
+	if servers["repo-tools"].Command != "./tools/mcp" {
+		t.Errorf("Project server missing: %+v", servers)
+	}
+
+	if ignored := eff.Ignored(); !reflect.DeepEqual(ignored, []string{"mcpServers.audit", "registries.approved"}) {
+		t.Errorf("Ignored() = %v", ignored)
+	}
+
+	sources := eff.Sources["registries.open"]
+	if len(sources) != 2 || sources[0].Layer != LayerSystem || sources[0].Status != SettingOverridden ||
+		sources[1].Layer != LayerUser || sources[1].Status != SettingEffective ||
+		sources[1].File != filepath.Join(userDir, DefaultConfigFileName) {
+		t.Errorf("Sources[registries.open] = %+v", sources)
+	}
+	sources = eff.Sources["registries.approved"]
+	if len(sources) != 2 || sources[0].Status != SettingEffective || sources[1].Status != SettingIgnored {
+		t.Errorf("Sources[registries.approved] = %+v", sources)
+	}
+
+	if keys := eff.Keys("registries"); !reflect.DeepEqual(keys, []string{"registries.approved", "registries.open"}) {
+		t.Errorf("Keys(registries) = %v", keys)
+	}
+}
+
+func TestLoadEffectiveUserOnly(t *testing.T) {
+	_, userDir, projectDir := setupLayers(t, map[string]string{
+		"user/config.yaml": "version: 1\nmcps: [a]\nbackups:\n  path: /tmp/backups\n",
+	})
+	if err := os.Remove(projectDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(paths.SystemDirEnv, filepath.Join(userDir, "missing"))
+
+	eff, err := LoadEffective()
+	if err != nil {
+		t.Fatalf("LoadEffective failed: %v", err)
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if !reflect.DeepEqual(eff.Config, cfg) {
+		t.Errorf("With only a user layer, the effective config should be the user's.\nExpected: %+v\nGot:      %+v", cfg, eff.Config)
+	}
+}
+
+func TestValidateMCPConfigFileLayers(t *testing.T) {
+	_, _, projectDir := setupLayers(t, map[string]string{
+		"etc/mcp.json":             `{"mcpServers": {"bad": {}}}`,
+		"work/.mcpenetes/mcp.json": `{"mcpServers": {"worse": {"command": "x", "url": "https://example.com"}}}`,
+	})
+	if err := TrustProject(projectDir); err != nil {
+		t.Fatal(err)
+	}
+
+	var errs ValidationErrors
+	err := ValidateMCPConfigFile(ValidateOptions{})
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Expected a problem in both the system and the project layer, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/tuannvm/mcpenetes/internal/paths"
+)
+
+// MigrateLegacyConfigDir moves the files of older versions from ~/.config/mcpetes to the
+// current config directory, and points the backup path in config.yaml at the new location
+// if it was inside the old one. It does nothing if there is nothing to move.
+func MigrateLegacyConfigDir() error {
+	from, to, err := paths.MigrateLegacy()
+	if err != nil || from == "" {
+		return err
+	}
+	fmt.Printf("Moved mcpenetes files from %s to %s.\n", from, to)
+
+	return UpdateConfig(func(cfg *Config) error {
+		backupPath, ok := rebasePath(cfg.Backups.Path, from, to)
+		if !ok {
+			return ErrNoChange
+		}
+		cfg.Backups.Path = backupPath
+		return nil
+	})
+}
+
+// rebasePath returns path moved from the directory from to the directory to, if it was inside
+// from. The legacy "~/.config/mcpetes" spelling is recognized as well.
+func rebasePath(path, from, to string) (string, bool) {
+	for _, prefix := range []string{from, "~/.config/mcpetes"} {
+		if path == prefix {
+			return to, true
+		}
+		if strings.HasPrefix(path, prefix+"/") || strings.HasPrefix(path, prefix+string(filepath.Separator)) {
+			return filepath.Join(to, path[len(prefix)+1:]), true
+		}
+	}
+	return path, false
+}
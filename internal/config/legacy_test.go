@@ -0,0 +1,23 @@
+package config
+
+import "testing"
+
+func TestRebasePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+		ok       bool
+	}{
+		{"/home/u/.config/mcpetes/backups", "/home/u/.config/mcpenetes/backups", true},
+		{"/home/u/.config/mcpetes", "/home/u/.config/mcpenetes", true},
+		{"~/.config/mcpetes/backups", "/home/u/.config/mcpenetes/backups", true},
+		{"/home/u/.config/mcpetes-other/backups", "/home/u/.config/mcpetes-other/backups", false},
+		{"/srv/backups", "/srv/backups", false},
+	}
+	for _, tt := range tests {
+		got, ok := rebasePath(tt.path, "/home/u/.config/mcpetes", "/home/u/.config/mcpenetes")
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("rebasePath(%q) = %q, %v; expected %q, %v", tt.path, got, ok, tt.expected, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/tuannvm/mcpenetes/internal/lock"
+)
+
+// ErrNoChange can be returned by an update function to skip saving.
+var ErrNoChange = errors.New("no change")
+
+// LockTimeout is how long to wait for another mcpenetes process to release a lock.
+var LockTimeout = lock.DefaultTimeout
+
+// lockFile locks one of mcpenetes' own files through a sidecar "<file>.lock".
+func lockFile(path string) (*lock.Lock, error) {
+	return lock.Acquire(path+".lock", LockTimeout)
+}
+
+// LockClientConfig takes the lock guarding a client's config file during read-modify-write.
+// Lock files for clients live under the mcpenetes config directory so client directories stay clean.
+func LockClientConfig(clientConfigPath string) (*lock.Lock, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	absPath, err := filepath.Abs(clientConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve client config path '%s': %w", clientConfigPath, err)
+	}
+	sum := sha256.Sum256([]byte(absPath))
+	return lock.Acquire(filepath.Join(configDir, "locks", hex.EncodeToString(sum[:])+".lock"), LockTimeout)
+}
+
+// UpdateConfig loads config.yaml, applies update and saves the result, holding the
+// config.yaml lock throughout so concurrent invocations don't lose each other's changes.
+// Nothing is saved if update returns an error; ErrNoChange is not reported as one.
+func UpdateConfig(update func(cfg *Config) error) error {
+	configFilePath, err := getConfigPath()
+	if err != nil {
+		return fmt.Errorf("failed to determine config path: %w", err)
+	}
+
+	l, err := lockFile(configFilePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Release() }()
+
+	cfg, err := loadConfig(true)
+	if err != nil {
+		return err
+	}
+	if err := update(cfg); err != nil {
+		if errors.Is(err, ErrNoChange) {
+			return nil
+		}
+		return err
+	}
+	return SaveConfig(cfg)
+}
+
+// UpdateMCPConfig loads mcp.json, applies update and saves the result while holding the mcp.json lock.
+// Nothing is saved if update returns an error; ErrNoChange is not reported as one.
+func UpdateMCPConfig(update func(mcpCfg *MCPConfig) error) error {
+	_, mcpFilePath, err := getConfigPaths()
+	if err != nil {
+		return fmt.Errorf("failed to determine mcp config path: %w", err)
+	}
+
+	l, err := lockFile(mcpFilePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Release() }()
+
+	mcpCfg, err := loadMCPConfig(true)
+	if err != nil {
+		return err
+	}
+	if err := update(mcpCfg); err != nil {
+		if errors.Is(err, ErrNoChange) {
+			return nil
+		}
+		return err
+	}
+	return SaveMCPConfig(mcpCfg)
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestUpdateConfigConcurrent(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, DefaultConfigFileName)
+
+	originalGetConfigPath := getConfigPath
+	getConfigPath = func() (string, error) {
+		return configPath, nil
+	}
+	defer func() { getConfigPath = originalGetConfigPath }()
+
+	if err := SaveConfig(GetDefaultConfig()); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	// Every update must survive; without the lock, concurrent read-modify-write cycles drop some
+	const updates = 20
+	var wg sync.WaitGroup
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := UpdateConfig(func(cfg *Config) error {
+				cfg.MCPs = append(cfg.MCPs, fmt.Sprintf("server-%d", i))
+				return nil
+			})
+			if err != nil {
+				t.Errorf("UpdateConfig failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.MCPs) != updates {
+		t.Errorf("expected %d MCPs after concurrent updates, got %d: %v", updates, len(cfg.MCPs), cfg.MCPs)
+	}
+}
+
+func TestUpdateConfigNoChange(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, DefaultConfigFileName)
+
+	originalGetConfigPath := getConfigPath
+	getConfigPath = func() (string, error) {
+		return configPath, nil
+	}
+	defer func() { getConfigPath = originalGetConfigPath }()
+
+	if err := SaveConfig(GetDefaultConfig()); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	err := UpdateConfig(func(cfg *Config) error {
+		cfg.MCPs = append(cfg.MCPs, "discarded")
+		return ErrNoChange
+	})
+	if err != nil {
+		t.Fatalf("UpdateConfig returned %v for ErrNoChange", err)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.MCPs) != 0 {
+		t.Errorf("expected nothing saved, got MCPs %v", cfg.MCPs)
+	}
+}
@@ -1,12 +1,15 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json" // Added json import
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
+	"github.com/tuannvm/mcpenetes/internal/paths"
 	"gopkg.in/yaml.v3"
 )
 
@@ -15,11 +18,7 @@ const DefaultMCPFileName = "mcp.json"
 
 // getConfigDir returns the application's configuration directory path.
 func getConfigDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get user home directory: %w", err)
-	}
-	return filepath.Join(homeDir, ".config", "mcpetes"), nil
+	return paths.ConfigDir()
 }
 
 // Variable to allow mocking in tests
@@ -42,17 +41,28 @@ func getConfigPaths() (configFilePath, mcpFilePath string, err error) {
 	return configFilePath, mcpFilePath, nil
 }
 
+// ConfigDir returns the directory holding config.yaml, mcp.json and the other mcpenetes files.
+func ConfigDir() (string, error) {
+	return getConfigDir()
+}
+
+// ConfigPaths returns the full paths of config.yaml and mcp.json.
+func ConfigPaths() (configFilePath, mcpFilePath string, err error) {
+	return getConfigPaths()
+}
+
 // GetDefaultConfig returns the default configuration structure.
 func GetDefaultConfig() *Config {
 	// Define default values here
-	configDir, _ := getConfigDir()            // Ignore error for default path generation
-	backupPath := "~/.config/mcpetes/backups" // Default string
+	configDir, _ := getConfigDir()              // Ignore error for default path generation
+	backupPath := "~/.config/mcpenetes/backups" // Default string
 	if configDir != "" {
 		backupPath = filepath.Join(configDir, "backups")
 	}
 
 	return &Config{
-		MCPs: []string{}, // No default selections initially
+		Version: CurrentConfigVersion,
+		MCPs:    []string{}, // No default selections initially
 		Registries: []Registry{
 			{
 				Name: "glama",
@@ -67,8 +77,27 @@ func GetDefaultConfig() *Config {
 }
 
 // LoadConfig loads the application configuration from the default path.
-// If the file doesn't exist, it creates a default one.
+// If the file doesn't exist, it creates a default one. A file of an older version is
+// migrated holding its lock.
 func LoadConfig() (*Config, error) {
+	cfg, err := loadConfig(false)
+	if !errors.Is(err, errMigrationUnlocked) {
+		return cfg, err
+	}
+	configFilePath, err := getConfigPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine config path: %w", err)
+	}
+	l, err := lockFile(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = l.Release() }()
+	return loadConfig(true)
+}
+
+// loadConfig loads config.yaml; it is migrated on disk only if the caller holds its lock (locked).
+func loadConfig(locked bool) (*Config, error) {
 	configFilePath, err := getConfigPath()
 	if err != nil {
 		return nil, fmt.Errorf("failed to determine config path: %w", err)
@@ -88,6 +117,15 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file '%s': %w", configFilePath, err)
 	}
 
+	// Upgrade files written by older versions before parsing them
+	plan, err := planConfigMigration(configFilePath, data)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = migrateOnLoad(plan, locked); err != nil {
+		return nil, err
+	}
+
 	var cfg Config
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config file '%s': %w", configFilePath, err)
@@ -121,37 +159,70 @@ func SaveConfig(cfg *Config) error {
 		return fmt.Errorf("failed to create config directory '%s': %w", configDir, err)
 	}
 
-	data, err := yaml.Marshal(cfg)
+	// Only the changed values are rewritten, keeping the user's comments and layout
+	before, beforeExists := readForHistory(configFilePath)
+	data, err := marshalConfig(before, cfg)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config to YAML: %w", err)
+		return err
+	}
+	if beforeExists && bytes.Equal(data, before) {
+		return nil
 	}
 
 	if err := os.WriteFile(configFilePath, data, 0600); err != nil { // Use 0600 for config files
 		return fmt.Errorf("failed to write config file '%s': %w", configFilePath, err)
 	}
+	recordHistory(configFilePath, before, beforeExists, data)
 
 	return nil
 }
 
-// LoadMCPConfig loads the local MCP configuration file.
+// LoadMCPConfig loads the local MCP configuration file, merged with the fragments in the
+// mcp.d directory next to it in lexical order. Files of an older version are migrated
+// holding the mcp.json lock.
 func LoadMCPConfig() (*MCPConfig, error) {
+	mcpCfg, err := loadMCPConfig(false)
+	if !errors.Is(err, errMigrationUnlocked) {
+		return mcpCfg, err
+	}
+	_, mcpFilePath, err := getConfigPaths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine mcp config path: %w", err)
+	}
+	l, err := lockFile(mcpFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = l.Release() }()
+	return loadMCPConfig(true)
+}
+
+// loadMCPConfig loads mcp.json and its fragments; they are migrated on disk only if the
+// caller holds the mcp.json lock (locked).
+func loadMCPConfig(locked bool) (*MCPConfig, error) {
 	_, mcpFilePath, err := getConfigPaths() // Use the helper
 	if err != nil {
 		return nil, fmt.Errorf("failed to determine mcp config path: %w", err)
 	}
+	migrate := func(plan *MigrationPlan) ([]byte, error) {
+		return migrateOnLoad(plan, locked)
+	}
 
-	data, err := os.ReadFile(mcpFilePath)
+	mcpCfg := MCPConfig{Version: CurrentMCPVersion}
+	plan, err := planMCPFile(mcpFilePath)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			// Return an empty config struct if the file doesn't exist
-			return &MCPConfig{MCPServers: make(map[string]MCPServer)}, nil
-		}
-		return nil, fmt.Errorf("failed to read mcp config file '%s': %w", mcpFilePath, err)
+		return nil, err
 	}
+	// A missing file is an empty config
+	if plan != nil {
+		data, err := migrate(plan)
+		if err != nil {
+			return nil, err
+		}
 
-	var mcpCfg MCPConfig
-	if err := json.Unmarshal(data, &mcpCfg); err != nil { // Use json import
-		return nil, fmt.Errorf("failed to parse mcp config file '%s': %w", mcpFilePath, err)
+		if err := json.Unmarshal(data, &mcpCfg); err != nil { // Use json import
+			return nil, fmt.Errorf("failed to parse mcp config file '%s': %w", mcpFilePath, err)
+		}
 	}
 
 	// Ensure the map is initialized if the file exists but is empty or has null
@@ -159,11 +230,15 @@ func LoadMCPConfig() (*MCPConfig, error) {
 		mcpCfg.MCPServers = make(map[string]MCPServer)
 	}
 
+	if err := mergeFragments(&mcpCfg, mcpFilePath, migrate); err != nil {
+		return nil, err
+	}
+
 	return &mcpCfg, nil
 }
 
-// SaveMCPConfig saves the local MCP configuration file.
-// Assumes MCPConfig is defined as `type MCPConfig map[string]MCPServer` in types.go
+// SaveMCPConfig saves the local MCP configuration file. Servers that came from, or were
+// routed to, an mcp.d fragment are saved to that fragment instead of mcp.json.
 func SaveMCPConfig(mcpCfg *MCPConfig) error {
 	if mcpCfg == nil {
 		// Or perhaps save an empty map? For now, error out.
@@ -180,14 +255,32 @@ func SaveMCPConfig(mcpCfg *MCPConfig) error {
 		return fmt.Errorf("failed to create config directory '%s': %w", configDir, err)
 	}
 
-	data, err := json.MarshalIndent(mcpCfg, "", "  ") // Use json import and MarshalIndent
+	if mcpCfg.Version == 0 {
+		mcpCfg.Version = CurrentMCPVersion
+	}
+
+	mainServers, fragments := splitFragments(mcpCfg)
+	fragmentNames := make([]string, 0, len(fragments))
+	for name := range fragments {
+		fragmentNames = append(fragmentNames, name)
+	}
+	sort.Strings(fragmentNames)
+	for _, name := range fragmentNames {
+		if err := saveFragment(filepath.Join(configDir, FragmentDirName, name), fragments[name]); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(&MCPConfig{Version: mcpCfg.Version, MCPServers: mainServers}, "", "  ") // Use json import and MarshalIndent
 	if err != nil {
 		return fmt.Errorf("failed to marshal mcp config to JSON: %w", err)
 	}
 
+	before, beforeExists := readForHistory(mcpFilePath)
 	if err := os.WriteFile(mcpFilePath, data, 0600); err != nil { // Use 0600 for config files
 		return fmt.Errorf("failed to write mcp config file '%s': %w", mcpFilePath, err)
 	}
+	recordHistory(mcpFilePath, before, beforeExists, data)
 
 	return nil
 }
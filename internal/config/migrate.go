@@ -0,0 +1,346 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Current format versions of config.yaml and mcp.json. Files with an older version are
+// migrated step by step when they are loaded.
+const (
+	CurrentConfigVersion = 1
+	CurrentMCPVersion    = 1
+)
+
+// Migration upgrades a decoded document from version From to From+1.
+type Migration struct {
+	From        int
+	Description string
+	Apply       func(doc map[string]interface{}) error
+}
+
+// configMigrations upgrade config.yaml, in order.
+var configMigrations = []Migration{
+	{
+		From:        0,
+		Description: "expand client shorthand (name: path) to name: {config_path: path}",
+		Apply: func(doc map[string]interface{}) error {
+			clients, ok := doc["clients"].(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			for name, client := range clients {
+				if path, ok := client.(string); ok {
+					clients[name] = map[string]interface{}{"config_path": path}
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// mcpMigrations upgrade mcp.json, in order.
+var mcpMigrations = []Migration{
+	{
+		From:        0,
+		Description: "move servers from a top-level map or a \"servers\" key under \"mcpServers\"",
+		Apply: func(doc map[string]interface{}) error {
+			if _, ok := doc["mcpServers"]; ok {
+				return nil
+			}
+			if servers, ok := doc["servers"].(map[string]interface{}); ok {
+				doc["mcpServers"] = servers
+				delete(doc, "servers")
+				return nil
+			}
+
+			// A bare map of servers: every top-level value is a server definition
+			servers := make(map[string]interface{})
+			for name, value := range doc {
+				server, ok := value.(map[string]interface{})
+				if !ok {
+					return nil
+				}
+				if _, hasCommand := server["command"]; !hasCommand {
+					if _, hasURL := server["url"]; !hasURL {
+						return nil
+					}
+				}
+				servers[name] = server
+			}
+			if len(servers) == 0 {
+				return nil
+			}
+			for name := range servers {
+				delete(doc, name)
+			}
+			doc["mcpServers"] = servers
+			return nil
+		},
+	},
+}
+
+// MigrationPlan describes the upgrade of one file to the current version.
+type MigrationPlan struct {
+	File        string
+	FromVersion int
+	ToVersion   int
+	Steps       []string // Descriptions of the migrations to apply
+	Before      []byte   // Current content
+	After       []byte   // Content once migrated
+
+	lockPath string // File whose lock guards File, when it is not File itself
+}
+
+// Needed reports whether the file has to be migrated.
+func (p *MigrationPlan) Needed() bool {
+	return p != nil && p.FromVersion != p.ToVersion
+}
+
+// PlanConfigMigration computes the migration of config.yaml, without writing anything.
+// It returns nil if the file does not exist.
+func PlanConfigMigration() (*MigrationPlan, error) {
+	configFilePath, err := getConfigPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine config path: %w", err)
+	}
+	data, err := os.ReadFile(configFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read config file '%s': %w", configFilePath, err)
+	}
+	return planConfigMigration(configFilePath, data)
+}
+
+// PlanMCPMigration computes the migration of mcp.json, without writing anything.
+// It returns nil if the file does not exist.
+func PlanMCPMigration() (*MigrationPlan, error) {
+	_, mcpFilePath, err := getConfigPaths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine mcp config path: %w", err)
+	}
+	return planMCPFile(mcpFilePath)
+}
+
+// PlanFragmentMigrations computes the migrations of the mcp.d fragments that need one,
+// without writing anything. Fragments that would only gain a version stamp are left out.
+func PlanFragmentMigrations() ([]*MigrationPlan, error) {
+	_, mcpFilePath, err := getConfigPaths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine mcp config path: %w", err)
+	}
+	files, err := FragmentFiles(mcpFilePath)
+	if err != nil {
+		return nil, err
+	}
+	var plans []*MigrationPlan
+	for _, file := range files {
+		plan, err := planMCPFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if plan.Needed() && !stampOnly(plan) {
+			plan.lockPath = mcpFilePath // Fragments are saved under the mcp.json lock
+			plans = append(plans, plan)
+		}
+	}
+	return plans, nil
+}
+
+// planMCPFile computes the migration of an mcp.json or a fragment; After is in the file's own
+// format, so YAML fragments stay YAML. It returns nil if the file does not exist.
+func planMCPFile(path string) (*MigrationPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read mcp config file '%s': %w", path, err)
+	}
+	jsonData, err := fragmentJSON(path, data)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := planMCPMigration(path, jsonData)
+	if err != nil {
+		return nil, err
+	}
+	plan.Before = data
+	if !plan.Needed() {
+		plan.After = data
+		return plan, nil
+	}
+	if isYAMLFile(path) {
+		if plan.After, err = yamlFromJSON(path, plan.After); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// planConfigMigration migrates config.yaml content in memory.
+func planConfigMigration(file string, data []byte) (*MigrationPlan, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%s': %w", file, err)
+	}
+	if doc == nil {
+		doc = make(map[string]interface{})
+	}
+
+	plan, err := runMigrations(file, data, doc, configMigrations, CurrentConfigVersion)
+	if err != nil || !plan.Needed() {
+		return plan, err
+	}
+
+	// Round-trip through the typed config so the result is exactly what SaveConfig writes,
+	// patched into the original so its comments survive
+	migrated, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal migrated config: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(migrated, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse migrated config: %w", err)
+	}
+	if plan.After, err = marshalConfig(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to marshal migrated config: %w", err)
+	}
+	return plan, nil
+}
+
+// planMCPMigration migrates mcp.json content in memory.
+func planMCPMigration(file string, data []byte) (*MigrationPlan, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse mcp config file '%s': %w", file, err)
+	}
+	if doc == nil {
+		doc = make(map[string]interface{})
+	}
+
+	plan, err := runMigrations(file, data, doc, mcpMigrations, CurrentMCPVersion)
+	if err != nil || !plan.Needed() {
+		return plan, err
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal migrated mcp config: %w", err)
+	}
+	var mcpCfg MCPConfig
+	if err := json.Unmarshal(migrated, &mcpCfg); err != nil {
+		return nil, fmt.Errorf("failed to parse migrated mcp config: %w", err)
+	}
+	if plan.After, err = json.MarshalIndent(&mcpCfg, "", "  "); err != nil {
+		return nil, fmt.Errorf("failed to marshal migrated mcp config: %w", err)
+	}
+	return plan, nil
+}
+
+// runMigrations applies the migrations from the document's version up to current.
+func runMigrations(file string, data []byte, doc map[string]interface{}, migrations []Migration, current int) (*MigrationPlan, error) {
+	version, err := documentVersion(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version in '%s': %w", file, err)
+	}
+	if version > current {
+		return nil, fmt.Errorf("'%s' has version %d, but this mcpenetes only supports up to version %d; please upgrade mcpenetes", file, version, current)
+	}
+
+	plan := &MigrationPlan{File: file, FromVersion: version, ToVersion: current, Before: data, After: data}
+	for _, migration := range migrations {
+		if migration.From < version {
+			continue
+		}
+		if err := migration.Apply(doc); err != nil {
+			return nil, fmt.Errorf("failed to migrate '%s' from version %d: %w", file, migration.From, err)
+		}
+		plan.Steps = append(plan.Steps, fmt.Sprintf("v%d -> v%d: %s", migration.From, migration.From+1, migration.Description))
+	}
+	doc["version"] = current
+	return plan, nil
+}
+
+// documentVersion returns the version stamp of a decoded document, 0 when it has none.
+func documentVersion(doc map[string]interface{}) (int, error) {
+	switch v := doc["version"].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case float64:
+		if v != float64(int(v)) {
+			return 0, fmt.Errorf("%v is not a whole number", v)
+		}
+		return int(v), nil
+	}
+	return 0, fmt.Errorf("%v is not a number", doc["version"])
+}
+
+// ApplyMigration backs up the file as <file>.v<version>-<timestamp>.bak and writes the
+// migrated content, holding the file's lock. It fails if the file changed since it was planned.
+// It returns the path of the backup.
+func ApplyMigration(plan *MigrationPlan) (string, error) {
+	lockPath := plan.File
+	if plan.lockPath != "" {
+		lockPath = plan.lockPath
+	}
+	l, err := lockFile(lockPath)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = l.Release() }()
+
+	current, err := os.ReadFile(plan.File)
+	if err != nil {
+		return "", fmt.Errorf("failed to read '%s': %w", plan.File, err)
+	}
+	if !bytes.Equal(current, plan.Before) {
+		return "", fmt.Errorf("'%s' changed since the migration was planned; run the migration again", plan.File)
+	}
+	return applyMigration(plan)
+}
+
+// applyMigration backs up and rewrites the file without taking its lock.
+func applyMigration(plan *MigrationPlan) (string, error) {
+	backupPath := fmt.Sprintf("%s.v%d-%s.bak", plan.File, plan.FromVersion, time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(backupPath, plan.Before, 0600); err != nil {
+		return "", fmt.Errorf("failed to back up '%s' before migrating: %w", plan.File, err)
+	}
+	if err := os.WriteFile(plan.File, plan.After, 0600); err != nil {
+		return "", fmt.Errorf("failed to write migrated '%s': %w", plan.File, err)
+	}
+	recordHistory(plan.File, plan.Before, true, plan.After)
+	return backupPath, nil
+}
+
+// errMigrationUnlocked is returned by migrateOnLoad when a file has to be rewritten but
+// its lock is not held; the caller takes the lock and loads again.
+var errMigrationUnlocked = errors.New("migrating the file requires its lock")
+
+// migrateOnLoad migrates a file that is being loaded, returning the content to use. The
+// file is only rewritten while the caller holds its lock (locked); otherwise it returns
+// errMigrationUnlocked.
+func migrateOnLoad(plan *MigrationPlan, locked bool) ([]byte, error) {
+	if !plan.Needed() {
+		return plan.Before, nil
+	}
+	if !locked {
+		return nil, errMigrationUnlocked
+	}
+	backupPath, err := applyMigration(plan)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Migrated %s from version %d to %d (backup: %s).\n", plan.File, plan.FromVersion, plan.ToVersion, backupPath)
+	return plan.After, nil
+}
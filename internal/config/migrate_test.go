@@ -0,0 +1,246 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tuannvm/mcpenetes/internal/lock"
+	"gopkg.in/yaml.v3"
+)
+
+func TestPlanConfigMigration(t *testing.T) {
+	data := []byte(`registries:
+  - name: glama
+    url: https://glama.ai/api/mcp/v1/servers
+mcps: [server-a]
+clients:
+  cursor: ~/.cursor/mcp.json
+  vscode:
+    config_path: ~/.config/Code/User/settings.json
+`)
+	plan, err := planConfigMigration("config.yaml", data)
+	if err != nil {
+		t.Fatalf("planConfigMigration failed: %v", err)
+	}
+	if !plan.Needed() || plan.FromVersion != 0 || plan.ToVersion != CurrentConfigVersion {
+		t.Fatalf("Expected a migration from 0 to %d, got %+v", CurrentConfigVersion, plan)
+	}
+	if len(plan.Steps) != 1 {
+		t.Errorf("Expected 1 step, got %v", plan.Steps)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(plan.After, &cfg); err != nil {
+		t.Fatalf("Migrated config does not parse: %v", err)
+	}
+	expectedClients := map[string]Client{
+		"cursor": {ConfigPath: "~/.cursor/mcp.json"},
+		"vscode": {ConfigPath: "~/.config/Code/User/settings.json"},
+	}
+	if cfg.Version != CurrentConfigVersion {
+		t.Errorf("Expected version %d, got %d", CurrentConfigVersion, cfg.Version)
+	}
+	if !reflect.DeepEqual(cfg.Clients, expectedClients) {
+		t.Errorf("Clients not migrated.\nExpected: %+v\nGot:      %+v", expectedClients, cfg.Clients)
+	}
+	if !reflect.DeepEqual(cfg.MCPs, []string{"server-a"}) {
+		t.Errorf("MCPs not preserved: %v", cfg.MCPs)
+	}
+
+	// Already current: nothing to do
+	plan, err = planConfigMigration("config.yaml", plan.After)
+	if err != nil {
+		t.Fatalf("planConfigMigration failed on migrated config: %v", err)
+	}
+	if plan.Needed() || len(plan.Steps) != 0 {
+		t.Errorf("Expected no migration for a current config, got %+v", plan)
+	}
+
+	// Newer than supported
+	if _, err := planConfigMigration("config.yaml", []byte("version: 99\n")); err == nil || !strings.Contains(err.Error(), "upgrade mcpenetes") {
+		t.Errorf("Expected an error for a newer version, got %v", err)
+	}
+}
+
+func TestPlanMCPMigration(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"servers key", `{"servers": {"fetch": {"command": "uvx", "args": ["mcp-server-fetch"]}}}`},
+		{"bare map", `{"fetch": {"command": "uvx", "args": ["mcp-server-fetch"]}}`},
+		{"mcpServers without version", `{"mcpServers": {"fetch": {"command": "uvx", "args": ["mcp-server-fetch"]}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planMCPMigration("mcp.json", []byte(tt.data))
+			if err != nil {
+				t.Fatalf("planMCPMigration failed: %v", err)
+			}
+			if !plan.Needed() {
+				t.Fatalf("Expected a migration, got none")
+			}
+
+			var mcpCfg MCPConfig
+			if err := json.Unmarshal(plan.After, &mcpCfg); err != nil {
+				t.Fatalf("Migrated mcp.json does not parse: %v", err)
+			}
+			if mcpCfg.Version != CurrentMCPVersion {
+				t.Errorf("Expected version %d, got %d", CurrentMCPVersion, mcpCfg.Version)
+			}
+			server, ok := mcpCfg.MCPServers["fetch"]
+			if !ok || server.Command != "uvx" || !reflect.DeepEqual(server.Args, []string{"mcp-server-fetch"}) {
+				t.Errorf("Server not migrated: %+v", mcpCfg.MCPServers)
+			}
+		})
+	}
+}
+
+func TestLoadConfigMigrates(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTempConfigFile(t, tempDir, DefaultConfigFileName, "clients:\n  cursor: ~/.cursor/mcp.json\n")
+
+	originalGetConfigPath := getConfigPath
+	getConfigPath = func() (string, error) {
+		return configPath, nil
+	}
+	defer func() { getConfigPath = originalGetConfigPath }()
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Version != CurrentConfigVersion || cfg.Clients["cursor"].ConfigPath != "~/.cursor/mcp.json" {
+		t.Errorf("Config not migrated: %+v", cfg)
+	}
+
+	// The file is rewritten with the new version and the original is backed up
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read migrated config: %v", err)
+	}
+	if !strings.Contains(string(data), "version: 1") {
+		t.Errorf("Migrated file has no version stamp:\n%s", data)
+	}
+	backups, _ := filepath.Glob(configPath + ".v0-*.bak")
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 backup, got %v", backups)
+	}
+	backup, _ := os.ReadFile(backups[0])
+	if string(backup) != "clients:\n  cursor: ~/.cursor/mcp.json\n" {
+		t.Errorf("Backup does not hold the original content:\n%s", backup)
+	}
+}
+
+func TestApplyMigrationDetectsChanges(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTempConfigFile(t, tempDir, DefaultConfigFileName, "mcps: []\n")
+
+	plan, err := planConfigMigration(configPath, []byte("mcps: []\n"))
+	if err != nil {
+		t.Fatalf("planConfigMigration failed: %v", err)
+	}
+	createTempConfigFile(t, tempDir, DefaultConfigFileName, "mcps: [server-a]\n")
+
+	if _, err := ApplyMigration(plan); err == nil {
+		t.Errorf("Expected ApplyMigration to fail when the file changed since planning")
+	}
+}
+
+func TestLoadConfigMigratesUnderLock(t *testing.T) {
+	tempDir := t.TempDir()
+	legacy := "clients:\n  cursor: ~/.cursor/mcp.json\n"
+	configPath := createTempConfigFile(t, tempDir, DefaultConfigFileName, legacy)
+
+	originalGetConfigPath := getConfigPath
+	getConfigPath = func() (string, error) {
+		return configPath, nil
+	}
+	defer func() { getConfigPath = originalGetConfigPath }()
+	originalTimeout := LockTimeout
+	LockTimeout = 50 * time.Millisecond
+	defer func() { LockTimeout = originalTimeout }()
+
+	// While another process holds the lock, the file is not rewritten
+	l, err := lockFile(configPath)
+	if err != nil {
+		t.Fatalf("lockFile failed: %v", err)
+	}
+	if _, err := LoadConfig(); !errors.Is(err, lock.ErrTimeout) {
+		t.Errorf("Expected LoadConfig to wait for the lock, got %v", err)
+	}
+	if data, _ := os.ReadFile(configPath); string(data) != legacy {
+		t.Errorf("Config was rewritten without its lock:\n%s", data)
+	}
+	_ = l.Release()
+
+	// UpdateConfig already holds the lock and migrates without waiting for itself
+	err = UpdateConfig(func(cfg *Config) error {
+		cfg.MCPs = []string{"fetch"}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+	data, _ := os.ReadFile(configPath)
+	if !strings.Contains(string(data), "version: 1") || !strings.Contains(string(data), "config_path: ~/.cursor/mcp.json") {
+		t.Errorf("Config not migrated:\n%s", data)
+	}
+	if backups, _ := filepath.Glob(configPath + ".v0-*.bak"); len(backups) != 1 {
+		t.Errorf("Expected 1 backup, got %v", backups)
+	}
+}
+
+func TestLoadMCPConfigMigratesFragments(t *testing.T) {
+	dir := setupFragments(t, map[string]string{
+		"mcp.json":           `{"version": 1, "mcpServers": {}}`,
+		"mcp.d/10-old.json":  `{"servers": {"github": {"command": "github-mcp"}}}`,
+		"mcp.d/20-old.yaml":  "servers:\n  search:\n    url: https://search.example.com/mcp\n",
+		"mcp.d/30-hand.yaml": "# Written by hand\nmcpServers:\n  fetch:\n    command: uvx\n",
+	})
+
+	plans, err := PlanFragmentMigrations()
+	if err != nil {
+		t.Fatalf("PlanFragmentMigrations failed: %v", err)
+	}
+	var planned []string
+	for _, plan := range plans {
+		planned = append(planned, filepath.Base(plan.File))
+	}
+	if !reflect.DeepEqual(planned, []string{"10-old.json", "20-old.yaml"}) {
+		t.Errorf("Expected the legacy fragments to be planned, got %v", planned)
+	}
+
+	mcpCfg, err := LoadMCPConfig()
+	if err != nil {
+		t.Fatalf("LoadMCPConfig failed: %v", err)
+	}
+	if len(mcpCfg.MCPServers) != 3 {
+		t.Fatalf("Expected 3 servers, got %v", mcpCfg.MCPServers)
+	}
+
+	fragmentDir := filepath.Join(dir, FragmentDirName)
+	data, _ := os.ReadFile(filepath.Join(fragmentDir, "10-old.json"))
+	if !strings.Contains(string(data), `"mcpServers"`) || !strings.Contains(string(data), `"version": 1`) {
+		t.Errorf("JSON fragment not migrated:\n%s", data)
+	}
+	data, _ = os.ReadFile(filepath.Join(fragmentDir, "20-old.yaml"))
+	if !strings.Contains(string(data), "mcpServers:") || strings.Contains(string(data), "{") {
+		t.Errorf("YAML fragment not migrated to YAML:\n%s", data)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(fragmentDir, "*.bak")); len(backups) != 2 {
+		t.Errorf("Expected 2 backups, got %v", backups)
+	}
+
+	// A fragment that only lacks the version stamp keeps its formatting
+	data, _ = os.ReadFile(filepath.Join(fragmentDir, "30-hand.yaml"))
+	if !strings.HasPrefix(string(data), "# Written by hand") {
+		t.Errorf("Fragment without legacy layout was rewritten:\n%s", data)
+	}
+}
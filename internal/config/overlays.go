@@ -0,0 +1,114 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// unsafeFileChars matches the characters of a hostname that can't be part of an overlay file name.
+var unsafeFileChars = regexp.MustCompile(`[^a-z0-9._-]`)
+
+// OverlayFiles returns the overlay files of mcpFile for this machine, in the order they are
+// applied: mcp.<os>.json, then mcp.<hostname>.json with the hostname's first label, lowercased.
+// The files need not exist.
+func OverlayFiles(mcpFile string) []string {
+	base := strings.TrimSuffix(mcpFile, filepath.Ext(mcpFile))
+	ext := filepath.Ext(mcpFile)
+	files := []string{base + "." + host.OS + ext}
+	if names, err := host.hostnames(); err == nil {
+		name := unsafeFileChars.ReplaceAllString(names[len(names)-1], "_")
+		if overlay := base + "." + name + ext; name != "" && overlay != files[0] {
+			files = append(files, overlay)
+		}
+	}
+	return files
+}
+
+// applyOverlays merges the overlay files of mcpFile into mcpCfg. A server in an overlay
+// replaces the fields it sets of the server with the same name, or is added; a server set to
+// null is removed. It returns the overlay file that last changed each server.
+func applyOverlays(mcpCfg *MCPConfig, mcpFile string) (map[string]string, error) {
+	overlaid := make(map[string]string)
+	for _, file := range OverlayFiles(mcpFile) {
+		servers, err := readOverlay(file)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(servers))
+		for name := range servers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			overlaid[name] = file
+			if bytes.Equal(bytes.TrimSpace(servers[name]), []byte("null")) {
+				delete(mcpCfg.MCPServers, name)
+				continue
+			}
+			server, err := overlayServer(mcpCfg.MCPServers[name], servers[name])
+			if err != nil {
+				return nil, fmt.Errorf("failed to apply server '%s' from overlay '%s': %w", name, file, err)
+			}
+			if mcpCfg.MCPServers == nil {
+				mcpCfg.MCPServers = make(map[string]MCPServer)
+			}
+			mcpCfg.MCPServers[name] = server
+		}
+	}
+	return overlaid, nil
+}
+
+// readOverlay returns the servers of an overlay file, undecoded. A missing file has none.
+func readOverlay(path string) (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read overlay '%s': %w", path, err)
+	}
+	var overlay struct {
+		MCPServers map[string]json.RawMessage `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &overlay); err != nil {
+		return nil, fmt.Errorf("failed to parse overlay '%s': %w", path, err)
+	}
+	return overlay.MCPServers, nil
+}
+
+// overlayServer returns base with the fields set in overlay replaced.
+func overlayServer(base MCPServer, overlay json.RawMessage) (MCPServer, error) {
+	baseData, err := json.Marshal(base)
+	if err != nil {
+		return MCPServer{}, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(baseData, &fields); err != nil {
+		return MCPServer{}, err
+	}
+	var overlayFields map[string]json.RawMessage
+	if err := json.Unmarshal(overlay, &overlayFields); err != nil {
+		return MCPServer{}, fmt.Errorf("server definition must be an object or null: %w", err)
+	}
+	for key, value := range overlayFields {
+		fields[key] = value
+	}
+
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return MCPServer{}, err
+	}
+	var server MCPServer
+	if err := json.Unmarshal(merged, &server); err != nil {
+		return MCPServer{}, err
+	}
+	return server, nil
+}
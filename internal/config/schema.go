@@ -0,0 +1,10 @@
+package config
+
+import (
+	_ "embed"
+)
+
+// MCPSchema is the JSON Schema of mcp.json, for editor integration.
+//
+//go:embed mcp.schema.json
+var MCPSchema []byte
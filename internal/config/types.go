@@ -1,5 +1,10 @@
 package config
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 // Config represents the structure of config.yaml
 type Config struct {
 	Version    int               `yaml:"version"`
@@ -7,6 +12,16 @@ type Config struct {
 	MCPs       []string          `yaml:"mcps"`
 	Clients    map[string]Client `yaml:"clients"`
 	Backups    BackupConfig      `yaml:"backups"`
+	// LastClients remembers the clients selected in the last interactive apply
+	LastClients []string `yaml:"last_clients,omitempty"`
+	// AllowCmdSecrets lets ${cmd:...} references run when client configs are written.
+	// Only honoured in the user layer.
+	AllowCmdSecrets bool `yaml:"allow_cmd_secrets,omitempty"`
+	// Sync is where 'mcpenetes sync' fetches the team baseline from
+	Sync *SyncConfig `yaml:"sync,omitempty"`
+	// Locked lists keys (e.g. registries.glama, mcpServers) that the user and project layers
+	// may not override. Only honoured in the system layer.
+	Locked []string `yaml:"locked,omitempty"`
 }
 
 // Registry defines a registry endpoint
@@ -20,6 +35,13 @@ type Client struct {
 	ConfigPath string `yaml:"config_path"`
 }
 
+// SyncConfig defines the source of the team baseline
+type SyncConfig struct {
+	Source string `yaml:"source"`         // Git repository path or URL, or http(s) URL of an mcp.json
+	Ref    string `yaml:"ref,omitempty"`  // Git commit-ish, default HEAD
+	Path   string `yaml:"path,omitempty"` // Directory of the bundle inside the repository
+}
+
 // BackupConfig defines backup settings
 type BackupConfig struct {
 	Path      string `yaml:"path"`
@@ -28,7 +50,13 @@ type BackupConfig struct {
 
 // MCPConfig represents the structure of mcp.json
 type MCPConfig struct {
+	Version    int                  `json:"version,omitempty"`
 	MCPServers map[string]MCPServer `json:"mcpServers"`
+
+	// Sources maps the servers defined in an mcp.d fragment to the fragment's file name;
+	// servers defined in mcp.json itself are not listed. Fragments lists the fragments loaded.
+	Sources   map[string]string `json:"-"`
+	Fragments []string          `json:"-"`
 }
 
 // MCPServer defines the configuration for a single MCP server
@@ -41,4 +69,86 @@ type MCPServer struct {
 	Env         map[string]string `json:"env,omitempty"`
 	Disabled    bool              `json:"disabled,omitempty"`
 	AutoApprove []string          `json:"autoApprove,omitempty"`
+	// When limits the server to some machines; it is never written to clients
+	When *Condition `json:"when,omitempty" yaml:"-" toml:"-"`
+
+	// Extras holds any keys not modelled above (e.g. type, headers, timeout, trust)
+	// so that they survive a load/save round trip and can be passed on to clients.
+	Extras map[string]interface{} `json:"-" yaml:",inline" toml:"-"`
+}
+
+// knownServerFields lists the JSON keys decoded into the typed MCPServer fields.
+var knownServerFields = map[string]bool{
+	"command":     true,
+	"args":        true,
+	"url":         true,
+	"env":         true,
+	"disabled":    true,
+	"autoApprove": true,
+	"when":        true,
+}
+
+// mcpServerFields is an alias without the custom (un)marshalers, used to avoid recursion.
+type mcpServerFields MCPServer
+
+// UnmarshalJSON decodes the known fields and keeps every other key in Extras.
+func (s *MCPServer) UnmarshalJSON(data []byte) error {
+	var fields mcpServerFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	fields.Extras = nil
+	for key, value := range raw {
+		if knownServerFields[key] {
+			continue
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(value, &decoded); err != nil {
+			return fmt.Errorf("failed to decode server field '%s': %w", key, err)
+		}
+		if fields.Extras == nil {
+			fields.Extras = make(map[string]interface{})
+		}
+		fields.Extras[key] = decoded
+	}
+
+	*s = MCPServer(fields)
+	return nil
+}
+
+// MarshalJSON encodes the known fields followed by any Extras.
+// Extras never override a typed field of the same name.
+func (s MCPServer) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(mcpServerFields(s))
+	if err != nil || len(s.Extras) == 0 {
+		return data, err
+	}
+
+	merged := make(map[string]interface{})
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	for key, value := range s.Extras {
+		if _, exists := merged[key]; exists || knownServerFields[key] {
+			continue
+		}
+		merged[key] = value
+	}
+	return json.Marshal(merged)
+}
+
+// Equal reports whether two server definitions serialize to the same mcp.json entry.
+func (s MCPServer) Equal(other MCPServer) bool {
+	a, errA := json.Marshal(s)
+	b, errB := json.Marshal(other)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return string(a) == string(b)
 }
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMCPServerExtrasRoundTrip(t *testing.T) {
+	input := `{
+		"command": "npx",
+		"args": ["-y", "server"],
+		"env": {"TOKEN": "abc"},
+		"type": "stdio",
+		"timeout": 30,
+		"trust": true,
+		"headers": {"Authorization": "Bearer x"}
+	}`
+
+	var server MCPServer
+	if err := json.Unmarshal([]byte(input), &server); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if server.Command != "npx" || len(server.Args) != 2 || server.Env["TOKEN"] != "abc" {
+		t.Errorf("Known fields not decoded correctly: %+v", server)
+	}
+
+	expectedExtras := map[string]interface{}{
+		"type":    "stdio",
+		"timeout": float64(30),
+		"trust":   true,
+		"headers": map[string]interface{}{"Authorization": "Bearer x"},
+	}
+	if !reflect.DeepEqual(server.Extras, expectedExtras) {
+		t.Errorf("Extras mismatch.\nExpected: %+v\nGot:      %+v", expectedExtras, server.Extras)
+	}
+
+	data, err := json.Marshal(server)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var original, roundTripped map[string]interface{}
+	_ = json.Unmarshal([]byte(input), &original)
+	if err := json.Unmarshal(data, &roundTripped); err != nil {
+		t.Fatalf("Failed to decode marshaled server: %v", err)
+	}
+	if !reflect.DeepEqual(original, roundTripped) {
+		t.Errorf("Round trip lost data.\nExpected: %+v\nGot:      %+v", original, roundTripped)
+	}
+}
+
+func TestMCPServerWithoutExtras(t *testing.T) {
+	var server MCPServer
+	if err := json.Unmarshal([]byte(`{"url": "https://example.com/mcp"}`), &server); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if server.Extras != nil {
+		t.Errorf("Expected nil Extras, got %+v", server.Extras)
+	}
+
+	data, err := json.Marshal(server)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(data) != `{"url":"https://example.com/mcp"}` {
+		t.Errorf("Unexpected JSON: %s", data)
+	}
+}
+
+func TestMCPServerExtrasDoNotOverrideKnownFields(t *testing.T) {
+	server := MCPServer{
+		Command: "node",
+		Extras:  map[string]interface{}{"command": "evil", "type": "stdio"},
+	}
+
+	data, err := json.Marshal(server)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	_ = json.Unmarshal(data, &decoded)
+	if decoded["command"] != "node" || decoded["type"] != "stdio" {
+		t.Errorf("Unexpected JSON: %s", data)
+	}
+}
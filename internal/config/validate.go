@@ -0,0 +1,489 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tuannvm/mcpenetes/internal/secrets"
+	"github.com/tuannvm/mcpenetes/internal/vars"
+)
+
+// envKeyPattern matches valid environment variable names.
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validURLSchemes are the URL schemes accepted for remote servers.
+var validURLSchemes = map[string]bool{"http": true, "https": true, "ws": true, "wss": true}
+
+// ValidationError is a problem found in mcp.json, located by JSON path and line/column.
+type ValidationError struct {
+	File    string
+	Path    string // e.g. .mcpServers.github.env.TOKEN; empty for the whole document
+	Line    int
+	Column  int
+	Message string
+}
+
+// Error formats the error as file:line:column: path: message.
+func (e ValidationError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File + ":")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d:", e.Line, e.Column)
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path + ": ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+// ValidationErrors is the list of problems found in mcp.json.
+type ValidationErrors []ValidationError
+
+// Error lists every problem on its own line.
+func (errs ValidationErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValidateOptions controls the optional checks of ValidateMCPConfigData.
+type ValidateOptions struct {
+	// SecretExists reports whether a ${secret:NAME} reference can be resolved.
+	// When nil, secret references are only checked for syntax.
+	SecretExists func(name string) (bool, error)
+	// Overlay relaxes the checks for an overlay such as mcp.linux.json: a server may be null,
+	// and need not have a command or url since it only changes some fields.
+	Overlay bool
+}
+
+// validator collects the errors found in one document.
+type validator struct {
+	file      string
+	data      []byte
+	positions map[string]int64
+	opts      ValidateOptions
+	errs      ValidationErrors
+}
+
+// ValidateMCPConfigFile validates the mcp.json, mcp.d fragments and overlays for this machine
+// of every configuration layer, and checks that no server is defined twice within a layer. A missing file is valid:
+// it is treated as an empty configuration.
+func ValidateMCPConfigFile(opts ValidateOptions) error {
+	layers, err := Layers()
+	if err != nil {
+		return err
+	}
+
+	var all ValidationErrors
+	for _, layer := range layers {
+		mcpFilePath := filepath.Join(layer.Dir, DefaultMCPFileName)
+		if layer.Name == LayerUser {
+			if _, mcpFilePath, err = getConfigPaths(); err != nil {
+				return fmt.Errorf("failed to determine mcp config path: %w", err)
+			}
+		}
+		fragments, err := FragmentFiles(mcpFilePath)
+		if err != nil {
+			return err
+		}
+
+		layerValid := true
+		for _, path := range append([]string{mcpFilePath}, fragments...) {
+			var errs ValidationErrors
+			if err := ValidateMCPFile(path, opts); errors.As(err, &errs) {
+				all = append(all, errs...)
+				layerValid = false
+			} else if err != nil {
+				return err
+			}
+		}
+		overlayOpts := opts
+		overlayOpts.Overlay = true
+		for _, path := range OverlayFiles(mcpFilePath) {
+			var errs ValidationErrors
+			if err := ValidateMCPFile(path, overlayOpts); errors.As(err, &errs) {
+				all = append(all, errs...)
+			} else if err != nil {
+				return err
+			}
+		}
+		if !layerValid {
+			continue
+		}
+
+		var duplicates *DuplicateServerError
+		if _, err := readLayerMCPConfig(mcpFilePath); errors.As(err, &duplicates) {
+			all = append(all, duplicates.validationErrors(filepath.Dir(mcpFilePath))...)
+		} else if err != nil {
+			return err
+		}
+	}
+	if len(all) > 0 {
+		return all
+	}
+	return nil
+}
+
+// ValidateMCPFile validates one mcp.json or fragment like ValidateMCPConfigData. YAML files
+// are checked after conversion to JSON, so their problems are reported by path only.
+// A missing file is valid.
+func ValidateMCPFile(path string, opts ValidateOptions) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read mcp config file '%s': %w", path, err)
+	}
+	if !isYAMLFile(path) {
+		return ValidateMCPConfigData(path, data, opts)
+	}
+
+	if data, err = fragmentJSON(path, data); err != nil {
+		return ValidationErrors{{File: path, Message: err.Error()}}
+	}
+	var errs ValidationErrors
+	if err := ValidateMCPConfigData(path, data, opts); errors.As(err, &errs) {
+		for i := range errs {
+			errs[i].Line, errs[i].Column = 0, 0
+		}
+		return errs
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// validationErrors reports every duplicated server at its later definitions, relative to dir.
+func (e *DuplicateServerError) validationErrors(dir string) ValidationErrors {
+	names := make([]string, 0, len(e.Servers))
+	for name := range e.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs ValidationErrors
+	for _, name := range names {
+		files := e.Servers[name]
+		for _, file := range files[1:] {
+			errs = append(errs, ValidationError{
+				File:    filepath.Join(dir, file),
+				Path:    joinPath(".mcpServers", name),
+				Message: fmt.Sprintf("server is already defined in %s; server names must be unique across mcp.json and %s", filepath.Join(dir, files[0]), FragmentDirName),
+			})
+		}
+	}
+	return errs
+}
+
+// ValidateMCPConfigData checks an mcp.json document: every server must have either a
+// command or a url, URLs and env keys must be valid, fields must have the right types, and
+// template variables and secret references must be well formed (and, with
+// opts.SecretExists, resolvable). It returns ValidationErrors, or nil if the document is valid.
+func ValidateMCPConfigData(file string, data []byte, opts ValidateOptions) error {
+	v := &validator{file: file, data: data, opts: opts}
+
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			v.addAt(syntaxErr.Offset, "", "invalid JSON: %v", err)
+		} else {
+			v.addAt(0, "", "invalid JSON: %v", err)
+		}
+		return v.errs
+	}
+	if dec.More() {
+		v.addAt(dec.InputOffset(), "", "unexpected data after the JSON document")
+		return v.errs
+	}
+	positions, err := jsonPositions(data)
+	if err != nil {
+		v.addAt(0, "", "invalid JSON: %v", err)
+		return v.errs
+	}
+	v.positions = positions
+
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		v.add("", "mcp.json must be a JSON object")
+		return v.errs
+	}
+	if version, ok := root["version"]; ok {
+		// Numbers are decoded as json.Number
+		number, isNumber := version.(json.Number)
+		n, err := number.Int64()
+		if !isNumber || err != nil || n < 0 {
+			v.add(".version", "must be a non-negative whole number")
+		} else if n > CurrentMCPVersion {
+			v.add(".version", "version %d is newer than this mcpenetes supports (%d); please upgrade mcpenetes", n, CurrentMCPVersion)
+		}
+	}
+	serversValue, ok := root["mcpServers"]
+	if !ok || serversValue == nil {
+		return nil
+	}
+	servers, ok := serversValue.(map[string]interface{})
+	if !ok {
+		v.add(".mcpServers", "must be an object mapping server names to server definitions")
+		return v.errs
+	}
+
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v.validateServer(joinPath(".mcpServers", name), name, servers[name])
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		if v.errs[i].Line != v.errs[j].Line {
+			return v.errs[i].Line < v.errs[j].Line
+		}
+		return v.errs[i].Column < v.errs[j].Column
+	})
+	return v.errs
+}
+
+// validateServer checks a single server definition.
+func (v *validator) validateServer(path, name string, value interface{}) {
+	if strings.TrimSpace(name) == "" {
+		v.add(path, "server name must not be empty")
+	}
+	if value == nil && v.opts.Overlay {
+		return // Removes the server
+	}
+	server, ok := value.(map[string]interface{})
+	if !ok {
+		v.add(path, "server definition must be an object")
+		return
+	}
+
+	command, hasCommand := v.stringField(server, path, "command")
+	serverURL, hasURL := v.stringField(server, path, "url")
+	switch {
+	case hasCommand && hasURL:
+		v.add(path, "command and url are mutually exclusive; use command for local servers or url for remote ones")
+	case !hasCommand && !hasURL && !v.opts.Overlay:
+		v.add(path, "must have either command or url")
+	}
+	if hasCommand && strings.TrimSpace(command) == "" {
+		v.add(joinPath(path, "command"), "must not be empty")
+	}
+	if hasURL {
+		v.validateURL(joinPath(path, "url"), serverURL)
+	}
+
+	if args, ok := server["args"]; ok {
+		v.stringList(joinPath(path, "args"), args)
+	}
+	if autoApprove, ok := server["autoApprove"]; ok {
+		v.stringList(joinPath(path, "autoApprove"), autoApprove)
+	}
+	if disabled, ok := server["disabled"]; ok {
+		if _, ok := disabled.(bool); !ok {
+			v.add(joinPath(path, "disabled"), "must be true or false")
+		}
+	}
+	if envValue, ok := server["env"]; ok {
+		envPath := joinPath(path, "env")
+		env, ok := envValue.(map[string]interface{})
+		if !ok {
+			v.add(envPath, "must be an object mapping variable names to strings")
+		} else {
+			for key, value := range env {
+				keyPath := joinPath(envPath, key)
+				if !envKeyPattern.MatchString(key) {
+					v.add(keyPath, "invalid environment variable name %q (letters, digits and underscores, not starting with a digit)", key)
+				}
+				if _, ok := value.(string); !ok {
+					v.add(keyPath, "must be a string")
+				}
+			}
+		}
+	}
+
+	if when, ok := server["when"]; ok {
+		v.validateWhen(joinPath(path, "when"), when)
+	}
+
+	v.validateStrings(path, server)
+}
+
+// validateWhen checks a server's conditions.
+func (v *validator) validateWhen(path string, value interface{}) {
+	when, ok := value.(map[string]interface{})
+	if !ok {
+		v.add(path, "must be an object with os, hostname or command_exists")
+		return
+	}
+	keys := make([]string, 0, len(when))
+	for key := range when {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		keyPath := joinPath(path, key)
+		if !knownConditionFields[key] {
+			v.add(keyPath, "unknown condition %q (expected os, hostname or command_exists)", key)
+			continue
+		}
+		for _, item := range v.stringOrList(keyPath, when[key]) {
+			switch key {
+			case "os":
+				if !KnownOSes[strings.ToLower(item)] {
+					v.add(keyPath, "unknown os %q (expected a Go OS name such as linux, darwin or windows)", item)
+				}
+			case "hostname":
+				if _, err := pathpkg.Match(item, ""); err != nil {
+					v.add(keyPath, "invalid hostname glob %q: %v", item, err)
+				}
+			}
+		}
+	}
+}
+
+// stringOrList returns a value that must be a non-empty string or list of them, reporting it otherwise.
+func (v *validator) stringOrList(path string, value interface{}) []string {
+	var items []interface{}
+	switch val := value.(type) {
+	case string:
+		items = []interface{}{val}
+	case []interface{}:
+		items = val
+	}
+	if len(items) == 0 {
+		v.add(path, "must be a string or a non-empty list of strings")
+		return nil
+	}
+	var list []string
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok || s == "" {
+			v.add(path, "must be a string or a non-empty list of strings")
+			return nil
+		}
+		list = append(list, s)
+	}
+	return list
+}
+
+// stringField returns a string field of a server, reporting it if it has another type.
+func (v *validator) stringField(server map[string]interface{}, path, key string) (string, bool) {
+	value, ok := server[key]
+	if !ok || value == nil {
+		return "", false
+	}
+	s, ok := value.(string)
+	if !ok {
+		v.add(joinPath(path, key), "must be a string")
+	}
+	return s, true
+}
+
+// stringList reports a value that is not a list of strings.
+func (v *validator) stringList(path string, value interface{}) {
+	list, ok := value.([]interface{})
+	if !ok {
+		v.add(path, "must be a list of strings")
+		return
+	}
+	for i, item := range list {
+		if _, ok := item.(string); !ok {
+			v.add(fmt.Sprintf("%s[%d]", path, i), "must be a string")
+		}
+	}
+}
+
+// validateURL checks a server URL. URLs built from templates or secret references are
+// only checked once they are rendered.
+func (v *validator) validateURL(path, rawURL string) {
+	if vars.HasTemplate(rawURL) || secrets.HasReference(rawURL) {
+		return
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		v.add(path, "invalid URL: %v", err)
+		return
+	}
+	if !validURLSchemes[parsed.Scheme] {
+		v.add(path, "invalid URL %q: the scheme must be http, https, ws or wss", rawURL)
+		return
+	}
+	if parsed.Host == "" {
+		v.add(path, "invalid URL %q: missing host", rawURL)
+	}
+}
+
+// validateStrings checks the template variables and secret references in every string of a value.
+func (v *validator) validateStrings(path string, value interface{}) {
+	switch val := value.(type) {
+	case string:
+		if err := vars.Validate(val); err != nil {
+			v.add(path, "%v", err)
+		}
+		for _, ref := range secrets.References(val) {
+			if err := ref.Validate(); err != nil {
+				v.add(path, "%v", err)
+				continue
+			}
+			if ref.Kind == "secret" && v.opts.SecretExists != nil {
+				exists, err := v.opts.SecretExists(ref.Arg)
+				if err != nil {
+					v.add(path, "cannot check ${secret:%s}: %v", ref.Arg, err)
+				} else if !exists {
+					v.add(path, "${secret:%s} is not in the secret store (add it with 'mcpenetes secret set %s')", ref.Arg, ref.Arg)
+				}
+			}
+		}
+	case map[string]interface{}:
+		for key, child := range val {
+			v.validateStrings(joinPath(path, key), child)
+		}
+	case []interface{}:
+		for i, child := range val {
+			v.validateStrings(fmt.Sprintf("%s[%d]", path, i), child)
+		}
+	}
+}
+
+// add records an error at the position of the value at path.
+func (v *validator) add(path, format string, a ...interface{}) {
+	v.addAt(v.positions[path], path, format, a...)
+}
+
+// addAt records an error at a byte offset.
+func (v *validator) addAt(offset int64, path, format string, a ...interface{}) {
+	line, column := lineColumn(v.data, offset)
+	v.errs = append(v.errs, ValidationError{
+		File:    v.file,
+		Path:    path,
+		Line:    line,
+		Column:  column,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
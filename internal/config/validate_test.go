@@ -0,0 +1,100 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateMCPConfigData(t *testing.T) {
+	data := `{
+  "mcpServers": {
+    "both": {"command": "npx", "url": "https://example.com/mcp"},
+    "neither": {"args": ["x"]},
+    "bad-url": {"url": "example.com/mcp"},
+    "bad-env": {
+      "command": "npx",
+      "env": {"1BAD": "x", "GOOD": 2}
+    },
+    "bad-args": {"command": "npx", "args": "--flag"},
+    "bad-template": {"command": "npx", "args": ["{{homedir}}"]},
+    "bad-secret": {"command": "npx", "env": {"TOKEN": "${secret:MISSING}"}},
+    "templated-url": {"url": "{{env.MCP_URL}}"},
+    "ok": {"command": "uvx", "args": ["mcp-server-fetch"], "env": {"KEY": "${secret:PRESENT}"}}
+  }
+}`
+
+	err := ValidateMCPConfigData("mcp.json", []byte(data), ValidateOptions{
+		SecretExists: func(name string) (bool, error) { return name == "PRESENT", nil },
+	})
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+
+	expected := []string{
+		`mcp.json:3:13: .mcpServers.both: command and url are mutually exclusive`,
+		`mcp.json:4:16: .mcpServers.neither: must have either command or url`,
+		`mcp.json:5:24: .mcpServers.bad-url.url: invalid URL "example.com/mcp": the scheme must be http, https, ws or wss`,
+		`mcp.json:8:23: .mcpServers.bad-env.env.1BAD: invalid environment variable name "1BAD"`,
+		`mcp.json:8:36: .mcpServers.bad-env.env.GOOD: must be a string`,
+		`mcp.json:10:44: .mcpServers.bad-args.args: must be a list of strings`,
+		`mcp.json:11:49: .mcpServers.bad-template.args[0]: {{homedir}}: unknown template variable`,
+		`mcp.json:12:55: .mcpServers.bad-secret.env.TOKEN: ${secret:MISSING} is not in the secret store`,
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %d:\n%v", len(expected), len(errs), errs)
+	}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(errs[i].Error(), prefix) {
+			t.Errorf("Error %d:\n got      %s\n expected %s...", i, errs[i].Error(), prefix)
+		}
+	}
+}
+
+func TestValidateMCPConfigDataSyntaxError(t *testing.T) {
+	data := "{\n  \"mcpServers\": {\n    \"a\": {\"command\": \"x\",}\n  }\n}"
+	err := ValidateMCPConfigData("mcp.json", []byte(data), ValidateOptions{})
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("Expected one validation error, got %v", err)
+	}
+	if errs[0].Line != 3 || !strings.Contains(errs[0].Message, "invalid JSON") {
+		t.Errorf("Expected an invalid JSON error on line 3, got %v", errs[0])
+	}
+}
+
+func TestValidateMCPConfigDataValid(t *testing.T) {
+	for _, data := range []string{
+		`{}`,
+		`{"mcpServers": {}}`,
+		`{"version": 1, "mcpServers": {}}`,
+		`{"mcpServers": {"fetch": {"command": "uvx", "args": ["mcp-server-fetch"], "type": "stdio"}}}`,
+		`{"mcpServers": {"remote": {"url": "https://example.com/sse", "headers": {"Authorization": "Bearer ${env:TOKEN}"}}}}`,
+	} {
+		if err := ValidateMCPConfigData("mcp.json", []byte(data), ValidateOptions{}); err != nil {
+			t.Errorf("Expected %s to be valid, got:\n%v", data, err)
+		}
+	}
+}
+
+func TestValidateMCPConfigDataVersion(t *testing.T) {
+	for _, version := range []string{`1.5`, `-1`, `"1"`, `99`} {
+		data := `{"version": ` + version + `, "mcpServers": {}}`
+		var errs ValidationErrors
+		if err := ValidateMCPConfigData("mcp.json", []byte(data), ValidateOptions{}); !errors.As(err, &errs) || len(errs) != 1 || errs[0].Path != ".version" {
+			t.Errorf("Expected one .version error for %s, got %v", data, err)
+		}
+	}
+}
+
+func TestMCPSchemaIsValidJSON(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(MCPSchema, &schema); err != nil {
+		t.Fatalf("Embedded schema is not valid JSON: %v", err)
+	}
+	if schema["$schema"] == nil {
+		t.Errorf("Embedded schema has no $schema")
+	}
+}
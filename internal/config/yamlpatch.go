@@ -0,0 +1,393 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultYAMLIndent is the indentation of config files written from scratch, as yaml.Marshal does.
+const defaultYAMLIndent = 4
+
+// marshalConfig returns the YAML for cfg. When original holds the current config.yaml, the
+// values that differ are patched into its node tree instead, so comments, key order, quoting
+// and blank lines survive; if nothing differs, original is returned as it is.
+func marshalConfig(original []byte, cfg *Config) ([]byte, error) {
+	var want yaml.Node
+	if err := want.Encode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to marshal config to YAML: %w", err)
+	}
+	if len(bytes.TrimSpace(original)) == 0 {
+		return yaml.Marshal(cfg)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(original, &doc); err != nil || doc.Kind != yaml.DocumentNode {
+		// Not ours to preserve; write it from scratch
+		return yaml.Marshal(cfg)
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{&want} // Only comments so far
+	} else if doc.Content[0].Kind != yaml.MappingNode {
+		return yaml.Marshal(cfg)
+	} else if !patchNode(doc.Content[0], &want, reflect.TypeOf(cfg), "") {
+		return original, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(detectIndent(&doc))
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to marshal config to YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal config to YAML: %w", err)
+	}
+	return restoreCommentSpacing(original, restoreBlankLines(original, &doc, buf.Bytes())), nil
+}
+
+// patchNode changes dst in place to hold the value of src, touching only what differs:
+// mapping entries are matched by key and sequence items by value or name, and a replaced
+// value keeps the comments and quoting of the one it replaces. typ is the Go type src was
+// encoded from, or nil if unknown, and path the dotted key of dst. It reports whether dst changed.
+func patchNode(dst, src *yaml.Node, typ reflect.Type, path string) bool {
+	if sameValue(dst, src) {
+		return false
+	}
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch {
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		patchMapping(dst, src, typ, path)
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode:
+		var elem reflect.Type
+		if typ != nil && (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) {
+			elem = typ.Elem()
+		}
+		patchSequence(dst, src, elem, path)
+	default:
+		replaceNode(dst, src)
+	}
+	return true
+}
+
+// patchMapping keeps dst's entries in their order, patching those src has too, removes the
+// ones src lacks and inserts src's new entries after the entry that precedes them in src.
+// Entries typ has no field for are not config keys at all, e.g. a typo or a key of a newer
+// version; they are kept as written, with a warning.
+func patchMapping(dst, src *yaml.Node, typ reflect.Type, path string) {
+	srcValues := make(map[string]*yaml.Node, len(src.Content)/2)
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		srcValues[src.Content[i].Value] = src.Content[i+1]
+	}
+
+	var content []*yaml.Node
+	kept := make(map[string]bool)
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		key, value := dst.Content[i], dst.Content[i+1]
+		fieldType, known := keyType(typ, key.Value)
+		want, ok := srcValues[key.Value]
+		switch {
+		case !ok && !known:
+			if !isEmptyValue(value) {
+				fmt.Fprintf(os.Stderr, "Warning: '%s' is not a config.yaml setting; it is kept as written\n", joinKey(path, key.Value))
+			}
+		case !ok:
+			// Omitted from src because it was removed or is empty (omitempty); an empty
+			// value the user wrote does no harm, anything else is dropped
+			if !isEmptyValue(value) {
+				continue
+			}
+		default:
+			if value.Kind == yaml.ScalarNode && want.Kind != yaml.ScalarNode && key.LineComment == "" {
+				// A block value starts on the next line; its comment stays on the key's line
+				key.LineComment, value.LineComment = value.LineComment, ""
+			}
+			patchNode(value, want, fieldType, joinKey(path, key.Value))
+		}
+		kept[key.Value] = true
+		content = append(content, key, value)
+	}
+
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key := src.Content[i].Value
+		if kept[key] {
+			continue
+		}
+		at := 0 // After the last kept entry that precedes key in src, else first
+		for j := i - 2; j >= 0; j -= 2 {
+			if pos := indexOfKey(content, src.Content[j].Value); pos >= 0 {
+				at = pos + 2
+				break
+			}
+		}
+		if at == 0 && len(content) > 0 {
+			// The comment heading the file stays at the top
+			src.Content[i].HeadComment, content[0].HeadComment = content[0].HeadComment, ""
+		}
+		content = append(content[:at], append([]*yaml.Node{src.Content[i], src.Content[i+1]}, content[at:]...)...)
+		kept[key] = true
+	}
+	dst.Content = content
+}
+
+// keyType returns the type of the value under key in a mapping encoded from typ, and whether
+// typ has such a key: struct fields by their yaml name, and any key of a map. With no struct
+// to check against, every key is known.
+func keyType(typ reflect.Type, key string) (reflect.Type, bool) {
+	if typ == nil {
+		return nil, true
+	}
+	switch typ.Kind() {
+	case reflect.Map:
+		return typ.Elem(), true
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			if name == key && field.IsExported() && name != "-" {
+				return field.Type, true
+			}
+		}
+		return nil, false
+	}
+	return nil, true
+}
+
+// joinKey returns the dotted key of key under path.
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// indexOfKey returns the index of key in mapping content, or -1.
+func indexOfKey(content []*yaml.Node, key string) int {
+	for i := 0; i+1 < len(content); i += 2 {
+		if content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// patchSequence rebuilds dst in src's order, reusing each dst item that has the same value
+// as an src item, or the same name for mappings such as registries, so its comments survive.
+// elem is the Go type of the items, or nil if unknown.
+func patchSequence(dst, src *yaml.Node, elem reflect.Type, path string) {
+	used := make([]bool, len(dst.Content))
+	content := make([]*yaml.Node, 0, len(src.Content))
+	for _, want := range src.Content {
+		match := -1
+		for i, item := range dst.Content {
+			if !used[i] && sameValue(item, want) {
+				match = i
+				break
+			}
+		}
+		if match < 0 {
+			if name := mappingName(want); name != "" {
+				for i, item := range dst.Content {
+					if !used[i] && mappingName(item) == name {
+						match = i
+						break
+					}
+				}
+			}
+		}
+		if match < 0 {
+			content = append(content, want)
+			continue
+		}
+		used[match] = true
+		patchNode(dst.Content[match], want, elem, path)
+		content = append(content, dst.Content[match])
+	}
+	dst.Content = content
+}
+
+// mappingName returns the value of the name key of a mapping, or "".
+func mappingName(node *yaml.Node) string {
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+	if i := indexOfKey(node.Content, "name"); i >= 0 && node.Content[i+1].Kind == yaml.ScalarNode {
+		return node.Content[i+1].Value
+	}
+	return ""
+}
+
+// replaceNode replaces dst with src, keeping dst's comments and, for strings, its quoting style.
+func replaceNode(dst, src *yaml.Node) {
+	replacement := *src
+	replacement.HeadComment = dst.HeadComment
+	replacement.FootComment = dst.FootComment
+	if src.Kind == yaml.ScalarNode || dst.Style&yaml.FlowStyle != 0 {
+		replacement.LineComment = dst.LineComment
+	}
+	if dst.Kind == yaml.ScalarNode && src.Kind == yaml.ScalarNode && dst.Tag == src.Tag {
+		replacement.Style = dst.Style
+	}
+	if dst.Kind == src.Kind && dst.Style&yaml.FlowStyle != 0 {
+		replacement.Style |= yaml.FlowStyle
+	}
+	replacement.Line, replacement.Column = dst.Line, dst.Column
+	*dst = replacement
+}
+
+// sameValue reports whether two nodes decode to the same value, whatever their style.
+func sameValue(a, b *yaml.Node) bool {
+	var va, vb interface{}
+	if a.Decode(&va) != nil || b.Decode(&vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// isEmptyValue reports whether a node decodes to nothing: null, "", false, 0 or an empty collection.
+func isEmptyValue(node *yaml.Node) bool {
+	var v interface{}
+	if node.Decode(&v) != nil {
+		return false
+	}
+	if v == nil {
+		return true
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+// detectIndent returns the indentation of the first nested block mapping in doc, or
+// defaultYAMLIndent if there is none.
+func detectIndent(doc *yaml.Node) int {
+	var find func(node *yaml.Node) int
+	find = func(node *yaml.Node) int {
+		if node.Kind != yaml.MappingNode || node.Style&yaml.FlowStyle != 0 {
+			return 0
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Kind == yaml.MappingNode && value.Style&yaml.FlowStyle == 0 && len(value.Content) > 0 && value.Line > 0 {
+				if indent := value.Content[0].Column - key.Column; indent > 0 {
+					return indent
+				}
+			}
+			if indent := find(value); indent > 0 {
+				return indent
+			}
+		}
+		return 0
+	}
+	for _, node := range doc.Content {
+		if indent := find(node); indent >= 2 && indent <= 8 {
+			return indent
+		}
+	}
+	return defaultYAMLIndent
+}
+
+// restoreBlankLines puts back the blank lines the encoder drops: every mapping entry and
+// sequence item that followed a blank line in original is preceded by one in out again.
+// Nodes added by the patch have no position and get none.
+func restoreBlankLines(original []byte, patched *yaml.Node, out []byte) []byte {
+	var encoded yaml.Node
+	if err := yaml.Unmarshal(out, &encoded); err != nil {
+		return out
+	}
+	originalLines := strings.Split(string(original), "\n")
+	blankBefore := func(line int) bool {
+		return line >= 2 && line-2 < len(originalLines) && strings.TrimSpace(originalLines[line-2]) == ""
+	}
+
+	var insertAt []int // Output line numbers, 1-based, to put a blank line before
+	var walk func(p, e *yaml.Node)
+	mark := func(p, e *yaml.Node) {
+		if p.Line > 0 && blankBefore(p.Line-commentLines(p.HeadComment)) {
+			insertAt = append(insertAt, e.Line-commentLines(e.HeadComment))
+		}
+	}
+	walk = func(p, e *yaml.Node) {
+		if p.Kind != e.Kind || len(p.Content) != len(e.Content) {
+			return
+		}
+		switch p.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(p.Content); i += 2 {
+				mark(p.Content[i], e.Content[i])
+				walk(p.Content[i+1], e.Content[i+1])
+			}
+		case yaml.SequenceNode:
+			for i := range p.Content {
+				mark(p.Content[i], e.Content[i])
+				walk(p.Content[i], e.Content[i])
+			}
+		case yaml.DocumentNode:
+			for i := range p.Content {
+				walk(p.Content[i], e.Content[i])
+			}
+		}
+	}
+	walk(patched, &encoded)
+	if len(insertAt) == 0 {
+		return out
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(insertAt)))
+	lines := strings.Split(string(out), "\n")
+	last := -1
+	for _, line := range insertAt {
+		if line <= 1 || line > len(lines) || line == last || strings.TrimSpace(lines[line-2]) == "" {
+			continue
+		}
+		last = line
+		lines = append(lines[:line-1], append([]string{""}, lines[line-1:]...)...)
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// commentSpacing matches the whitespace before a line comment, which the encoder normalizes.
+var commentSpacing = regexp.MustCompile(`[ \t]+#`)
+
+// restoreCommentSpacing puts back the original alignment of line comments: an output line that
+// differs from an original line only in the spaces before a comment is replaced by it.
+func restoreCommentSpacing(original, out []byte) []byte {
+	originals := make(map[string]string)
+	for _, line := range strings.Split(string(original), "\n") {
+		if strings.Contains(line, "#") {
+			originals[commentSpacing.ReplaceAllString(line, " #")] = line
+		}
+	}
+	lines := strings.Split(string(out), "\n")
+	for i, line := range lines {
+		if !strings.Contains(line, "#") {
+			continue
+		}
+		if originalLine, ok := originals[commentSpacing.ReplaceAllString(line, " #")]; ok {
+			lines[i] = originalLine
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// commentLines returns how many lines a comment takes up.
+func commentLines(comment string) int {
+	if comment == "" {
+		return 0
+	}
+	return strings.Count(comment, "\n") + 1
+}
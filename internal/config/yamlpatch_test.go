@@ -0,0 +1,269 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// commentedConfig is a hand-written config.yaml with comments everywhere, blank lines,
+// quoting and a key order that differs from the one yaml.Marshal uses.
+const commentedConfig = `# mcpenetes configuration
+# Edited by hand; keep the comments!
+
+version: 1
+
+# Where backups go
+backups:
+  path: "/var/backups/mcp"  # quoted on purpose
+  retention: 5
+
+# Registries, most trusted first
+registries:
+  # The default registry
+  - name: glama
+    url: https://glama.ai/api/mcp/v1/servers
+
+  # Our own
+  - name: internal
+    url: https://mcp.internal.example.com # VPN only
+
+mcps: [github, fetch] # flow style
+
+clients:
+  # Desktop app
+  claude-desktop:
+    config_path: ~/Library/Application Support/Claude/claude_desktop_config.json
+  cursor:
+    config_path: ~/.cursor/mcp.json # project-independent
+# End of file
+`
+
+func loadCommented(t *testing.T) *Config {
+	t.Helper()
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(commentedConfig), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	return &cfg
+}
+
+func TestMarshalConfigUnchanged(t *testing.T) {
+	data, err := marshalConfig([]byte(commentedConfig), loadCommented(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != commentedConfig {
+		t.Errorf("expected an unchanged config to be kept byte for byte, got:\n%s", data)
+	}
+}
+
+func TestMarshalConfigPatchesChangedKeys(t *testing.T) {
+	cfg := loadCommented(t)
+	cfg.MCPs = append(cfg.MCPs, "search")
+	cfg.Backups.Retention = 10
+	cfg.Registries[1].URL = "https://mcp2.internal.example.com"
+	cfg.Clients["windsurf"] = Client{ConfigPath: "~/.codeium/windsurf/mcp_config.json"}
+	delete(cfg.Clients, "cursor")
+	cfg.LastClients = []string{"claude-desktop"}
+
+	data, err := marshalConfig([]byte(commentedConfig), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+
+	// Every comment that belongs to something still there survives
+	for _, comment := range []string{
+		"# mcpenetes configuration",
+		"# Edited by hand; keep the comments!",
+		"# Where backups go",
+		"# quoted on purpose",
+		"# Registries, most trusted first",
+		"# The default registry",
+		"# Our own",
+		"# VPN only",
+		"# flow style",
+		"# Desktop app",
+	} {
+		if !strings.Contains(out, comment) {
+			t.Errorf("comment %q was lost:\n%s", comment, out)
+		}
+	}
+	if strings.Contains(out, "project-independent") || strings.Contains(out, "cursor") {
+		t.Errorf("expected the removed client and its comment to be gone:\n%s", out)
+	}
+
+	// Changed values are patched in place, keeping their style
+	for _, expected := range []string{
+		"retention: 10\n",
+		`path: "/var/backups/mcp"  # quoted on purpose`,
+		"url: https://mcp2.internal.example.com # VPN only\n",
+		"mcps: [github, fetch, search] # flow style\n",
+		"windsurf:\n",
+		"last_clients:\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in:\n%s", expected, out)
+		}
+	}
+
+	// The user's key order and blank lines are kept; a new key follows the key that precedes
+	// it in config.yaml's field order
+	order := []string{"version:", "backups:", "last_clients:", "registries:", "mcps:", "clients:"}
+	last := -1
+	for _, key := range order {
+		i := strings.Index(out, "\n"+key)
+		if i <= last {
+			t.Fatalf("expected keys in the order %v:\n%s", order, out)
+		}
+		last = i
+	}
+	for _, expected := range []string{"\n\nversion: 1\n\n# Where backups go", "\n\n  # Our own", "\n\nmcps:", "\n\nclients:"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected blank line in %q:\n%s", expected, out)
+		}
+	}
+
+	// And it still means the same thing
+	var roundTripped Config
+	if err := yaml.Unmarshal(data, &roundTripped); err != nil {
+		t.Fatalf("patched config does not parse: %v\n%s", err, out)
+	}
+	expectedData, _ := yaml.Marshal(cfg)
+	actualData, _ := yaml.Marshal(&roundTripped)
+	if string(expectedData) != string(actualData) {
+		t.Errorf("patched config differs:\n%s\nexpected:\n%s", actualData, expectedData)
+	}
+}
+
+func TestMarshalConfigKeepsUnknownKeys(t *testing.T) {
+	original := `version: 1
+colour: blue # not a setting
+backups:
+  path: /var/backups/mcp
+  retention: 5
+  compress: true
+mcps: [github]
+clients:
+  cursor:
+    config_path: ~/.cursor/mcp.json
+    profile: work
+  windsurf:
+    config_path: ~/.codeium/windsurf/mcp_config.json
+`
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(original), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg.MCPs = append(cfg.MCPs, "fetch")
+	delete(cfg.Clients, "windsurf")
+
+	var data []byte
+	stderr := captureStderr(t, func() {
+		var err error
+		if data, err = marshalConfig([]byte(original), &cfg); err != nil {
+			t.Fatal(err)
+		}
+	})
+	out := string(data)
+
+	for _, expected := range []string{"colour: blue # not a setting", "compress: true", "profile: work", "mcps: [github, fetch]"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in the patched config:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "windsurf") {
+		t.Errorf("expected the removed client to be dropped:\n%s", out)
+	}
+	for _, key := range []string{"'colour'", "'backups.compress'", "'clients.cursor.profile'"} {
+		if !strings.Contains(stderr, key) {
+			t.Errorf("expected a warning naming %s, got:\n%s", key, stderr)
+		}
+	}
+}
+
+// captureStderr returns what fn writes to stderr.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = original }()
+
+	fn()
+	_ = w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestMarshalConfigFromScratch(t *testing.T) {
+	cfg := GetDefaultConfig()
+	data, err := marshalConfig(nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := yaml.Marshal(cfg)
+	if string(data) != string(expected) {
+		t.Errorf("expected a new file to be written like yaml.Marshal, got:\n%s", data)
+	}
+}
+
+func TestUpdateConfigKeepsComments(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, DefaultConfigFileName)
+	if err := os.WriteFile(configPath, []byte(commentedConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	originalGetConfigPath := getConfigPath
+	getConfigPath = func() (string, error) { return configPath, nil }
+	defer func() { getConfigPath = originalGetConfigPath }()
+
+	err := UpdateConfig(func(cfg *Config) error {
+		cfg.MCPs = append(cfg.MCPs, "search")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Replace(commentedConfig, "mcps: [github, fetch]", "mcps: [github, fetch, search]", 1)
+	if string(data) != expected {
+		t.Errorf("expected only the mcps line to change, got:\n%s", data)
+	}
+}
+
+func TestConfigMigrationKeepsComments(t *testing.T) {
+	data := `# Written by an old mcpenetes
+registries:
+  - name: glama # default
+    url: https://glama.ai/api/mcp/v1/servers
+
+clients:
+  cursor: ~/.cursor/mcp.json # shorthand
+`
+	plan, err := planConfigMigration("config.yaml", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(plan.After)
+	for _, expected := range []string{"# Written by an old mcpenetes\nversion: 1\n", "- name: glama # default", "\n\nclients:", "cursor: # shorthand\n", "config_path: ~/.cursor/mcp.json"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in the migrated config:\n%s", expected, out)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change.
+const DefaultContext = 3
+
+// opKind is the kind of a single line in an edit script.
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// op is a single line of an edit script, with its 0-based position in each input.
+type op struct {
+	Kind opKind
+	Line string
+	A, B int
+}
+
+// Unified returns a unified diff between a and b, or an empty string if they are equal.
+// fromName and toName are used in the ---/+++ header lines.
+func Unified(fromName, toName string, a, b []byte, context int) string {
+	if string(a) == string(b) {
+		return ""
+	}
+
+	ops := editScript(splitLines(string(a)), splitLines(string(b)))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for _, hunk := range hunks(ops, context) {
+		writeHunk(&sb, hunk)
+	}
+	return sb.String()
+}
+
+// splitLines splits text into lines, keeping a final line without trailing newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// editScript computes a minimal line-based edit script turning a into b.
+func editScript(a, b []string) []op {
+	// Trim the common prefix and suffix, which keeps the LCS table small for typical edits
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of midA[i:] and midB[j:]
+	lcs := make([][]int, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]op, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, op{Kind: opEqual, Line: a[i], A: i, B: i})
+	}
+
+	i, j := 0, 0
+	for i < len(midA) || j < len(midB) {
+		switch {
+		case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+			ops = append(ops, op{Kind: opEqual, Line: midA[i], A: prefix + i, B: prefix + j})
+			i++
+			j++
+		case j < len(midB) && (i == len(midA) || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, op{Kind: opInsert, Line: midB[j], A: prefix + i, B: prefix + j})
+			j++
+		default:
+			ops = append(ops, op{Kind: opDelete, Line: midA[i], A: prefix + i, B: prefix + j})
+			i++
+		}
+	}
+
+	for k := 0; k < suffix; k++ {
+		ops = append(ops, op{Kind: opEqual, Line: a[len(a)-suffix+k], A: len(a) - suffix + k, B: len(b) - suffix + k})
+	}
+	return ops
+}
+
+// hunks groups an edit script into hunks with the given amount of context.
+func hunks(ops []op, context int) [][]op {
+	var result [][]op
+	start, end := -1, -1
+	for idx, o := range ops {
+		if o.Kind == opEqual {
+			continue
+		}
+		lo := max(idx-context, 0)
+		hi := min(idx+context+1, len(ops))
+		if start >= 0 && lo <= end {
+			end = hi
+			continue
+		}
+		if start >= 0 {
+			result = append(result, ops[start:end])
+		}
+		start, end = lo, hi
+	}
+	if start >= 0 {
+		result = append(result, ops[start:end])
+	}
+	return result
+}
+
+// writeHunk writes a single hunk, including its @@ header.
+func writeHunk(sb *strings.Builder, hunk []op) {
+	aStart, bStart := hunk[0].A, hunk[0].B
+	aCount, bCount := 0, 0
+	for _, o := range hunk {
+		if o.Kind != opInsert {
+			aCount++
+		}
+		if o.Kind != opDelete {
+			bCount++
+		}
+	}
+	// Unified diffs use 1-based line numbers, and the line before the hunk for empty ranges
+	if aCount > 0 {
+		aStart++
+	}
+	if bCount > 0 {
+		bStart++
+	}
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+
+	for _, o := range hunk {
+		prefix := " "
+		switch o.Kind {
+		case opDelete:
+			prefix = "-"
+		case opInsert:
+			prefix = "+"
+		}
+		sb.WriteString(prefix + o.Line)
+		if !strings.HasSuffix(o.Line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
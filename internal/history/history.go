@@ -0,0 +1,312 @@
+// Package history keeps every version of mcpenetes' own config files in a content-addressed
+// store, so changes can be listed, inspected and undone.
+package history
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tuannvm/mcpenetes/internal/lock"
+)
+
+// DirName is the name of the history directory inside the config directory.
+const DirName = "history"
+
+// What an entry records.
+const (
+	ActionSave     = "save"     // mcpenetes wrote the file
+	ActionEdit     = "edit"     // The file was changed outside mcpenetes
+	ActionSnapshot = "snapshot" // First version of the file seen; nothing to undo to
+	ActionUndo     = "undo"     // An earlier change was undone
+)
+
+// ErrNothingToUndo is returned by UndoTarget when there is no change left to undo.
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// Command is recorded with every entry; the CLI sets it to the running command, e.g. "load".
+var Command string
+
+// Entry is one change of a file. Previous and Hash identify the content before and after the
+// change in the object store; empty means the file did not exist.
+type Entry struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	File     string    `json:"file"`
+	Action   string    `json:"action"`
+	Command  string    `json:"command,omitempty"`
+	Previous string    `json:"previous,omitempty"`
+	Hash     string    `json:"hash,omitempty"`
+	Undoes   string    `json:"undoes,omitempty"`
+}
+
+// Store is a history directory: objects/<sha256> holds every version of the files, and
+// log.jsonl lists the changes, oldest first.
+type Store struct {
+	Dir         string
+	LockTimeout time.Duration
+}
+
+// NewStore returns the store in dir, which is created on first write.
+func NewStore(dir string, lockTimeout time.Duration) *Store {
+	return &Store{Dir: dir, LockTimeout: lockTimeout}
+}
+
+func (s *Store) logPath() string {
+	return filepath.Join(s.Dir, "log.jsonl")
+}
+
+func (s *Store) objectPath(hash string) string {
+	return filepath.Join(s.Dir, "objects", hash)
+}
+
+// lock guards the log against concurrent writers.
+func (s *Store) lock() (*lock.Lock, error) {
+	if err := os.MkdirAll(s.Dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create history directory '%s': %w", s.Dir, err)
+	}
+	return lock.Acquire(filepath.Join(s.Dir, "log.lock"), s.LockTimeout)
+}
+
+// Entries returns every entry, oldest first. A missing history has no entries.
+func (s *Store) Entries() ([]Entry, error) {
+	f, err := os.Open(s.logPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse history entry on line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
+
+// Get returns the entry whose ID is id or starts with it.
+func (s *Store) Get(id string) (*Entry, error) {
+	entries, err := s.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var found *Entry
+	for i := range entries {
+		if id != "" && strings.HasPrefix(entries[i].ID, id) {
+			if found != nil {
+				return nil, fmt.Errorf("history id '%s' is ambiguous", id)
+			}
+			found = &entries[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no history entry '%s'", id)
+	}
+	return found, nil
+}
+
+// Object returns the content stored under hash.
+func (s *Store) Object(hash string) ([]byte, error) {
+	data, err := os.ReadFile(s.objectPath(hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history object %s: %w", hash, err)
+	}
+	return data, nil
+}
+
+// Observe records the current content of file as an edit if it differs from the last
+// recorded content, e.g. because it was edited by hand.
+func (s *Store) Observe(file string, data []byte, exists bool) error {
+	l, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Release() }()
+
+	_, err = s.observe(file, data, exists)
+	return err
+}
+
+// Record records that mcpenetes changed file from before to after. If before is not the
+// last recorded content, it is recorded as an edit first.
+func (s *Store) Record(file string, before []byte, beforeExists bool, after []byte, afterExists bool) error {
+	l, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Release() }()
+
+	previous, err := s.observe(file, before, beforeExists)
+	if err != nil {
+		return err
+	}
+	hash, err := s.store(after, afterExists)
+	if err != nil {
+		return err
+	}
+	if hash == previous {
+		return nil
+	}
+	return s.append(Entry{File: file, Action: ActionSave, Previous: previous, Hash: hash})
+}
+
+// UndoTarget returns the most recent change that was not undone yet.
+func (s *Store) UndoTarget() (*Entry, error) {
+	entries, err := s.Entries()
+	if err != nil {
+		return nil, err
+	}
+	undone := UndoneIDs(entries)
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Action == ActionUndo || e.Action == ActionSnapshot || undone[e.ID] {
+			continue
+		}
+		return &e, nil
+	}
+	return nil, ErrNothingToUndo
+}
+
+// RecordUndo records that target was undone by restoring its previous content.
+func (s *Store) RecordUndo(target *Entry) error {
+	l, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Release() }()
+
+	entries, err := s.Entries()
+	if err != nil {
+		return err
+	}
+	previous := ""
+	if last := lastEntry(entries, target.File); last != nil {
+		previous = last.Hash
+	}
+	return s.append(Entry{File: target.File, Action: ActionUndo, Previous: previous, Hash: target.Previous, Undoes: target.ID})
+}
+
+// UndoneIDs returns the IDs of the entries that were undone.
+func UndoneIDs(entries []Entry) map[string]bool {
+	undone := make(map[string]bool)
+	for _, e := range entries {
+		if e.Undoes != "" {
+			undone[e.Undoes] = true
+		}
+	}
+	return undone
+}
+
+// Latest returns the hash of the last recorded content of file, and whether any is recorded.
+func (s *Store) Latest(file string) (string, bool, error) {
+	entries, err := s.Entries()
+	if err != nil {
+		return "", false, err
+	}
+	if last := lastEntry(entries, file); last != nil {
+		return last.Hash, true, nil
+	}
+	return "", false, nil
+}
+
+// observe records data as the content of file if it differs from the last recorded one, and
+// returns its hash. The caller holds the lock.
+func (s *Store) observe(file string, data []byte, exists bool) (string, error) {
+	entries, err := s.Entries()
+	if err != nil {
+		return "", err
+	}
+	hash, err := s.store(data, exists)
+	if err != nil {
+		return "", err
+	}
+
+	last := lastEntry(entries, file)
+	switch {
+	case last != nil && hash == last.Hash, last == nil && !exists:
+		return hash, nil
+	case last == nil:
+		return hash, s.append(Entry{File: file, Action: ActionSnapshot, Hash: hash})
+	}
+	return hash, s.append(Entry{File: file, Action: ActionEdit, Previous: last.Hash, Hash: hash})
+}
+
+// lastEntry returns the last entry for file, or nil if there is none.
+func lastEntry(entries []Entry, file string) *Entry {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].File == file {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// store saves data as an object and returns its hash; a missing file has no object.
+func (s *Store) store(data []byte, exists bool) (string, error) {
+	if !exists {
+		return "", nil
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	objectPath := s.objectPath(hash)
+	if _, err := os.Stat(objectPath); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0750); err != nil {
+		return "", fmt.Errorf("failed to create history directory: %w", err)
+	}
+	tmp := objectPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write history object: %w", err)
+	}
+	if err := os.Rename(tmp, objectPath); err != nil {
+		_ = os.Remove(tmp)
+		return "", fmt.Errorf("failed to write history object: %w", err)
+	}
+	return hash, nil
+}
+
+// append adds an entry to the log, filling in its ID, time and command.
+func (s *Store) append(e Entry) error {
+	e.Time = time.Now().UTC()
+	if e.Command == "" {
+		e.Command = Command
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%s", e.Time.Format(time.RFC3339Nano), e.File, e.Action, e.Previous, e.Hash)))
+	e.ID = hex.EncodeToString(sum[:])[:12]
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+	f, err := os.OpenFile(s.logPath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return f.Close()
+}
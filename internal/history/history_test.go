@@ -0,0 +1,133 @@
+package history
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecordAndUndoTarget(t *testing.T) {
+	s := NewStore(t.TempDir(), time.Second)
+
+	if _, err := s.UndoTarget(); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("expected ErrNothingToUndo for an empty history, got %v", err)
+	}
+
+	// Creating a file has nothing before it
+	if err := s.Record("mcp.json", nil, false, []byte("v1"), true); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Record("mcp.json", []byte("v1"), true, []byte("v2"), true); err != nil {
+		t.Fatal(err)
+	}
+	// Saving identical content records nothing
+	if err := s.Record("mcp.json", []byte("v2"), true, []byte("v2"), true); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := s.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %+v", len(entries), entries)
+	}
+	if entries[0].Action != ActionSave || entries[0].Previous != "" {
+		t.Errorf("expected the first entry to create the file, got %+v", entries[0])
+	}
+
+	target, err := s.UndoTarget()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.ID != entries[1].ID {
+		t.Fatalf("expected to undo the latest save %s, got %s", entries[1].ID, target.ID)
+	}
+	previous, err := s.Object(target.Previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(previous) != "v1" {
+		t.Errorf("expected previous content v1, got %q", previous)
+	}
+
+	// After undoing, the change before it is next
+	if err := s.RecordUndo(target); err != nil {
+		t.Fatal(err)
+	}
+	next, err := s.UndoTarget()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.ID != entries[0].ID {
+		t.Errorf("expected to undo %s next, got %s", entries[0].ID, next.ID)
+	}
+	latest, ok, err := s.Latest("mcp.json")
+	if err != nil || !ok || latest != target.Previous {
+		t.Errorf("expected the latest content to be the restored one, got %q, %v, %v", latest, ok, err)
+	}
+}
+
+func TestObserveRecordsEdits(t *testing.T) {
+	s := NewStore(t.TempDir(), time.Second)
+
+	// The first sighting of an existing file is a snapshot, which cannot be undone
+	if err := s.Observe("config.yaml", []byte("a"), true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UndoTarget(); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("expected ErrNothingToUndo after a snapshot, got %v", err)
+	}
+
+	// A save from content that was edited by hand records the edit first
+	if err := s.Record("config.yaml", []byte("b"), true, []byte("c"), true); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := s.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	expected := []string{ActionSnapshot, ActionEdit, ActionSave}
+	if len(actions) != len(expected) {
+		t.Fatalf("expected actions %v, got %v", expected, actions)
+	}
+	for i := range expected {
+		if actions[i] != expected[i] {
+			t.Fatalf("expected actions %v, got %v", expected, actions)
+		}
+	}
+	if entries[2].Previous != entries[1].Hash {
+		t.Errorf("expected the save to follow the edit")
+	}
+}
+
+func TestGet(t *testing.T) {
+	s := NewStore(t.TempDir(), time.Second)
+	Command = "load"
+	defer func() { Command = "" }()
+
+	if err := s.Record("mcp.json", nil, false, []byte("v1"), true); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := s.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := s.Get(entries[0].ID[:6])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Command != "load" {
+		t.Errorf("expected the command to be recorded, got %q", entry.Command)
+	}
+	if _, err := s.Get("zzzz"); err == nil {
+		t.Error("expected an error for an unknown id")
+	}
+	if _, err := s.Get(""); err == nil {
+		t.Error("expected an error for an empty id")
+	}
+}
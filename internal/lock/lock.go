@@ -0,0 +1,73 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultTimeout is how long Acquire waits for a lock held by another process.
+const DefaultTimeout = 10 * time.Second
+
+// pollInterval is how often Acquire retries a busy lock.
+const pollInterval = 50 * time.Millisecond
+
+// ErrTimeout is returned (wrapped) when a lock could not be acquired in time.
+var ErrTimeout = errors.New("timed out waiting for lock")
+
+// errBusy is returned by tryLock when another process holds the lock.
+var errBusy = errors.New("lock is held by another process")
+
+// Lock is an advisory, cross-process lock backed by a lock file.
+type Lock struct {
+	path string
+	file *os.File
+}
+
+// Acquire takes an exclusive advisory lock on lockPath, creating the file if needed.
+// It waits up to timeout for another process to release the lock.
+func Acquire(lockPath string, timeout time.Duration) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory '%s': %w", filepath.Dir(lockPath), err)
+	}
+
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file '%s': %w", lockPath, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := tryLock(file)
+		if err == nil {
+			return &Lock{path: lockPath, file: file}, nil
+		}
+		if !errors.Is(err, errBusy) {
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to lock '%s': %w", lockPath, err)
+		}
+		if time.Now().After(deadline) {
+			_ = file.Close()
+			return nil, fmt.Errorf("%w '%s' after %s; another mcpenetes process may be running (use --lock-timeout to wait longer)", ErrTimeout, lockPath, timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Release unlocks and closes the lock file. The file itself is left in place.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := unlock(l.file)
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	l.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to release lock '%s': %w", l.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquireRelease(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "nested", "test.lock")
+
+	l, err := Acquire(lockPath, time.Second)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Errorf("Lock file was not created: %v", err)
+	}
+	if err := l.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+
+	// The lock can be taken again once released
+	l, err = Acquire(lockPath, time.Second)
+	if err != nil {
+		t.Fatalf("Acquire after release failed: %v", err)
+	}
+	_ = l.Release()
+}
+
+func TestAcquireTimeout(t *testing.T) {
+	if os.Getenv("MCPENETES_LOCK_HELPER") != "" {
+		// Child process: hold the lock until killed
+		l, err := Acquire(os.Getenv("MCPENETES_LOCK_HELPER"), time.Second)
+		if err != nil {
+			os.Exit(1)
+		}
+		defer func() { _ = l.Release() }()
+		_, _ = os.Stdout.WriteString("locked\n")
+		time.Sleep(time.Minute)
+		return
+	}
+
+	lockPath := filepath.Join(t.TempDir(), "test.lock")
+
+	// flock is per open file description, so hold the lock from another process
+	child := exec.Command(os.Args[0], "-test.run=TestAcquireTimeout")
+	child.Env = append(os.Environ(), "MCPENETES_LOCK_HELPER="+lockPath)
+	stdout, err := child.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	if err := child.Start(); err != nil {
+		t.Fatalf("Failed to start helper process: %v", err)
+	}
+	defer func() {
+		_ = child.Process.Kill()
+		_ = child.Wait()
+	}()
+
+	buf := make([]byte, len("locked\n"))
+	if _, err := stdout.Read(buf); err != nil {
+		t.Fatalf("Helper process did not acquire the lock: %v", err)
+	}
+
+	_, err = Acquire(lockPath, 200*time.Millisecond)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got %v", err)
+	}
+}
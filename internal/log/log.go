@@ -1,6 +1,7 @@
 package log
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/fatih/color"
@@ -17,22 +18,22 @@ var (
 
 // Info prints an informational message (cyan).
 func Info(format string, a ...interface{}) {
-	_, _ = InfoColor.Fprintf(os.Stdout, format+"\n", a...)
+	_, _ = InfoColor.Fprint(os.Stdout, Redact(fmt.Sprintf(format, a...))+"\n")
 }
 
 // Success prints a success message (green).
 func Success(format string, a ...interface{}) {
-	_, _ = SuccessColor.Fprintf(os.Stdout, format+"\n", a...)
+	_, _ = SuccessColor.Fprint(os.Stdout, Redact(fmt.Sprintf(format, a...))+"\n")
 }
 
 // Warn prints a warning message (yellow) to stderr.
 func Warn(format string, a ...interface{}) {
-	_, _ = WarnColor.Fprintf(os.Stderr, "Warning: "+format+"\n", a...)
+	_, _ = WarnColor.Fprint(os.Stderr, Redact("Warning: "+fmt.Sprintf(format, a...))+"\n")
 }
 
 // Error prints an error message (red) to stderr.
 func Error(format string, a ...interface{}) {
-	_, _ = ErrorColor.Fprintf(os.Stderr, "Error: "+format+"\n", a...)
+	_, _ = ErrorColor.Fprint(os.Stderr, Redact("Error: "+fmt.Sprintf(format, a...))+"\n")
 }
 
 // Fatal prints an error message (red) to stderr and exits with status 1.
@@ -43,15 +44,15 @@ func Fatal(format string, a ...interface{}) {
 
 // Detail prints less important details (usually white/default).
 func Detail(format string, a ...interface{}) {
-	_, _ = DetailColor.Fprintf(os.Stdout, format+"\n", a...)
+	_, _ = DetailColor.Fprint(os.Stdout, Redact(fmt.Sprintf(format, a...))+"\n")
 }
 
 // Printf allows printing with a specific color.
 func Printf(c *color.Color, format string, a ...interface{}) {
-	_, _ = c.Printf(format, a...)
+	_, _ = c.Print(Redact(fmt.Sprintf(format, a...)))
 }
 
 // Fprintf allows printing to a specific writer with a specific color.
 func Fprintf(w *os.File, c *color.Color, format string, a ...interface{}) {
-	_, _ = c.Fprintf(w, format, a...)
+	_, _ = c.Fprint(w, Redact(fmt.Sprintf(format, a...)))
 }
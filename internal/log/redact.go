@@ -0,0 +1,126 @@
+package log
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces redacted values in output.
+const Mask = "********"
+
+// minSecretLength is the shortest registered secret that is masked; shorter values would
+// mask unrelated text.
+const minSecretLength = 4
+
+// secretKeyPattern matches key names (env vars, headers, JSON fields) whose values are secrets.
+var secretKeyPattern = regexp.MustCompile(`(?i)(token|secret|passw(or)?d|passwd|pwd|api[_-]?key|access[_-]?key|private[_-]?key|client[_-]?secret|credential|authorization|cookie|session[_-]?id|^auth$|[_-]auth$|^auth[_-])`)
+
+// keyValuePattern matches "key": "value", key: value, key = "value" and KEY=value pairs.
+// Group 2 is the key and group 4 the value, quoted or not.
+var keyValuePattern = regexp.MustCompile(`("?)([A-Za-z0-9_.-]+)("?[ \t]*[:=][ \t]*)("(?:[^"\\\n]|\\.)*"|'[^'\n]*'|[^\s,;}\]"']+)`)
+
+var (
+	mu          sync.RWMutex
+	showSecrets bool
+	secrets     = make(map[string]bool)
+	// secretList holds the registered secrets, longest first, so longer ones are masked before their substrings
+	secretList []string
+)
+
+// SetShowSecrets disables (true) or enables (false) redaction.
+func SetShowSecrets(show bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	showSecrets = show
+}
+
+// ShowSecrets reports whether redaction is disabled.
+func ShowSecrets() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return showSecrets
+}
+
+// RegisterSecret marks a value as secret, so it is masked wherever it appears in output.
+func RegisterSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if secrets[value] {
+		return
+	}
+	secrets[value] = true
+	secretList = append(secretList, value)
+	sort.Slice(secretList, func(i, j int) bool { return len(secretList[i]) > len(secretList[j]) })
+}
+
+// ContainsSecret reports whether s contains a value registered with RegisterSecret.
+func ContainsSecret(s string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, secret := range secretList {
+		if strings.Contains(s, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSecretKey reports whether values of the given key (an env var, header or field name) are secrets.
+// For a dotted path such as env.GITHUB_TOKEN, the last segment is checked.
+func IsSecretKey(key string) bool {
+	if idx := strings.LastIndex(key, "."); idx >= 0 {
+		key = key[idx+1:]
+	}
+	return secretKeyPattern.MatchString(key)
+}
+
+// Redact masks registered secrets and the values of secret-looking keys in s,
+// unless redaction was disabled with SetShowSecrets. Every print function of this
+// package passes its output through Redact.
+func Redact(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if showSecrets {
+		return s
+	}
+
+	for _, secret := range secretList {
+		s = strings.ReplaceAll(s, secret, Mask)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range keyValuePattern.FindAllStringSubmatchIndex(s, -1) {
+		key, separator, value := s[m[4]:m[5]], s[m[6]:m[7]], s[m[8]:m[9]]
+		if !IsSecretKey(key) || value == `""` || value == `''` || strings.Contains(value, Mask) {
+			continue
+		}
+		quoted := value[0] == '"' || value[0] == '\''
+		// An unquoted value after "key:" is only YAML when the key starts the line;
+		// elsewhere it is more likely a message such as "env.TOKEN: not set"
+		if !quoted && strings.Contains(separator, ":") && !startsLine(s, m[0]) {
+			continue
+		}
+
+		b.WriteString(s[last:m[8]])
+		if quoted {
+			b.WriteString(value[:1] + Mask + value[len(value)-1:])
+		} else {
+			b.WriteString(Mask)
+		}
+		last = m[9]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// startsLine reports whether only indentation, list markers or diff markers precede pos on its line.
+func startsLine(s string, pos int) bool {
+	lineStart := strings.LastIndexByte(s[:pos], '\n') + 1
+	return strings.Trim(s[lineStart:pos], " \t-+") == ""
+}
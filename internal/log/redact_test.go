@@ -0,0 +1,53 @@
+package log
+
+import "testing"
+
+func TestRedact(t *testing.T) {
+	RegisterSecret("registered-secret-value")
+	RegisterSecret("abc") // Too short to be masked safely
+
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{`"GITHUB_TOKEN": "ghp_123"`, `"GITHUB_TOKEN": "********"`},
+		{`{"env": {"OPENAI_API_KEY": "sk-1", "DEBUG": "1"}}`, `{"env": {"OPENAI_API_KEY": "********", "DEBUG": "1"}}`},
+		{`"Authorization": "Bearer xyz"`, `"Authorization": "********"`},
+		{`DB_PASSWORD=hunter2 OTHER=x`, `DB_PASSWORD=******** OTHER=x`},
+		{`api_key = "toml-value"`, `api_key = "********"`},
+		{"+      SLACK_TOKEN: xoxb-1", "+      SLACK_TOKEN: ********"},
+		{"Error: env.GITHUB_TOKEN: environment variable GITHUB_TOKEN is not set", "Error: env.GITHUB_TOKEN: environment variable GITHUB_TOKEN is not set"},
+		{`"author": "someone", "command": "npx"`, `"author": "someone", "command": "npx"`},
+		{`"TOKEN": ""`, `"TOKEN": ""`},
+		{"value is registered-secret-value here", "value is ******** here"},
+		{"abc stays", "abc stays"},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.in); got != tt.expected {
+			t.Errorf("Redact(%q)\n got      %q\n expected %q", tt.in, got, tt.expected)
+		}
+	}
+
+	if !ContainsSecret("x registered-secret-value y") || ContainsSecret("abc") {
+		t.Errorf("ContainsSecret should report registered secrets long enough to mask")
+	}
+
+	SetShowSecrets(true)
+	defer SetShowSecrets(false)
+	if got := Redact(`"GITHUB_TOKEN": "ghp_123"`); got != `"GITHUB_TOKEN": "ghp_123"` {
+		t.Errorf("Expected no redaction with secrets shown, got %q", got)
+	}
+}
+
+func TestIsSecretKey(t *testing.T) {
+	for _, key := range []string{"GITHUB_TOKEN", "env.API_KEY", "headers.Authorization", "client_secret", "PGPASSWORD", "X-Auth", "auth"} {
+		if !IsSecretKey(key) {
+			t.Errorf("Expected %s to be a secret key", key)
+		}
+	}
+	for _, key := range []string{"command", "args", "author", "DEBUG", "autoApprove", "env.PATH"} {
+		if IsSecretKey(key) {
+			t.Errorf("Expected %s not to be a secret key", key)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// rootOverride and homeOverride are set from the --root and --home flags. Client configs,
+// backups and {{home}} are then placed in that tree instead of the current user's home.
+var (
+	rootOverride string
+	homeOverride string
+)
+
+// SetRoot makes dir the filesystem root that client configs are written under.
+// An empty dir restores the real root.
+func SetRoot(dir string) {
+	rootOverride = dir
+}
+
+// SetHome makes dir the home directory that client configs are written for; with a root
+// it is taken relative to that root. An empty dir restores the current user's home.
+func SetHome(dir string) {
+	homeOverride = dir
+}
+
+// Rebased reports whether --root or --home redirect client paths.
+func Rebased() bool {
+	return rootOverride != "" || homeOverride != ""
+}
+
+// RealHomeDir returns the current user's home directory, ignoring --root and --home.
+func RealHomeDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return homeDir, nil
+}
+
+// root returns the absolute --root directory, or "" when none was given.
+func root() (string, error) {
+	if rootOverride == "" {
+		return "", nil
+	}
+	abs, err := filepath.Abs(rootOverride)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve root directory '%s': %w", rootOverride, err)
+	}
+	return abs, nil
+}
+
+// HomeDir returns the home directory client configs are written for: --home (under --root
+// if given), the current user's home under --root, or the current user's home.
+func HomeDir() (string, error) {
+	rootDir, err := root()
+	if err != nil {
+		return "", err
+	}
+
+	homeDir := homeOverride
+	if homeDir == "" {
+		if homeDir, err = RealHomeDir(); err != nil {
+			return "", err
+		}
+	}
+	if rootDir != "" {
+		if !filepath.IsAbs(homeDir) || !within(homeDir, rootDir) {
+			return joinUnder(rootDir, homeDir), nil
+		}
+		return homeDir, nil
+	}
+
+	abs, err := filepath.Abs(homeDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve home directory '%s': %w", homeDir, err)
+	}
+	return abs, nil
+}
+
+// Rebase moves an absolute path into the tree selected with --root and --home: paths in
+// the current user's home move to HomeDir, and other paths move under the root. Paths
+// already in that tree, relative paths, and all paths when neither flag is set, are returned
+// unchanged, so rebasing twice is the same as rebasing once.
+func Rebase(path string) (string, error) {
+	if !Rebased() || !filepath.IsAbs(path) {
+		return path, nil
+	}
+
+	homeDir, err := HomeDir()
+	if err != nil {
+		return "", err
+	}
+	rootDir, err := root()
+	if err != nil {
+		return "", err
+	}
+	if within(path, homeDir) || (rootDir != "" && within(path, rootDir)) {
+		return path, nil
+	}
+
+	realHome, err := RealHomeDir()
+	if err != nil {
+		return "", err
+	}
+	if within(path, realHome) {
+		rel, err := filepath.Rel(realHome, path)
+		if err != nil {
+			return "", fmt.Errorf("failed to rebase '%s': %w", path, err)
+		}
+		return filepath.Join(homeDir, rel), nil
+	}
+	if rootDir != "" {
+		return joinUnder(rootDir, path), nil
+	}
+	return path, nil
+}
+
+// within reports whether path is dir or inside it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// joinUnder places path inside dir, dropping any volume name (e.g. C:) from path.
+func joinUnder(dir, path string) string {
+	return filepath.Join(dir, path[len(filepath.VolumeName(path)):])
+}
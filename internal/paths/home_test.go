@@ -0,0 +1,85 @@
+package paths
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// setTree sets --root and --home for the duration of the test.
+func setTree(t *testing.T, rootDir, homeDir string) {
+	t.Helper()
+	SetRoot(rootDir)
+	SetHome(homeDir)
+	t.Cleanup(func() {
+		SetRoot("")
+		SetHome("")
+	})
+}
+
+func TestHomeDir(t *testing.T) {
+	realHome := setEnv(t)
+
+	tests := []struct {
+		name     string
+		root     string
+		home     string
+		expected string
+	}{
+		{"no flags", "", "", realHome},
+		{"--home", "", "/srv/home/dev", "/srv/home/dev"},
+		{"--root keeps the home path", "/tmp/img", "", filepath.Join("/tmp/img", realHome)},
+		{"--root and --home", "/tmp/img", "/home/dev", "/tmp/img/home/dev"},
+		{"--home already inside --root", "/tmp/img", "/tmp/img/home/dev", "/tmp/img/home/dev"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTree(t, tt.root, tt.home)
+			got, err := HomeDir()
+			if err != nil {
+				t.Fatalf("HomeDir failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("HomeDir() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRebase(t *testing.T) {
+	realHome := setEnv(t)
+
+	tests := []struct {
+		name     string
+		root     string
+		home     string
+		path     string
+		expected string
+	}{
+		{"no flags", "", "", filepath.Join(realHome, ".cursor/mcp.json"), filepath.Join(realHome, ".cursor/mcp.json")},
+		{"relative path", "/tmp/img", "/home/dev", "relative/mcp.json", "relative/mcp.json"},
+		{"real home to --home", "", "/srv/dev", filepath.Join(realHome, ".cursor/mcp.json"), "/srv/dev/.cursor/mcp.json"},
+		{"outside home without --root", "", "/srv/dev", "/etc/mcp.json", "/etc/mcp.json"},
+		{"real home into the tree", "/tmp/img", "/home/dev", filepath.Join(realHome, ".cursor/mcp.json"), "/tmp/img/home/dev/.cursor/mcp.json"},
+		{"outside home under --root", "/tmp/img", "/home/dev", "/etc/mcp.json", "/tmp/img/etc/mcp.json"},
+		{"already in the tree", "/tmp/img", "/home/dev", "/tmp/img/home/dev/.cursor/mcp.json", "/tmp/img/home/dev/.cursor/mcp.json"},
+		{"already under --root", "/tmp/img", "/home/dev", "/tmp/img/etc/mcp.json", "/tmp/img/etc/mcp.json"},
+		{"prefix is not containment", "/tmp/img", "/home/dev", "/tmp/img2/mcp.json", "/tmp/img/tmp/img2/mcp.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTree(t, tt.root, tt.home)
+			got, err := Rebase(tt.path)
+			if err != nil {
+				t.Fatalf("Rebase failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Rebase(%q) = %q, expected %q", tt.path, got, tt.expected)
+			}
+
+			again, err := Rebase(got)
+			if err != nil || again != got {
+				t.Errorf("Rebase is not idempotent: Rebase(%q) = %q, %v", got, again, err)
+			}
+		})
+	}
+}
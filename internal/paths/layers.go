@@ -0,0 +1,54 @@
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// SystemDirEnv overrides the machine-wide config directory, e.g. for testing a baseline.
+const SystemDirEnv = "MCPENETES_SYSTEM_DIR"
+
+// ProjectDirName is the directory holding a project's config, found in the current directory
+// or one of its parents.
+const ProjectDirName = ".mcpenetes"
+
+// SystemConfigDir returns the machine-wide config directory: $MCPENETES_SYSTEM_DIR,
+// %ProgramData%\mcpenetes on Windows, or /etc/mcpenetes.
+func SystemConfigDir() string {
+	if dir := os.Getenv(SystemDirEnv); dir != "" {
+		return dir
+	}
+	if runtime.GOOS == "windows" {
+		if programData := os.Getenv("ProgramData"); programData != "" {
+			return filepath.Join(programData, AppName)
+		}
+	}
+	return filepath.Join("/etc", AppName)
+}
+
+// ProjectConfigDir returns the nearest .mcpenetes directory in the current directory or its
+// parents, or "" if there is none. The user's own config directory is never a project directory.
+func ProjectConfigDir() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		candidate := filepath.Join(dir, ProjectDirName)
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() && candidate != configDir {
+			return candidate, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
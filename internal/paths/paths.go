@@ -0,0 +1,170 @@
+// Package paths resolves where mcpenetes keeps its configuration and cache.
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// AppName is the name of mcpenetes' directories under the XDG base directories.
+const AppName = "mcpenetes"
+
+// legacyDirName is the directory older versions used, always under ~/.config.
+const legacyDirName = "mcpetes"
+
+// Environment variables that relocate mcpenetes' files.
+const (
+	HomeEnv          = "MCPENETES_HOME"  // Holds everything: config, state and cache
+	xdgConfigHomeEnv = "XDG_CONFIG_HOME" // Config goes to $XDG_CONFIG_HOME/mcpenetes
+	xdgCacheHomeEnv  = "XDG_CACHE_HOME"  // Cache goes to $XDG_CACHE_HOME/mcpenetes
+)
+
+// configDirOverride is set from the --config-dir flag and takes precedence over the environment.
+var configDirOverride string
+
+// SetConfigDir makes dir the config directory, overriding the environment. An empty dir
+// restores the default resolution.
+func SetConfigDir(dir string) {
+	configDirOverride = dir
+}
+
+// explicitConfigDir returns the config directory chosen with --config-dir or MCPENETES_HOME, if any.
+func explicitConfigDir() (string, error) {
+	dir := configDirOverride
+	if dir == "" {
+		dir = os.Getenv(HomeEnv)
+	}
+	if dir == "" {
+		return "", nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve config directory '%s': %w", dir, err)
+	}
+	return abs, nil
+}
+
+// ConfigDir returns the directory holding config.yaml, mcp.json and the other mcpenetes
+// files. In order of precedence: --config-dir, $MCPENETES_HOME, $XDG_CONFIG_HOME/mcpenetes
+// and ~/.config/mcpenetes.
+func ConfigDir() (string, error) {
+	if dir, err := explicitConfigDir(); dir != "" || err != nil {
+		return dir, err
+	}
+	if xdg := os.Getenv(xdgConfigHomeEnv); filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, AppName), nil
+	}
+	homeDir, err := RealHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".config", AppName), nil
+}
+
+// CacheDir returns the directory for registry caches: $XDG_CACHE_HOME/mcpenetes when set,
+// and the cache directory inside ConfigDir otherwise (always when --config-dir or
+// $MCPENETES_HOME is used, so everything stays in one place).
+func CacheDir() (string, error) {
+	dir, err := explicitConfigDir()
+	if err != nil {
+		return "", err
+	}
+	if dir == "" {
+		if xdg := os.Getenv(xdgCacheHomeEnv); filepath.IsAbs(xdg) {
+			return filepath.Join(xdg, AppName), nil
+		}
+		if dir, err = ConfigDir(); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(dir, "cache"), nil
+}
+
+// LegacyConfigDir returns ~/.config/mcpetes, where older versions kept their files.
+func LegacyConfigDir() (string, error) {
+	homeDir, err := RealHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".config", legacyDirName), nil
+}
+
+// MigrateLegacy moves the legacy config directory to ConfigDir when the legacy directory
+// exists and ConfigDir does not. It is skipped when the config directory was chosen with
+// --config-dir or $MCPENETES_HOME. It returns the directories involved when it moved anything.
+func MigrateLegacy() (from, to string, err error) {
+	if dir, err := explicitConfigDir(); dir != "" || err != nil {
+		return "", "", err
+	}
+	legacyDir, err := LegacyConfigDir()
+	if err != nil {
+		return "", "", err
+	}
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", "", err
+	}
+
+	if info, err := os.Stat(legacyDir); err != nil || !info.IsDir() {
+		return "", "", nil
+	}
+	if _, err := os.Lstat(configDir); err == nil {
+		return "", "", nil // Already migrated, or both exist: leave the legacy directory alone
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", "", fmt.Errorf("failed to check config directory '%s': %w", configDir, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(configDir), 0750); err != nil {
+		return "", "", fmt.Errorf("failed to create directory '%s': %w", filepath.Dir(configDir), err)
+	}
+	if err := os.Rename(legacyDir, configDir); err != nil {
+		// Possibly on another filesystem ($XDG_CONFIG_HOME): copy, then remove the original
+		if err := copyDir(legacyDir, configDir); err != nil {
+			_ = os.RemoveAll(configDir)
+			return "", "", fmt.Errorf("failed to move '%s' to '%s': %w", legacyDir, configDir, err)
+		}
+		if err := os.RemoveAll(legacyDir); err != nil {
+			return "", "", fmt.Errorf("copied '%s' to '%s' but failed to remove the original: %w", legacyDir, configDir, err)
+		}
+	}
+	return legacyDir, configDir, nil
+}
+
+// copyDir copies the directory tree at src to dst, preserving permissions. Lock files are skipped.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case !info.Mode().IsRegular() || filepath.Ext(path) == ".lock":
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
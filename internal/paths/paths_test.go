@@ -0,0 +1,172 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setEnv points HOME at a temporary directory and clears the variables ConfigDir looks at.
+func setEnv(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(HomeEnv, "")
+	t.Setenv(xdgConfigHomeEnv, "")
+	t.Setenv(xdgCacheHomeEnv, "")
+	SetConfigDir("")
+	t.Cleanup(func() { SetConfigDir("") })
+	return home
+}
+
+func TestConfigDir(t *testing.T) {
+	home := setEnv(t)
+
+	tests := []struct {
+		name          string
+		flag          string
+		env           map[string]string
+		expectedDir   string
+		expectedCache string
+	}{
+		{
+			name:          "default",
+			expectedDir:   filepath.Join(home, ".config", "mcpenetes"),
+			expectedCache: filepath.Join(home, ".config", "mcpenetes", "cache"),
+		},
+		{
+			name:          "XDG base directories",
+			env:           map[string]string{xdgConfigHomeEnv: "/xdg/config", xdgCacheHomeEnv: "/xdg/cache"},
+			expectedDir:   "/xdg/config/mcpenetes",
+			expectedCache: "/xdg/cache/mcpenetes",
+		},
+		{
+			name:          "relative XDG paths are ignored",
+			env:           map[string]string{xdgConfigHomeEnv: "relative"},
+			expectedDir:   filepath.Join(home, ".config", "mcpenetes"),
+			expectedCache: filepath.Join(home, ".config", "mcpenetes", "cache"),
+		},
+		{
+			name:          "MCPENETES_HOME wins over XDG",
+			env:           map[string]string{HomeEnv: "/opt/mcp", xdgConfigHomeEnv: "/xdg/config", xdgCacheHomeEnv: "/xdg/cache"},
+			expectedDir:   "/opt/mcp",
+			expectedCache: "/opt/mcp/cache",
+		},
+		{
+			name:          "--config-dir wins over everything",
+			flag:          "/flag/dir",
+			env:           map[string]string{HomeEnv: "/opt/mcp", xdgConfigHomeEnv: "/xdg/config"},
+			expectedDir:   "/flag/dir",
+			expectedCache: "/flag/dir/cache",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t)
+			t.Setenv("HOME", home)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			SetConfigDir(tt.flag)
+
+			dir, err := ConfigDir()
+			if err != nil {
+				t.Fatalf("ConfigDir failed: %v", err)
+			}
+			if dir != tt.expectedDir {
+				t.Errorf("ConfigDir() = %q, expected %q", dir, tt.expectedDir)
+			}
+			cacheDir, err := CacheDir()
+			if err != nil {
+				t.Fatalf("CacheDir failed: %v", err)
+			}
+			if cacheDir != tt.expectedCache {
+				t.Errorf("CacheDir() = %q, expected %q", cacheDir, tt.expectedCache)
+			}
+		})
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	home := setEnv(t)
+	legacyDir := filepath.Join(home, ".config", "mcpetes")
+	if err := os.MkdirAll(filepath.Join(legacyDir, "backups"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(legacyDir, "mcp.json"), []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	from, to, err := MigrateLegacy()
+	if err != nil {
+		t.Fatalf("MigrateLegacy failed: %v", err)
+	}
+	expectedDir := filepath.Join(home, ".config", "mcpenetes")
+	if from != legacyDir || to != expectedDir {
+		t.Errorf("MigrateLegacy() = %q, %q; expected %q, %q", from, to, legacyDir, expectedDir)
+	}
+	if _, err := os.Stat(filepath.Join(expectedDir, "mcp.json")); err != nil {
+		t.Errorf("mcp.json was not moved: %v", err)
+	}
+	if _, err := os.Stat(legacyDir); !os.IsNotExist(err) {
+		t.Errorf("Legacy directory still exists")
+	}
+
+	// Nothing left to migrate
+	if from, _, err := MigrateLegacy(); err != nil || from != "" {
+		t.Errorf("Second MigrateLegacy() = %q, %v; expected nothing to do", from, err)
+	}
+}
+
+func TestMigrateLegacySkipped(t *testing.T) {
+	home := setEnv(t)
+	legacyDir := filepath.Join(home, ".config", "mcpetes")
+	if err := os.MkdirAll(legacyDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	// An explicit config directory is never populated from the legacy one
+	SetConfigDir(filepath.Join(home, "elsewhere"))
+	if from, _, err := MigrateLegacy(); err != nil || from != "" {
+		t.Errorf("MigrateLegacy() with --config-dir = %q, %v; expected nothing to do", from, err)
+	}
+	SetConfigDir("")
+
+	// Neither is an existing config directory
+	if err := os.MkdirAll(filepath.Join(home, ".config", "mcpenetes"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if from, _, err := MigrateLegacy(); err != nil || from != "" {
+		t.Errorf("MigrateLegacy() with an existing config directory = %q, %v; expected nothing to do", from, err)
+	}
+	if _, err := os.Stat(legacyDir); err != nil {
+		t.Errorf("Legacy directory should be left alone: %v", err)
+	}
+}
+
+func TestCopyDir(t *testing.T) {
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "copy")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"a.json": "a", "sub/b.yaml": "b", "config.yaml.lock": ""} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := copyDir(src, dst); err != nil {
+		t.Fatalf("copyDir failed: %v", err)
+	}
+	for name, content := range map[string]string{"a.json": "a", "sub/b.yaml": "b"} {
+		data, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil || string(data) != content {
+			t.Errorf("%s: got %q, %v; expected %q", name, data, err, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dst, "config.yaml.lock")); !os.IsNotExist(err) {
+		t.Errorf("Lock files should not be copied")
+	}
+}
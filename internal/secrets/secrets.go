@@ -0,0 +1,300 @@
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tuannvm/mcpenetes/internal/log"
+)
+
+// CommandTimeout bounds how long a ${cmd:...} reference may run.
+const CommandTimeout = 30 * time.Second
+
+// ErrCommandNotAllowed is returned (wrapped) for a ${cmd:...} reference that may not run.
+var ErrCommandNotAllowed = errors.New("${cmd:...} references are not allowed to run")
+
+// referencePattern matches ${kind:argument} references. A leading "$$" escapes a reference.
+var referencePattern = regexp.MustCompile(`\$?\$\{(env|file|dotenv|cmd|secret):([^}]*)\}`)
+
+// Resolver resolves secret references in values:
+//
+//	${env:NAME}           the environment variable NAME
+//	${file:PATH}          the content of the file at PATH, without trailing newlines
+//	${dotenv:PATH:KEY}    KEY from the .env file at PATH
+//	${cmd:COMMAND}        the output of COMMAND run by the shell, without trailing newlines
+//	${secret:NAME}        NAME from the encrypted secret store at StorePath
+//
+// Relative paths are resolved against BaseDir. Write $${...} for a literal ${...}.
+// ${cmd:...} references only run when AllowCommands is set.
+// A Resolver is safe for concurrent use; command output is cached so each command runs once,
+// and the secret store is only decrypted, once, when a ${secret:...} reference is resolved.
+type Resolver struct {
+	BaseDir       string
+	StorePath     string
+	AllowCommands bool
+
+	mu       sync.Mutex
+	commands map[string]commandResult
+
+	storeMu  sync.Mutex
+	store    *Store
+	storeErr error
+}
+
+type commandResult struct {
+	output string
+	err    error
+}
+
+// NewResolver returns a Resolver for relative paths under baseDir, using the secret store in baseDir.
+func NewResolver(baseDir string) *Resolver {
+	return &Resolver{
+		BaseDir:   baseDir,
+		StorePath: filepath.Join(baseDir, DefaultStoreFileName),
+		commands:  make(map[string]commandResult),
+	}
+}
+
+// HasReference reports whether value contains a secret reference.
+func HasReference(value string) bool {
+	for _, match := range referencePattern.FindAllString(value, -1) {
+		if !strings.HasPrefix(match, "$$") {
+			return true
+		}
+	}
+	return false
+}
+
+// HasCommand reports whether value contains a ${cmd:...} reference.
+func HasCommand(value string) bool {
+	for _, ref := range References(value) {
+		if ref.Kind == "cmd" {
+			return true
+		}
+	}
+	return false
+}
+
+// Reference is a secret reference found in a value.
+type Reference struct {
+	Kind string // env, file, dotenv, cmd or secret
+	Arg  string
+}
+
+// References returns the secret references in value, ignoring escaped ones.
+func References(value string) []Reference {
+	var refs []Reference
+	for _, match := range referencePattern.FindAllStringSubmatch(value, -1) {
+		if strings.HasPrefix(match[0], "$$") {
+			continue
+		}
+		refs = append(refs, Reference{Kind: match[1], Arg: match[2]})
+	}
+	return refs
+}
+
+// Validate checks the syntax of a reference without resolving it.
+func (ref Reference) Validate() error {
+	switch {
+	case ref.Arg == "":
+		return fmt.Errorf("${%s:}: missing argument", ref.Kind)
+	case ref.Kind == "dotenv" && !strings.Contains(ref.Arg, ":"):
+		return fmt.Errorf("${dotenv:%s}: expected ${dotenv:PATH:KEY}", ref.Arg)
+	case ref.Kind == "secret":
+		if err := ValidateName(ref.Arg); err != nil {
+			return fmt.Errorf("${secret:%s}: %w", ref.Arg, err)
+		}
+	}
+	return nil
+}
+
+// Resolve returns value with every secret reference replaced by its resolved value.
+func (r *Resolver) Resolve(value string) (string, error) {
+	var firstErr error
+	resolved := referencePattern.ReplaceAllStringFunc(value, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		if firstErr != nil {
+			return match
+		}
+		parts := referencePattern.FindStringSubmatch(match)
+		secret, err := r.resolveReference(parts[1], parts[2])
+		if err != nil {
+			firstErr = fmt.Errorf("failed to resolve %s: %w", match, err)
+			return match
+		}
+		// Mask every resolved value in output: referencing it marks it as secret, whatever its name
+		log.RegisterSecret(secret)
+		return secret
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return resolved, nil
+}
+
+// resolveReference resolves a single reference of the given kind.
+func (r *Resolver) resolveReference(kind, arg string) (string, error) {
+	switch kind {
+	case "env":
+		value, ok := os.LookupEnv(arg)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", arg)
+		}
+		return value, nil
+	case "file":
+		path, err := r.path(arg)
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case "dotenv":
+		// Split on the last colon so Windows paths like C:\x\.env keep theirs
+		idx := strings.LastIndex(arg, ":")
+		if idx < 0 {
+			return "", fmt.Errorf("expected ${dotenv:PATH:KEY}")
+		}
+		path, err := r.path(arg[:idx])
+		if err != nil {
+			return "", err
+		}
+		return lookupDotenv(path, arg[idx+1:])
+	case "cmd":
+		if !r.AllowCommands {
+			return "", ErrCommandNotAllowed
+		}
+		return r.runCommand(arg)
+	case "secret":
+		store, err := r.openStore()
+		if err != nil {
+			return "", err
+		}
+		value, ok := store.Get(arg)
+		if !ok {
+			return "", fmt.Errorf("secret %s is not in the secret store (add it with 'mcpenetes secret set %s')", arg, arg)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("unknown reference kind %q", kind)
+}
+
+// path expands ~ and resolves a relative path against BaseDir.
+func (r *Resolver) path(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("empty path")
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get user home directory: %w", err)
+		}
+		return filepath.Join(homeDir, path[1:]), nil
+	}
+	if !filepath.IsAbs(path) && r.BaseDir != "" {
+		return filepath.Join(r.BaseDir, path), nil
+	}
+	return path, nil
+}
+
+// lookupDotenv returns the value of key in the .env file at path.
+func lookupDotenv(path, key string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(name) != key {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		return value, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s is not set in %s", key, path)
+}
+
+// openStore decrypts the secret store on first use and caches the result.
+func (r *Resolver) openStore() (*Store, error) {
+	r.storeMu.Lock()
+	defer r.storeMu.Unlock()
+	if r.store != nil || r.storeErr != nil {
+		return r.store, r.storeErr
+	}
+
+	if _, err := os.Stat(r.StorePath); err != nil {
+		r.storeErr = fmt.Errorf("no secret store at %s (add secrets with 'mcpenetes secret set')", r.StorePath)
+		return nil, r.storeErr
+	}
+	secret, err := KeyMaterial("", false)
+	if err == nil {
+		r.store, err = OpenStore(r.StorePath, secret)
+	}
+	r.storeErr = err
+	return r.store, r.storeErr
+}
+
+// runCommand runs command through the shell once and caches its trimmed output.
+func (r *Resolver) runCommand(command string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if result, ok := r.commands[command]; ok {
+		return result.output, result.err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), CommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+
+	var result commandResult
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		result.err = fmt.Errorf("command failed: %w", err)
+	} else {
+		result.output = strings.TrimRight(string(output), "\r\n")
+	}
+	if r.commands == nil {
+		r.commands = make(map[string]commandResult)
+	}
+	r.commands[command] = result
+	return result.output, result.err
+}
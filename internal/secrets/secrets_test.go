@@ -0,0 +1,117 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("file-secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+	dotenv := "# comment\nexport API_KEY=\"dotenv-secret\"\nOTHER=x\n"
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(dotenv), 0600); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+	t.Setenv("MCPENETES_TEST_TOKEN", "env-secret")
+
+	r := NewResolver(dir)
+	r.AllowCommands = true
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"plain", "plain"},
+		{"${env:MCPENETES_TEST_TOKEN}", "env-secret"},
+		{"Bearer ${env:MCPENETES_TEST_TOKEN}", "Bearer env-secret"},
+		{"${file:token}", "file-secret"},
+		{"${file:" + filepath.Join(dir, "token") + "}", "file-secret"},
+		{"${dotenv:.env:API_KEY}", "dotenv-secret"},
+		{"$${env:MCPENETES_TEST_TOKEN}", "${env:MCPENETES_TEST_TOKEN}"},
+		{"${workspaceFolder}", "${workspaceFolder}"},
+		{"${input:token}", "${input:token}"},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			value    string
+			expected string
+		}{"${cmd:echo cmd-secret}", "cmd-secret"})
+	}
+
+	for _, tt := range tests {
+		got, err := r.Resolve(tt.value)
+		if err != nil {
+			t.Errorf("Resolve(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("Resolve(%q) = %q, expected %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("A=1\n"), 0600); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+
+	r := NewResolver(dir)
+	r.AllowCommands = true
+	tests := []struct {
+		value   string
+		message string
+	}{
+		{"${env:MCPENETES_TEST_UNSET}", "environment variable MCPENETES_TEST_UNSET is not set"},
+		{"${file:missing}", "no such file"},
+		{"${dotenv:.env:B}", "B is not set"},
+		{"${dotenv:.env}", "expected ${dotenv:PATH:KEY}"},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			value   string
+			message string
+		}{"${cmd:echo oops >&2; exit 3}", "oops"})
+	}
+
+	for _, tt := range tests {
+		_, err := r.Resolve(tt.value)
+		if err == nil {
+			t.Errorf("Resolve(%q) succeeded, expected an error", tt.value)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("Resolve(%q) error %q does not mention %q", tt.value, err, tt.message)
+		}
+	}
+}
+
+func TestResolveCommandNotAllowed(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "ran")
+	r := NewResolver(t.TempDir())
+
+	_, err := r.Resolve("${cmd:touch " + marker + "}")
+	if !errors.Is(err, ErrCommandNotAllowed) {
+		t.Errorf("Expected ErrCommandNotAllowed, got %v", err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("Expected the command not to run")
+	}
+	if !HasCommand("Bearer ${cmd:pass show gh}") || HasCommand("$${cmd:x} ${env:A}") {
+		t.Errorf("HasCommand did not find exactly the unescaped ${cmd:...} reference")
+	}
+}
+
+func TestHasReference(t *testing.T) {
+	if !HasReference("x ${env:A} y") {
+		t.Errorf("Expected a reference to be found")
+	}
+	if HasReference("$${env:A} ${workspaceFolder}") {
+		t.Errorf("Expected escaped and non-secret variables to be ignored")
+	}
+}
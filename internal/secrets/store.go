@@ -0,0 +1,269 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultStoreFileName is the name of the encrypted secret store in the config directory.
+const DefaultStoreFileName = "secrets.enc"
+
+// Environment variables providing the store's key material
+const (
+	PassphraseEnv = "MCPENETES_SECRET_PASSPHRASE"
+	KeyFileEnv    = "MCPENETES_SECRET_KEY_FILE"
+)
+
+const (
+	storeVersion = 1
+	storeKDF     = "pbkdf2-sha256"
+	saltSize     = 16
+	keySize      = 32 // AES-256
+)
+
+// kdfIterations is the PBKDF2 iteration count used when a store is written.
+var kdfIterations = 600000
+
+// maxKDFIterationFactor bounds the iteration count read from a store to this multiple of
+// kdfIterations, so an edited store can't make opening it take hours.
+const maxKDFIterationFactor = 10
+
+// ErrDecrypt is returned (wrapped) when the store cannot be decrypted with the given key material.
+var ErrDecrypt = errors.New("failed to decrypt secret store (wrong passphrase or key file?)")
+
+// PromptPassphrase, when set, is called for the passphrase if neither MCPENETES_SECRET_KEY_FILE
+// nor MCPENETES_SECRET_PASSPHRASE is set. confirm is true when the store is being created.
+var PromptPassphrase func(confirm bool) (string, error)
+
+// storeFile is the on-disk format of the store. Only Ciphertext holds secrets; the
+// other fields are authenticated as additional data.
+type storeFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Store is the decrypted content of an encrypted secret store file.
+type Store struct {
+	path    string
+	secret  string
+	entries map[string]string
+}
+
+// KeyMaterial returns the passphrase or key file content protecting the store, read from
+// MCPENETES_SECRET_KEY_FILE, MCPENETES_SECRET_PASSPHRASE or PromptPassphrase, in that order.
+// keyFile, if not empty, takes precedence over all of them.
+func KeyMaterial(keyFile string, creating bool) (string, error) {
+	if keyFile == "" {
+		keyFile = os.Getenv(KeyFileEnv)
+	}
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read key file: %w", err)
+		}
+		if len(data) == 0 {
+			return "", fmt.Errorf("key file '%s' is empty", keyFile)
+		}
+		return string(data), nil
+	}
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if PromptPassphrase != nil {
+		passphrase, err := PromptPassphrase(creating)
+		if err != nil {
+			return "", err
+		}
+		if passphrase == "" {
+			return "", errors.New("the passphrase must not be empty")
+		}
+		return passphrase, nil
+	}
+	return "", fmt.Errorf("the secret store is locked; set %s or %s", PassphraseEnv, KeyFileEnv)
+}
+
+// OpenStore decrypts the store at path with secret, a passphrase or key file content.
+// A missing file opens as an empty store that is created on Save.
+func OpenStore(path, secret string) (*Store, error) {
+	store := &Store{path: path, secret: secret, entries: make(map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read secret store '%s': %w", path, err)
+	}
+
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse secret store '%s': %w", path, err)
+	}
+	if file.Version != storeVersion || file.KDF != storeKDF {
+		return nil, fmt.Errorf("unsupported secret store '%s' (version %d, kdf %q)", path, file.Version, file.KDF)
+	}
+	salt, err := base64.StdEncoding.DecodeString(file.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt in secret store '%s': %w", path, err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(file.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce in secret store '%s': %w", path, err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(file.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext in secret store '%s': %w", path, err)
+	}
+
+	aead, err := newAEAD(secret, salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce in secret store '%s'", path)
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData(file))
+	if err != nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrDecrypt, path)
+	}
+	if err := json.Unmarshal(plaintext, &store.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse decrypted secret store '%s': %w", path, err)
+	}
+	if store.entries == nil {
+		store.entries = make(map[string]string)
+	}
+	return store, nil
+}
+
+// Exists reports whether the store file exists.
+func (s *Store) Exists() bool {
+	_, err := os.Stat(s.path)
+	return err == nil
+}
+
+// Get returns the value of a secret.
+func (s *Store) Get(name string) (string, bool) {
+	value, ok := s.entries[name]
+	return value, ok
+}
+
+// Set adds or replaces a secret. Call Save to persist it.
+func (s *Store) Set(name, value string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	s.entries[name] = value
+	return nil
+}
+
+// Delete removes a secret and reports whether it existed. Call Save to persist it.
+func (s *Store) Delete(name string) bool {
+	_, ok := s.entries[name]
+	delete(s.entries, name)
+	return ok
+}
+
+// Names returns the sorted names of all secrets.
+func (s *Store) Names() []string {
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save encrypts the store with a fresh salt and nonce and writes it atomically.
+func (s *Store) Save() error {
+	plaintext, err := json.Marshal(s.entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal secrets: %w", err)
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	file := storeFile{
+		Version:    storeVersion,
+		KDF:        storeKDF,
+		Iterations: kdfIterations,
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+	}
+	aead, err := newAEAD(s.secret, salt, file.Iterations)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	file.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	file.Ciphertext = base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, plaintext, additionalData(file)))
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal secret store: %w", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", dir, err)
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write secret store '%s': %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace secret store '%s': %w", s.path, err)
+	}
+	return nil
+}
+
+// ValidateName checks that a secret name can be used in a ${secret:NAME} reference.
+func ValidateName(name string) error {
+	if name == "" {
+		return errors.New("secret name must not be empty")
+	}
+	if strings.ContainsAny(name, "}:$ \t\r\n") {
+		return fmt.Errorf("invalid secret name %q: it must not contain whitespace, ':', '$' or '}'", name)
+	}
+	return nil
+}
+
+// newAEAD derives the AES-256-GCM cipher from the secret.
+func newAEAD(secret string, salt []byte, iterations int) (cipher.AEAD, error) {
+	if iterations < kdfIterations || iterations > maxKDFIterationFactor*kdfIterations {
+		return nil, fmt.Errorf("unsupported iteration count %d (expected %d to %d)", iterations, kdfIterations, maxKDFIterationFactor*kdfIterations)
+	}
+	key, err := pbkdf2.Key(sha256.New, secret, salt, iterations, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the store's parameters to the ciphertext.
+func additionalData(file storeFile) []byte {
+	return []byte(fmt.Sprintf("mcpenetes-secrets:%d:%s:%d:%s", file.Version, file.KDF, file.Iterations, file.Salt))
+}
@@ -0,0 +1,133 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	kdfIterations = 1000 // Keep the test fast
+	storePath := filepath.Join(t.TempDir(), DefaultStoreFileName)
+
+	store, err := OpenStore(storePath, "correct horse")
+	if err != nil {
+		t.Fatalf("OpenStore failed for a new store: %v", err)
+	}
+	if store.Exists() {
+		t.Errorf("Expected a new store not to exist before Save")
+	}
+	if err := store.Set("GITHUB_TOKEN", "ghp_secret"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := store.Set("OTHER", "x"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	data, err := os.ReadFile(storePath)
+	if err != nil {
+		t.Fatalf("Failed to read store: %v", err)
+	}
+	if strings.Contains(string(data), "ghp_secret") || strings.Contains(string(data), "GITHUB_TOKEN") {
+		t.Errorf("The store file contains a secret in plain text")
+	}
+	if info, err := os.Stat(storePath); err == nil && info.Mode().Perm()&0077 != 0 {
+		t.Errorf("Expected the store to be readable by its owner only, got %v", info.Mode().Perm())
+	}
+
+	reopened, err := OpenStore(storePath, "correct horse")
+	if err != nil {
+		t.Fatalf("OpenStore failed: %v", err)
+	}
+	if value, ok := reopened.Get("GITHUB_TOKEN"); !ok || value != "ghp_secret" {
+		t.Errorf("Get returned %q, %v", value, ok)
+	}
+	if !reopened.Delete("OTHER") || reopened.Delete("OTHER") {
+		t.Errorf("Delete should report whether the secret existed")
+	}
+	if names := reopened.Names(); len(names) != 1 || names[0] != "GITHUB_TOKEN" {
+		t.Errorf("Unexpected names %v", names)
+	}
+
+	if _, err := OpenStore(storePath, "wrong"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt for a wrong passphrase, got %v", err)
+	}
+}
+
+func TestStoreTampered(t *testing.T) {
+	kdfIterations = 1000
+	storePath := filepath.Join(t.TempDir(), DefaultStoreFileName)
+
+	store, _ := OpenStore(storePath, "pass")
+	_ = store.Set("A", "1")
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Changing an authenticated parameter must make decryption fail
+	data, _ := os.ReadFile(storePath)
+	tampered := strings.Replace(string(data), `"iterations": 1000`, `"iterations": 1001`, 1)
+	if err := os.WriteFile(storePath, []byte(tampered), 0600); err != nil {
+		t.Fatalf("Failed to write store: %v", err)
+	}
+	if _, err := OpenStore(storePath, "pass"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt for a tampered store, got %v", err)
+	}
+
+	// Iteration counts outside the supported range are rejected before deriving a key
+	for _, iterations := range []string{"0", "999", "10001", "2000000000"} {
+		outOfRange := strings.Replace(string(data), `"iterations": 1000`, `"iterations": `+iterations, 1)
+		if err := os.WriteFile(storePath, []byte(outOfRange), 0600); err != nil {
+			t.Fatalf("Failed to write store: %v", err)
+		}
+		if _, err := OpenStore(storePath, "pass"); err == nil || !strings.Contains(err.Error(), "unsupported iteration count") {
+			t.Errorf("Expected %s iterations to be rejected, got %v", iterations, err)
+		}
+	}
+}
+
+func TestResolveSecretReference(t *testing.T) {
+	kdfIterations = 1000
+	dir := t.TempDir()
+	store, _ := OpenStore(filepath.Join(dir, DefaultStoreFileName), "pass")
+	_ = store.Set("API_KEY", "from-store")
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	t.Setenv(KeyFileEnv, "")
+	t.Setenv(PassphraseEnv, "pass")
+	r := NewResolver(dir)
+	got, err := r.Resolve("Bearer ${secret:API_KEY}")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if got != "Bearer from-store" {
+		t.Errorf("Resolve returned %q", got)
+	}
+	if _, err := r.Resolve("${secret:MISSING}"); err == nil || !strings.Contains(err.Error(), "secret set MISSING") {
+		t.Errorf("Expected a missing secret to explain how to add it, got %v", err)
+	}
+}
+
+func TestKeyMaterialFromKeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte("key-file-content"), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	t.Setenv(PassphraseEnv, "ignored")
+	t.Setenv(KeyFileEnv, keyFile)
+
+	secret, err := KeyMaterial("", false)
+	if err != nil {
+		t.Fatalf("KeyMaterial failed: %v", err)
+	}
+	if secret != "key-file-content" {
+		t.Errorf("Expected the key file to take precedence over the passphrase, got %q", secret)
+	}
+}
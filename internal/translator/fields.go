@@ -0,0 +1,97 @@
+package translator
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tuannvm/mcpenetes/internal/config"
+)
+
+// mapServerStrings returns a copy of serverConf with fn applied to its command, url, args,
+// env values and the strings in its passthrough fields (e.g. headers). Errors are prefixed
+// with the path of the failing value, such as env.TOKEN.
+func mapServerStrings(serverConf config.MCPServer, fn func(value string) (string, error)) (config.MCPServer, error) {
+	var err error
+	mapped := serverConf
+	if mapped.Command, err = fn(serverConf.Command); err != nil {
+		return config.MCPServer{}, fmt.Errorf("command: %w", err)
+	}
+	if mapped.URL, err = fn(serverConf.URL); err != nil {
+		return config.MCPServer{}, fmt.Errorf("url: %w", err)
+	}
+
+	if serverConf.Args != nil {
+		mapped.Args = make([]string, len(serverConf.Args))
+		for i, arg := range serverConf.Args {
+			if mapped.Args[i], err = fn(arg); err != nil {
+				return config.MCPServer{}, fmt.Errorf("args[%d]: %w", i, err)
+			}
+		}
+	}
+
+	if serverConf.Env != nil {
+		mapped.Env = make(map[string]string, len(serverConf.Env))
+		keys := make([]string, 0, len(serverConf.Env))
+		for key := range serverConf.Env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys) // Report the same error every time
+		for _, key := range keys {
+			if mapped.Env[key], err = fn(serverConf.Env[key]); err != nil {
+				return config.MCPServer{}, fmt.Errorf("env.%s: %w", key, err)
+			}
+		}
+	}
+
+	if serverConf.Extras != nil {
+		extras, err := mapValueStrings(serverConf.Extras, "", fn)
+		if err != nil {
+			return config.MCPServer{}, err
+		}
+		mapped.Extras = extras.(map[string]interface{})
+	}
+	return mapped, nil
+}
+
+// mapValueStrings applies fn to the strings of a decoded JSON value, returning a copy.
+// path names the value in error messages.
+func mapValueStrings(value interface{}, path string, fn func(value string) (string, error)) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		mapped, err := fn(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return mapped, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		mapped := make(map[string]interface{}, len(v))
+		for _, key := range keys {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			child, err := mapValueStrings(v[key], childPath, fn)
+			if err != nil {
+				return nil, err
+			}
+			mapped[key] = child
+		}
+		return mapped, nil
+	case []interface{}:
+		mapped := make([]interface{}, len(v))
+		for i, item := range v {
+			child, err := mapValueStrings(item, fmt.Sprintf("%s[%d]", path, i), fn)
+			if err != nil {
+				return nil, err
+			}
+			mapped[i] = child
+		}
+		return mapped, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,183 @@
+package translator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/util"
+)
+
+// ErrConflict is returned (wrapped) when a client's config file changed on disk between
+// being read and being written, e.g. because the client rewrote its own config meanwhile.
+var ErrConflict = errors.New("client config changed on disk since it was read")
+
+// MaxConflictRetries is how many times a write re-merges against a client config that changed on disk.
+const MaxConflictRetries = 3
+
+// ClientRender holds a client's current config file content and the content apply would write.
+// CurrentHash identifies the content the render was computed from. Skipped holds the servers
+// whose secret references or template variables could not be resolved; their entries in the
+// client are left as they are.
+type ClientRender struct {
+	ClientName  string
+	Path        string
+	Exists      bool
+	Current     []byte
+	CurrentHash string
+	Rendered    []byte
+	Skipped     map[string]error
+}
+
+// Changed reports whether applying the render would modify the client's config file.
+func (r *ClientRender) Changed() bool {
+	return !r.Exists || string(r.Current) != string(r.Rendered)
+}
+
+// RenderClientConfig computes, in memory, the config file apply would write for a client:
+// every server from mcp.json merged into the current content, minus obsolete servers.
+func (t *Translator) RenderClientConfig(clientName string, clientConf config.Client) (*ClientRender, error) {
+	clientConfigPath, err := util.ExpandPath(clientConf.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand client config path '%s' for %s: %w", clientConf.ConfigPath, clientName, err)
+	}
+
+	render := &ClientRender{ClientName: clientName, Path: clientConfigPath}
+	if err := t.Rerender(render); err != nil {
+		return nil, err
+	}
+	return render, nil
+}
+
+// Rerender reads the client's config file again and recomputes render from its current content.
+func (t *Translator) Rerender(render *ClientRender) error {
+	current, exists, err := readClientFile(render.Path)
+	if err != nil {
+		return err
+	}
+
+	rendered, skipped, err := t.renderContent(render.ClientName, render.Path, current)
+	if err != nil {
+		return err
+	}
+	render.Exists = exists
+	render.Current = current
+	render.CurrentHash = contentHash(current, exists)
+	render.Rendered = rendered
+	render.Skipped = skipped
+	return nil
+}
+
+// readClientFile reads a client config file, reporting whether it exists.
+func readClientFile(path string) ([]byte, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to read client config file '%s': %w", path, err)
+	}
+	return data, true, nil
+}
+
+// contentHash returns a hash of a file's content; a missing file hashes differently from an empty one.
+func contentHash(data []byte, exists bool) string {
+	if !exists {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkUnchanged returns an error wrapping ErrConflict if the file at path no longer has the given hash.
+func checkUnchanged(path, hash string) error {
+	data, exists, err := readClientFile(path)
+	if err != nil {
+		return err
+	}
+	if contentHash(data, exists) != hash {
+		return fmt.Errorf("%w: '%s'", ErrConflict, path)
+	}
+	return nil
+}
+
+// renderContent applies every server and removes obsolete ones from the given content.
+// Servers whose secret references or template variables fail to resolve are skipped and
+// returned with their errors.
+func (t *Translator) renderContent(clientName, clientConfigPath string, content []byte) ([]byte, map[string]error, error) {
+	serverIDs := make([]string, 0, len(t.MCPConfig.MCPServers))
+	for serverID := range t.MCPConfig.MCPServers {
+		serverIDs = append(serverIDs, serverID)
+	}
+	sort.Strings(serverIDs)
+
+	var skipped map[string]error
+	for _, serverID := range serverIDs {
+		serverConf, err := t.prepareServer(clientName, serverID, t.MCPConfig.MCPServers[serverID])
+		if err != nil {
+			if skipped == nil {
+				skipped = make(map[string]error)
+			}
+			skipped[serverID] = err
+			continue
+		}
+
+		content, err = t.translateServer(clientName, clientConfigPath, content, serverID, serverConf)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	content, _, err := t.removeServers(clientName, clientConfigPath, content)
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, skipped, nil
+}
+
+// WriteClientConfig writes a rendered config to the client's config path. It fails with an
+// error wrapping ErrConflict, without writing, if the file changed since the render read it.
+func (t *Translator) WriteClientConfig(render *ClientRender) error {
+	if err := checkUnchanged(render.Path, render.CurrentHash); err != nil {
+		return err
+	}
+
+	// Ensure the target directory exists
+	clientConfigDir := filepath.Dir(render.Path)
+	if err := os.MkdirAll(clientConfigDir, 0750); err != nil {
+		return fmt.Errorf("failed to create directory '%s' for client %s: %w", clientConfigDir, render.ClientName, err)
+	}
+
+	if err := os.WriteFile(render.Path, render.Rendered, clientFileMode); err != nil {
+		return fmt.Errorf("failed to write config file '%s' for client %s: %w", render.Path, render.ClientName, err)
+	}
+	return nil
+}
+
+// RestoreClientConfig undoes a write of render. The client's config is copied back from
+// backupPath, or removed if it did not exist before the write (empty backupPath).
+func (t *Translator) RestoreClientConfig(render *ClientRender, backupPath string) error {
+	if backupPath == "" {
+		if render.Exists {
+			return fmt.Errorf("no backup available to restore '%s' for client %s", render.Path, render.ClientName)
+		}
+		if err := os.Remove(render.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove config file '%s' for client %s: %w", render.Path, render.ClientName, err)
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to read backup file '%s': %w", backupPath, err)
+	}
+	if err := os.WriteFile(render.Path, data, clientFileMode); err != nil {
+		return fmt.Errorf("failed to restore config file '%s' for client %s: %w", render.Path, render.ClientName, err)
+	}
+	return nil
+}
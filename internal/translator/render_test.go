@@ -0,0 +1,335 @@
+package translator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/secrets"
+)
+
+func TestWriteClientConfigConflict(t *testing.T) {
+	tempDir := t.TempDir()
+	clientPath := filepath.Join(tempDir, "mcp.json")
+	if err := os.WriteFile(clientPath, []byte(`{"mcpServers": {}}`), 0600); err != nil {
+		t.Fatalf("Failed to write client config: %v", err)
+	}
+
+	mcpCfg := &config.MCPConfig{MCPServers: map[string]config.MCPServer{
+		"fetch": {Command: "uvx", Args: []string{"mcp-server-fetch"}},
+	}}
+	trans := NewTranslator(config.GetDefaultConfig(), mcpCfg)
+
+	render, err := trans.RenderClientConfig("cursor", config.Client{ConfigPath: clientPath})
+	if err != nil {
+		t.Fatalf("RenderClientConfig failed: %v", err)
+	}
+
+	// The client rewrites its own config after the render read it
+	clientEdit := `{"mcpServers": {}, "theme": "dark"}`
+	if err := os.WriteFile(clientPath, []byte(clientEdit), 0600); err != nil {
+		t.Fatalf("Failed to rewrite client config: %v", err)
+	}
+
+	err = trans.WriteClientConfig(render)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+	data, _ := os.ReadFile(clientPath)
+	if string(data) != clientEdit {
+		t.Errorf("WriteClientConfig overwrote a config that changed on disk")
+	}
+
+	// Merging again against the new content keeps the client's change
+	if err := trans.Rerender(render); err != nil {
+		t.Fatalf("Rerender failed: %v", err)
+	}
+	if err := trans.WriteClientConfig(render); err != nil {
+		t.Fatalf("WriteClientConfig failed after Rerender: %v", err)
+	}
+	data, _ = os.ReadFile(clientPath)
+	if !strings.Contains(string(data), `"theme": "dark"`) || !strings.Contains(string(data), "mcp-server-fetch") {
+		t.Errorf("Expected the client's change and the server to both be written, got:\n%s", data)
+	}
+}
+
+func TestWriteClientConfigConflictOnCreate(t *testing.T) {
+	clientPath := filepath.Join(t.TempDir(), "mcp.json")
+	mcpCfg := &config.MCPConfig{MCPServers: map[string]config.MCPServer{
+		"fetch": {Command: "uvx"},
+	}}
+	trans := NewTranslator(config.GetDefaultConfig(), mcpCfg)
+
+	render, err := trans.RenderClientConfig("cursor", config.Client{ConfigPath: clientPath})
+	if err != nil {
+		t.Fatalf("RenderClientConfig failed: %v", err)
+	}
+
+	// A file that appears after the render must not be clobbered either, even if empty
+	if err := os.WriteFile(clientPath, nil, 0600); err != nil {
+		t.Fatalf("Failed to create client config: %v", err)
+	}
+	if err := trans.WriteClientConfig(render); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected ErrConflict, got %v", err)
+	}
+}
+
+func TestClientFileModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on Windows")
+	}
+	tempDir := t.TempDir()
+	mcpCfg := &config.MCPConfig{MCPServers: map[string]config.MCPServer{
+		"api": {Command: "api-server", Env: map[string]string{"API_KEY": "resolved-secret"}},
+	}}
+	appCfg := config.GetDefaultConfig()
+	appCfg.Backups.Path = filepath.Join(tempDir, "backups")
+	trans := NewTranslator(appCfg, mcpCfg)
+	mode := func(path string) os.FileMode {
+		t.Helper()
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Failed to stat '%s': %v", path, err)
+		}
+		return info.Mode().Perm()
+	}
+
+	// A new client config may hold resolved secrets, so only its owner can read it
+	newPath := filepath.Join(tempDir, "new", "mcp.json")
+	render, err := trans.RenderClientConfig("cursor", config.Client{ConfigPath: newPath})
+	if err != nil {
+		t.Fatalf("RenderClientConfig failed: %v", err)
+	}
+	if err := trans.WriteClientConfig(render); err != nil {
+		t.Fatalf("WriteClientConfig failed: %v", err)
+	}
+	if got := mode(newPath); got != 0600 {
+		t.Errorf("Expected a new client config to be created with 0600, got %v", got)
+	}
+
+	// An existing client config keeps the mode its owner chose, and so does a restore
+	existingPath := filepath.Join(tempDir, "existing.json")
+	if err := os.WriteFile(existingPath, []byte(`{"mcpServers": {}}`), 0600); err != nil {
+		t.Fatalf("Failed to write client config: %v", err)
+	}
+	if err := os.Chmod(existingPath, 0640); err != nil {
+		t.Fatalf("Failed to chmod client config: %v", err)
+	}
+	backupPath, err := trans.BackupClientConfig("vscode", config.Client{ConfigPath: existingPath})
+	if err != nil {
+		t.Fatalf("BackupClientConfig failed: %v", err)
+	}
+	if got := mode(backupPath); got != 0600 {
+		t.Errorf("Expected the backup to be created with 0600, got %v", got)
+	}
+	render, err = trans.RenderClientConfig("vscode", config.Client{ConfigPath: existingPath})
+	if err != nil {
+		t.Fatalf("RenderClientConfig failed: %v", err)
+	}
+	if err := trans.WriteClientConfig(render); err != nil {
+		t.Fatalf("WriteClientConfig failed: %v", err)
+	}
+	if err := trans.RestoreClientConfig(render, backupPath); err != nil {
+		t.Fatalf("RestoreClientConfig failed: %v", err)
+	}
+	if got := mode(existingPath); got != 0640 {
+		t.Errorf("Expected the existing client config to keep 0640, got %v", got)
+	}
+}
+
+func TestRenderSkipsUnresolvedServers(t *testing.T) {
+	clientPath := filepath.Join(t.TempDir(), "mcp.json")
+	existing := `{"mcpServers": {"github": {"command": "npx", "env": {"TOKEN": "old"}}}}`
+	if err := os.WriteFile(clientPath, []byte(existing), 0600); err != nil {
+		t.Fatalf("Failed to write client config: %v", err)
+	}
+	t.Setenv("MCPENETES_TEST_FETCH_KEY", "resolved-key")
+
+	mcpCfg := &config.MCPConfig{MCPServers: map[string]config.MCPServer{
+		"fetch":  {Command: "uvx", Env: map[string]string{"KEY": "${env:MCPENETES_TEST_FETCH_KEY}"}},
+		"github": {Command: "npx", Env: map[string]string{"TOKEN": "${env:MCPENETES_TEST_UNSET}"}},
+	}}
+	trans := NewTranslator(config.GetDefaultConfig(), mcpCfg)
+
+	render, err := trans.RenderClientConfig("cursor", config.Client{ConfigPath: clientPath})
+	if err != nil {
+		t.Fatalf("RenderClientConfig failed: %v", err)
+	}
+
+	if err := render.Skipped["github"]; err == nil || !strings.Contains(err.Error(), "env.TOKEN") {
+		t.Errorf("Expected github to be skipped with an error naming env.TOKEN, got %v", err)
+	}
+	if _, ok := render.Skipped["fetch"]; ok {
+		t.Errorf("Expected fetch to be rendered")
+	}
+
+	servers, err := parseClientServers("cursor", clientPath, render.Rendered)
+	if err != nil {
+		t.Fatalf("Failed to parse rendered config: %v", err)
+	}
+	if got := servers["fetch"].Env["KEY"]; got != "resolved-key" {
+		t.Errorf("Expected the rendered secret to be resolved, got %q", got)
+	}
+	if got := servers["github"].Env["TOKEN"]; got != "old" {
+		t.Errorf("Expected the skipped server to be left untouched, got %q", got)
+	}
+	if mcpCfg.MCPServers["fetch"].Env["KEY"] != "${env:MCPENETES_TEST_FETCH_KEY}" {
+		t.Errorf("Rendering must not modify the references in mcp.json")
+	}
+
+	statuses, err := ClientStatus(render)
+	if err != nil {
+		t.Fatalf("ClientStatus failed: %v", err)
+	}
+	for _, status := range statuses {
+		if status.Name == "github" && status.State != StateUnresolved {
+			t.Errorf("Expected github to be %s, got %s", StateUnresolved, status.State)
+		}
+	}
+}
+
+func TestRenderTemplatesPerClient(t *testing.T) {
+	dir := t.TempDir()
+	mcpCfg := &config.MCPConfig{MCPServers: map[string]config.MCPServer{
+		"files": {Command: "npx", Args: []string{"{{home}}/docs", "{{os}}"}, Env: map[string]string{"ROOT": "{{env.MCPENETES_TEST_ROOT}}"}},
+	}}
+	trans := NewTranslator(config.GetDefaultConfig(), mcpCfg)
+	trans.VarContext.Home = "/home/me"
+	trans.VarContext.OS = "linux"
+	t.Setenv("MCPENETES_TEST_ROOT", "/srv")
+
+	tests := []struct {
+		client string
+		args   []string
+		root   string
+	}{
+		{"vscode", []string{"${userHome}/docs", "linux"}, "${env:MCPENETES_TEST_ROOT}"},
+		{"cursor", []string{"${userHome}/docs", "linux"}, "${env:MCPENETES_TEST_ROOT}"},
+		{"claude-desktop", []string{"/home/me/docs", "linux"}, "/srv"},
+	}
+	for _, tt := range tests {
+		clientPath := filepath.Join(dir, tt.client+".json")
+		render, err := trans.RenderClientConfig(tt.client, config.Client{ConfigPath: clientPath})
+		if err != nil {
+			t.Fatalf("RenderClientConfig(%s) failed: %v", tt.client, err)
+		}
+		servers, err := parseClientServers(tt.client, clientPath, render.Rendered)
+		if err != nil {
+			t.Fatalf("Failed to parse rendered config for %s: %v", tt.client, err)
+		}
+		got := servers["files"]
+		if strings.Join(got.Args, " ") != strings.Join(tt.args, " ") || got.Env["ROOT"] != tt.root {
+			t.Errorf("%s: got args %v and ROOT %q, expected %v and %q", tt.client, got.Args, got.Env["ROOT"], tt.args, tt.root)
+		}
+	}
+
+	// Claude Desktop has no workspace folder, so the server is skipped
+	mcpCfg.MCPServers["files"] = config.MCPServer{Command: "npx", Args: []string{"{{workspace}}"}}
+	render, err := trans.RenderClientConfig("claude-desktop", config.Client{ConfigPath: filepath.Join(dir, "claude.json")})
+	if err != nil {
+		t.Fatalf("RenderClientConfig failed: %v", err)
+	}
+	if err := render.Skipped["files"]; err == nil || !strings.Contains(err.Error(), "args[0]") {
+		t.Errorf("Expected files to be skipped with an error naming args[0], got %v", err)
+	}
+}
+
+func TestRenderClientConfigDropsWhen(t *testing.T) {
+	clientPath := filepath.Join(t.TempDir(), "mcp.json")
+	mcpCfg := &config.MCPConfig{MCPServers: map[string]config.MCPServer{
+		"docker": {Command: "docker", When: &config.Condition{OS: config.StringList{"linux"}}},
+	}}
+	trans := NewTranslator(config.GetDefaultConfig(), mcpCfg)
+
+	render, err := trans.RenderClientConfig("cursor", config.Client{ConfigPath: clientPath})
+	if err != nil {
+		t.Fatalf("RenderClientConfig failed: %v", err)
+	}
+	if !strings.Contains(string(render.Rendered), `"docker"`) || strings.Contains(string(render.Rendered), "when") {
+		t.Errorf("Expected the server without its condition, got:\n%s", render.Rendered)
+	}
+}
+
+func TestRenderKeepsExcludedServers(t *testing.T) {
+	tempDir := t.TempDir()
+	jsonPath := filepath.Join(tempDir, "mcp.json")
+	if err := os.WriteFile(jsonPath, []byte(`{"mcpServers": {"docker": {"command": "my-docker"}, "stale": {"command": "old"}}}`), 0600); err != nil {
+		t.Fatalf("Failed to write client config: %v", err)
+	}
+	yamlPath := filepath.Join(tempDir, "servers.yaml")
+	if err := os.WriteFile(yamlPath, []byte("docker:\n  command: my-docker\nstale:\n  command: old\n"), 0600); err != nil {
+		t.Fatalf("Failed to write client config: %v", err)
+	}
+
+	// docker is excluded on this machine, so the client's own docker entry is not obsolete
+	mcpCfg := &config.MCPConfig{MCPServers: map[string]config.MCPServer{
+		"fetch": {Command: "uvx"},
+	}}
+	trans := NewTranslator(config.GetDefaultConfig(), mcpCfg)
+	trans.Excluded = map[string]string{"docker": "requires os linux, this is darwin"}
+
+	for client, path := range map[string]string{"cursor": jsonPath, "custom": yamlPath} {
+		render, err := trans.RenderClientConfig(client, config.Client{ConfigPath: path})
+		if err != nil {
+			t.Fatalf("RenderClientConfig(%s) failed: %v", path, err)
+		}
+		rendered := string(render.Rendered)
+		if !strings.Contains(rendered, "my-docker") || strings.Contains(rendered, "stale") {
+			t.Errorf("Expected docker kept and stale removed in %s, got:\n%s", path, rendered)
+		}
+
+		statuses, err := ClientStatus(render)
+		if err != nil {
+			t.Fatalf("ClientStatus(%s) failed: %v", path, err)
+		}
+		for _, status := range statuses {
+			if status.Name == "docker" && status.State != StateInSync {
+				t.Errorf("Expected the excluded server to be left in sync in %s, got %s", path, status.State)
+			}
+		}
+	}
+}
+
+func TestRenderCommandPolicy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	clientPath := filepath.Join(t.TempDir(), "mcp.json")
+	mcpCfg := &config.MCPConfig{MCPServers: map[string]config.MCPServer{
+		"mine":    {Command: "npx", Env: map[string]string{"TOKEN": "${cmd:echo mine}"}},
+		"project": {Command: "npx", Env: map[string]string{"TOKEN": "${cmd:echo project}"}},
+	}}
+	trans := NewTranslator(config.GetDefaultConfig(), mcpCfg)
+
+	// Without AllowCommands, no command runs
+	render, err := trans.RenderClientConfig("cursor", config.Client{ConfigPath: clientPath})
+	if err != nil {
+		t.Fatalf("RenderClientConfig failed: %v", err)
+	}
+	if len(render.Skipped) != 2 || !errors.Is(render.Skipped["mine"], secrets.ErrCommandNotAllowed) {
+		t.Errorf("Expected both servers to be skipped, got %v", render.Skipped)
+	}
+
+	// CommandPolicy narrows down which servers may run theirs
+	trans.Secrets.AllowCommands = true
+	trans.CommandPolicy = func(serverID string) error {
+		if serverID == "project" {
+			return errors.New("not trusted")
+		}
+		return nil
+	}
+	render, err = trans.RenderClientConfig("cursor", config.Client{ConfigPath: clientPath})
+	if err != nil {
+		t.Fatalf("RenderClientConfig failed: %v", err)
+	}
+	if err := render.Skipped["project"]; !errors.Is(err, secrets.ErrCommandNotAllowed) || !strings.Contains(err.Error(), "not trusted") {
+		t.Errorf("Expected project to be skipped with the policy's reason, got %v", err)
+	}
+	if _, ok := render.Skipped["mine"]; ok || !strings.Contains(string(render.Rendered), `"TOKEN": "mine"`) {
+		t.Errorf("Expected mine to be rendered with its command's output, got:\n%s", render.Rendered)
+	}
+}
@@ -0,0 +1,105 @@
+package translator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// ReadClientServers parses a client's native configuration file and translates
+// the MCP servers it defines back into the mcp.json representation.
+// A missing or empty file yields an empty map.
+func ReadClientServers(clientName string, clientConf config.Client) (map[string]config.MCPServer, error) {
+	clientConfigPath, err := util.ExpandPath(clientConf.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand client config path '%s' for %s: %w", clientConf.ConfigPath, clientName, err)
+	}
+
+	data, err := os.ReadFile(clientConfigPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]config.MCPServer{}, nil
+		}
+		return nil, fmt.Errorf("failed to read client config file '%s': %w", clientConfigPath, err)
+	}
+
+	return parseClientServers(clientName, clientConfigPath, data)
+}
+
+// parseClientServers extracts the server definitions from the raw content of a client config file.
+func parseClientServers(clientName, clientConfigPath string, data []byte) (map[string]config.MCPServer, error) {
+	servers := make(map[string]config.MCPServer)
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return servers, nil
+	}
+
+	format := strings.ToLower(filepath.Ext(clientConfigPath))
+	switch format {
+	case ".json":
+		var clientConfig map[string]interface{}
+		if err := json.Unmarshal(data, &clientConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse client JSON config file '%s': %w", clientConfigPath, err)
+		}
+
+		for serverID, entry := range clientServerEntries(clientName, clientConfig) {
+			// Round-trip through JSON so unknown keys end up in Extras
+			entryData, err := json.Marshal(entry)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode server '%s' from '%s': %w", serverID, clientConfigPath, err)
+			}
+			var server config.MCPServer
+			if err := json.Unmarshal(entryData, &server); err != nil {
+				return nil, fmt.Errorf("failed to decode server '%s' from '%s': %w", serverID, clientConfigPath, err)
+			}
+			servers[serverID] = server
+		}
+
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &servers); err != nil {
+			return nil, fmt.Errorf("failed to parse client YAML config file '%s': %w", clientConfigPath, err)
+		}
+
+	case ".toml":
+		if _, err := toml.Decode(string(data), &servers); err != nil {
+			return nil, fmt.Errorf("failed to parse client TOML config file '%s': %w", clientConfigPath, err)
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported config format '%s' for client %s", format, clientName)
+	}
+
+	return servers, nil
+}
+
+// clientServerEntries returns the raw server entries from a decoded client JSON document,
+// using the same layout TranslateAndApply writes for that client.
+func clientServerEntries(clientName string, clientConfig map[string]interface{}) map[string]interface{} {
+	entries := make(map[string]interface{})
+
+	var servers map[string]interface{}
+	if strings.Contains(clientName, "vscode") {
+		if mcpObj, ok := clientConfig["mcp"].(map[string]interface{}); ok {
+			servers, _ = mcpObj["servers"].(map[string]interface{})
+		}
+		if servers == nil {
+			// Dedicated VS Code mcp.json files keep servers at the top level
+			servers, _ = clientConfig["servers"].(map[string]interface{})
+		}
+	} else {
+		servers, _ = clientConfig["mcpServers"].(map[string]interface{})
+	}
+
+	for serverID, entry := range servers {
+		if _, ok := entry.(map[string]interface{}); ok {
+			entries[serverID] = entry
+		}
+	}
+	return entries
+}
@@ -0,0 +1,102 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tuannvm/mcpenetes/internal/config"
+)
+
+func TestReadClientServers(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testCases := []struct {
+		name       string
+		clientName string
+		fileName   string
+		content    string
+		expected   map[string]config.MCPServer
+	}{
+		{
+			name:       "Claude Desktop mcpServers",
+			clientName: "claude-desktop",
+			fileName:   "claude_desktop_config.json",
+			content:    `{"mcpServers": {"fs": {"command": "npx", "args": ["-y", "fs"], "autoApprove": []}}, "theme": "dark"}`,
+			expected: map[string]config.MCPServer{
+				"fs": {Command: "npx", Args: []string{"-y", "fs"}},
+			},
+		},
+		{
+			name:       "VS Code settings with extras",
+			clientName: "vscode",
+			fileName:   "settings.json",
+			content:    `{"editor.fontSize": 12, "mcp": {"inputs": [], "servers": {"remote": {"type": "http", "url": "https://example.com/mcp", "env": {}}}}}`,
+			expected: map[string]config.MCPServer{
+				"remote": {URL: "https://example.com/mcp", Extras: map[string]interface{}{"type": "http"}},
+			},
+		},
+		{
+			name:       "VS Code dedicated mcp.json",
+			clientName: "vscode",
+			fileName:   "mcp.json",
+			content:    `{"servers": {"git": {"command": "uvx", "args": ["mcp-server-git"]}}}`,
+			expected: map[string]config.MCPServer{
+				"git": {Command: "uvx", Args: []string{"mcp-server-git"}},
+			},
+		},
+		{
+			name:       "Empty file",
+			clientName: "cursor",
+			fileName:   "empty.json",
+			content:    "",
+			expected:   map[string]config.MCPServer{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, tc.clientName+"-"+tc.fileName)
+			if err := os.WriteFile(path, []byte(tc.content), 0600); err != nil {
+				t.Fatalf("Failed to write client config: %v", err)
+			}
+
+			servers, err := ReadClientServers(tc.clientName, config.Client{ConfigPath: path})
+			if err != nil {
+				t.Fatalf("ReadClientServers failed: %v", err)
+			}
+
+			if len(servers) != len(tc.expected) {
+				t.Fatalf("Expected %d servers, got %d: %+v", len(tc.expected), len(servers), servers)
+			}
+			for name, expected := range tc.expected {
+				got, ok := servers[name]
+				if !ok {
+					t.Errorf("Server '%s' not found", name)
+					continue
+				}
+				if !got.Equal(expected) {
+					t.Errorf("Server '%s' mismatch.\nExpected: %+v\nGot:      %+v", name, expected, got)
+				}
+			}
+		})
+	}
+
+	t.Run("Missing file", func(t *testing.T) {
+		servers, err := ReadClientServers("cursor", config.Client{ConfigPath: filepath.Join(tempDir, "missing.json")})
+		if err != nil {
+			t.Fatalf("ReadClientServers failed: %v", err)
+		}
+		if len(servers) != 0 {
+			t.Errorf("Expected no servers, got %+v", servers)
+		}
+	})
+
+	t.Run("Invalid JSON", func(t *testing.T) {
+		path := filepath.Join(tempDir, "invalid.json")
+		_ = os.WriteFile(path, []byte("{not json"), 0600)
+		if _, err := ReadClientServers("cursor", config.Client{ConfigPath: path}); err == nil {
+			t.Errorf("Expected error for invalid JSON, got nil")
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package translator
+
+import (
+	"fmt"
+
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/secrets"
+)
+
+// resolveServer returns a copy of serverConf with the secret references in its command,
+// args, url, env and passthrough fields (e.g. headers) replaced by their values.
+// mcp.json itself keeps the references; only rendered client configs contain the secrets.
+// ${cmd:...} references fail without running if CommandPolicy refuses them for serverID.
+func (t *Translator) resolveServer(serverID string, serverConf config.MCPServer) (config.MCPServer, error) {
+	if t.Secrets == nil {
+		return serverConf, nil
+	}
+	resolve := t.Secrets.Resolve
+	if t.CommandPolicy != nil {
+		if err := t.CommandPolicy(serverID); err != nil {
+			refused := fmt.Errorf("%w: %w", secrets.ErrCommandNotAllowed, err)
+			resolve = func(value string) (string, error) {
+				if secrets.HasCommand(value) {
+					return "", refused
+				}
+				return t.Secrets.Resolve(value)
+			}
+		}
+	}
+	return mapServerStrings(serverConf, resolve)
+}
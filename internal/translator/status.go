@@ -0,0 +1,166 @@
+package translator
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SyncState describes how a server entry in a client compares to mcp.json.
+type SyncState string
+
+const (
+	// StateInSync means the client entry matches what apply would write.
+	StateInSync SyncState = "in-sync"
+	// StateMissing means the server is in mcp.json but not in the client.
+	StateMissing SyncState = "missing"
+	// StateModified means the client entry differs from what apply would write.
+	StateModified SyncState = "modified"
+	// StateForeign means the client has a server that is not in mcp.json.
+	StateForeign SyncState = "foreign"
+	// StateUnresolved means a secret reference or template variable of the server could not be resolved, so apply leaves it alone.
+	StateUnresolved SyncState = "unresolved"
+)
+
+// ServerStatus is the sync state of a single server in a single client.
+type ServerStatus struct {
+	Name    string
+	State   SyncState
+	Current map[string]interface{} // Entry in the client file, nil when missing
+	Desired map[string]interface{} // Entry apply would write, nil when foreign
+}
+
+// FieldDiff is a single field-level difference between a client entry and mcp.json.
+type FieldDiff struct {
+	Path    string
+	Current interface{}
+	Desired interface{}
+}
+
+// ClientStatus compares the servers in a client's current config with the ones apply would write.
+// The result is sorted by server name.
+func ClientStatus(render *ClientRender) ([]ServerStatus, error) {
+	current, err := serverEntries(render.ClientName, render.Path, render.Current)
+	if err != nil {
+		return nil, err
+	}
+	desired, err := serverEntries(render.ClientName, render.Path, render.Rendered)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for name := range current {
+		names[name] = true
+	}
+	for name := range desired {
+		names[name] = true
+	}
+	for name := range render.Skipped {
+		names[name] = true
+	}
+
+	statuses := make([]ServerStatus, 0, len(names))
+	for name := range names {
+		status := ServerStatus{Name: name, Current: current[name], Desired: desired[name]}
+		switch {
+		case render.Skipped[name] != nil:
+			status.State = StateUnresolved
+		case status.Current == nil:
+			status.State = StateMissing
+		case status.Desired == nil:
+			status.State = StateForeign
+		case reflect.DeepEqual(status.Current, status.Desired):
+			status.State = StateInSync
+		default:
+			status.State = StateModified
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+// DiffFields returns the field-level differences between two server entries, sorted by path.
+// Nested objects such as env are compared key by key; lists are compared as a whole.
+func DiffFields(current, desired map[string]interface{}) []FieldDiff {
+	var diffs []FieldDiff
+	diffMaps("", current, desired, &diffs)
+	return diffs
+}
+
+// diffMaps appends the differences between two decoded JSON objects to diffs.
+func diffMaps(prefix string, current, desired map[string]interface{}, diffs *[]FieldDiff) {
+	keys := make(map[string]bool)
+	for key := range current {
+		keys[key] = true
+	}
+	for key := range desired {
+		keys[key] = true
+	}
+
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	for _, key := range sortedKeys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		currentValue, desiredValue := current[key], desired[key]
+		currentMap, currentIsMap := currentValue.(map[string]interface{})
+		desiredMap, desiredIsMap := desiredValue.(map[string]interface{})
+		if currentIsMap && desiredIsMap {
+			diffMaps(path, currentMap, desiredMap, diffs)
+			continue
+		}
+		if !reflect.DeepEqual(currentValue, desiredValue) {
+			*diffs = append(*diffs, FieldDiff{Path: path, Current: currentValue, Desired: desiredValue})
+		}
+	}
+}
+
+// serverEntries decodes the server entries of a client config file into generic JSON objects.
+func serverEntries(clientName, clientConfigPath string, data []byte) (map[string]map[string]interface{}, error) {
+	entries := make(map[string]map[string]interface{})
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return entries, nil
+	}
+
+	if strings.ToLower(filepath.Ext(clientConfigPath)) == ".json" {
+		var clientConfig map[string]interface{}
+		if err := json.Unmarshal(data, &clientConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse client JSON config file '%s': %w", clientConfigPath, err)
+		}
+		for serverID, entry := range clientServerEntries(clientName, clientConfig) {
+			entries[serverID] = entry.(map[string]interface{})
+		}
+		return entries, nil
+	}
+
+	// YAML and TOML clients store typed servers; compare them in their mcp.json form
+	servers, err := parseClientServers(clientName, clientConfigPath, data)
+	if err != nil {
+		return nil, err
+	}
+	for serverID, server := range servers {
+		encoded, err := json.Marshal(server)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode server '%s' from '%s': %w", serverID, clientConfigPath, err)
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal(encoded, &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode server '%s' from '%s': %w", serverID, clientConfigPath, err)
+		}
+		entries[serverID] = entry
+	}
+	return entries, nil
+}
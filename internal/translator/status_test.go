@@ -0,0 +1,100 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tuannvm/mcpenetes/internal/config"
+)
+
+func TestRenderAndClientStatus(t *testing.T) {
+	tempDir := t.TempDir()
+	clientPath := filepath.Join(tempDir, "mcp.json")
+	existing := `{
+  "mcpServers": {
+    "fetch": {"command": "uvx", "args": ["mcp-server-fetch"]},
+    "github": {"command": "npx", "args": ["-y", "github"], "env": {"TOKEN": "old"}},
+    "legacy": {"command": "legacy-server"}
+  },
+  "otherSetting": true
+}`
+	if err := os.WriteFile(clientPath, []byte(existing), 0600); err != nil {
+		t.Fatalf("Failed to write client config: %v", err)
+	}
+
+	mcpCfg := &config.MCPConfig{MCPServers: map[string]config.MCPServer{
+		"fetch":  {Command: "uvx", Args: []string{"mcp-server-fetch"}},
+		"github": {Command: "npx", Args: []string{"-y", "github"}, Env: map[string]string{"TOKEN": "new"}},
+		"time":   {Command: "uvx", Args: []string{"mcp-server-time"}},
+	}}
+	trans := NewTranslator(config.GetDefaultConfig(), mcpCfg)
+
+	render, err := trans.RenderClientConfig("cursor", config.Client{ConfigPath: clientPath})
+	if err != nil {
+		t.Fatalf("RenderClientConfig failed: %v", err)
+	}
+	if !render.Changed() {
+		t.Errorf("Expected render to change the client config")
+	}
+
+	// Rendering must not touch the file
+	data, _ := os.ReadFile(clientPath)
+	if string(data) != existing {
+		t.Errorf("RenderClientConfig modified the client config file")
+	}
+
+	statuses, err := ClientStatus(render)
+	if err != nil {
+		t.Fatalf("ClientStatus failed: %v", err)
+	}
+
+	got := make(map[string]SyncState)
+	for _, status := range statuses {
+		got[status.Name] = status.State
+	}
+	expected := map[string]SyncState{
+		"fetch":  StateInSync,
+		"github": StateModified,
+		"legacy": StateForeign,
+		"time":   StateMissing,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Status mismatch.\nExpected: %v\nGot:      %v", expected, got)
+	}
+
+	// The rendered content must be in sync with itself
+	applied := &ClientRender{ClientName: "cursor", Path: clientPath, Exists: true, Current: render.Rendered, Rendered: render.Rendered}
+	statuses, err = ClientStatus(applied)
+	if err != nil {
+		t.Fatalf("ClientStatus failed: %v", err)
+	}
+	for _, status := range statuses {
+		if status.State != StateInSync {
+			t.Errorf("Expected %s to be in sync after apply, got %s", status.Name, status.State)
+		}
+	}
+}
+
+func TestDiffFields(t *testing.T) {
+	current := map[string]interface{}{
+		"command": "npx",
+		"args":    []interface{}{"-y", "a"},
+		"env":     map[string]interface{}{"A": "1", "B": "2"},
+	}
+	desired := map[string]interface{}{
+		"command": "npx",
+		"args":    []interface{}{"-y", "b"},
+		"env":     map[string]interface{}{"A": "1", "C": "3"},
+	}
+
+	expected := []FieldDiff{
+		{Path: "args", Current: []interface{}{"-y", "a"}, Desired: []interface{}{"-y", "b"}},
+		{Path: "env.B", Current: "2", Desired: nil},
+		{Path: "env.C", Current: nil, Desired: "3"},
+	}
+	if diffs := DiffFields(current, desired); !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("DiffFields mismatch.\nExpected: %+v\nGot:      %+v", expected, diffs)
+	}
+}
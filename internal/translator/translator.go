@@ -3,30 +3,52 @@ package translator
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/secrets"
 	"github.com/tuannvm/mcpenetes/internal/util"
+	"github.com/tuannvm/mcpenetes/internal/vars"
 	"gopkg.in/yaml.v3"
 )
 
+// clientFileMode is the mode of client configs and backups mcpenetes creates, since they may
+// hold resolved secrets. os.WriteFile only applies it to new files; existing ones keep theirs.
+const clientFileMode = 0600
+
 // Translator handles backing up and translating MCP configs for clients.
 type Translator struct {
 	AppConfig *config.Config
 	MCPConfig *config.MCPConfig
+	// Secrets resolves secret references in server fields while rendering client configs
+	Secrets *secrets.Resolver
+	// VarContext holds the literal values of {{...}} template variables
+	VarContext vars.Context
+	// CommandPolicy returns why a server's ${cmd:...} references may not run, or nil if they
+	// may as far as Secrets allows. Without it, Secrets alone decides.
+	CommandPolicy func(serverID string) error
+	// Excluded holds the servers left out of MCPConfig on this machine by their when condition.
+	// Client entries with these names are left as they are rather than removed as obsolete.
+	Excluded map[string]string
 }
 
 // NewTranslator creates a new Translator instance.
+// Relative paths in secret references are resolved against the config directory, next to mcp.json.
 func NewTranslator(appCfg *config.Config, mcpCfg *config.MCPConfig) *Translator {
+	baseDir, _ := config.ConfigDir()
 	return &Translator{
-		AppConfig: appCfg,
-		MCPConfig: mcpCfg,
+		AppConfig:  appCfg,
+		MCPConfig:  mcpCfg,
+		Secrets:    secrets.NewResolver(baseDir),
+		VarContext: vars.DefaultContext(),
 	}
 }
 
@@ -75,7 +97,7 @@ func (t *Translator) BackupClientConfig(clientName string, clientConf config.Cli
 	}()
 
 	// Create destination backup file
-	dstFile, err := os.Create(backupFilePath)
+	dstFile, err := os.OpenFile(backupFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, clientFileMode)
 	if err != nil {
 		return "", fmt.Errorf("failed to create backup file '%s': %w", backupFilePath, err)
 	}
@@ -89,8 +111,6 @@ func (t *Translator) BackupClientConfig(clientName string, clientConf config.Cli
 		return "", fmt.Errorf("failed to copy config to backup file '%s': %w", backupFilePath, err)
 	}
 
-	fmt.Printf("  Backed up '%s' to '%s'\n", clientConfigPath, backupFilePath)
-
 	// TODO: Implement backup retention logic here or separately
 
 	return backupFilePath, nil
@@ -136,18 +156,62 @@ func (t *Translator) TranslateAndApply(clientName string, clientConf config.Clie
 		}
 	}
 
+	// Ensure the target directory exists
+	clientConfigDir := filepath.Dir(clientConfigPath)
+	if err := os.MkdirAll(clientConfigDir, 0750); err != nil {
+		return fmt.Errorf("failed to create directory '%s' for client %s: %w", clientConfigDir, clientName, err)
+	}
+
+	// Merge with the existing file content, if any, and merge again if the client
+	// rewrites its config in the meantime
+	for attempt := 0; ; attempt++ {
+		existingFile, exists, err := readClientFile(clientConfigPath)
+		if err != nil {
+			return err
+		}
+
+		resolvedConf, err := t.prepareServer(clientName, serverID, serverConf)
+		if err != nil {
+			return fmt.Errorf("server '%s' not applied to %s: %w", serverID, clientName, err)
+		}
+
+		outputData, err := t.translateServer(clientName, clientConfigPath, existingFile, serverID, resolvedConf)
+		if err != nil {
+			return err
+		}
+
+		if err := checkUnchanged(clientConfigPath, contentHash(existingFile, exists)); err != nil {
+			if errors.Is(err, ErrConflict) && attempt < MaxConflictRetries {
+				continue
+			}
+			return err
+		}
+
+		// Write the translated config file
+		if err := os.WriteFile(clientConfigPath, outputData, clientFileMode); err != nil {
+			return fmt.Errorf("failed to write config file '%s' for client %s: %w", clientConfigPath, clientName, err)
+		}
+		break
+	}
+
+	fmt.Printf("  Successfully wrote config for %s to '%s'\n", clientName, clientConfigPath)
+	return nil
+}
+
+// translateServer merges a single server into the content of a client config file
+// and returns the new content. It does not touch the filesystem.
+func (t *Translator) translateServer(clientName, clientConfigPath string, existingFile []byte, serverID string, serverConf config.MCPServer) ([]byte, error) {
 	// Determine how to format the config based on client name and file extension
 	var outputData []byte
+	var err error
 	format := strings.ToLower(filepath.Ext(clientConfigPath))
 
-	// Check if the file already exists to determine if we need to merge with existing config
+	// Check if the file already has content to determine if we need to merge with existing config
 	existingConfig := make(map[string]interface{})
-	existingFile, err := os.ReadFile(clientConfigPath)
 	var configExists = false
-	if err == nil && len(existingFile) > 0 {
+	if len(existingFile) > 0 {
 		configExists = true
-		err = json.Unmarshal(existingFile, &existingConfig)
-		if err != nil {
+		if err := json.Unmarshal(existingFile, &existingConfig); err != nil {
 			// File exists but isn't valid JSON, we'll just overwrite it
 			configExists = false
 		}
@@ -204,6 +268,8 @@ func (t *Translator) TranslateAndApply(clientName string, clientConf config.Clie
 			serverEntry["autoApprove"] = []string{}
 		}
 
+		copyExtras(serverEntry, serverConf)
+
 		// Add/update the server in the map
 		mcpServers[serverID] = serverEntry
 		claudeConfig["mcpServers"] = mcpServers
@@ -211,7 +277,7 @@ func (t *Translator) TranslateAndApply(clientName string, clientConf config.Clie
 		// Marshal the updated config
 		outputData, err = json.MarshalIndent(claudeConfig, "", "  ")
 		if err != nil {
-			return fmt.Errorf("failed to marshal Claude Desktop config: %w", err)
+			return nil, fmt.Errorf("failed to marshal Claude Desktop config: %w", err)
 		}
 
 	case strings.Contains(clientName, "windsurf") || strings.Contains(clientName, "cursor"):
@@ -254,6 +320,8 @@ func (t *Translator) TranslateAndApply(clientName string, clientConf config.Clie
 			serverEntry["url"] = serverConf.URL
 		}
 
+		copyExtras(serverEntry, serverConf)
+
 		// Add/update the server in the map
 		mcpServers[serverID] = serverEntry
 		windsurfConfig["mcpServers"] = mcpServers
@@ -261,7 +329,7 @@ func (t *Translator) TranslateAndApply(clientName string, clientConf config.Clie
 		// Marshal the updated config
 		outputData, err = json.MarshalIndent(windsurfConfig, "", "  ")
 		if err != nil {
-			return fmt.Errorf("failed to marshal Windsurf config: %w", err)
+			return nil, fmt.Errorf("failed to marshal Windsurf config: %w", err)
 		}
 
 	case strings.Contains(clientName, "vscode"):
@@ -319,6 +387,8 @@ func (t *Translator) TranslateAndApply(clientName string, clientConf config.Clie
 			serverEntry["url"] = serverConf.URL
 		}
 
+		copyExtras(serverEntry, serverConf)
+
 		// Add/update the server in the map
 		mcpServers[serverID] = serverEntry
 		mcpObj["servers"] = mcpServers
@@ -327,7 +397,7 @@ func (t *Translator) TranslateAndApply(clientName string, clientConf config.Clie
 		// Marshal the updated config
 		outputData, err = json.MarshalIndent(vscodeConfig, "", "  ")
 		if err != nil {
-			return fmt.Errorf("failed to marshal VS Code/Cursor config: %w", err)
+			return nil, fmt.Errorf("failed to marshal VS Code/Cursor config: %w", err)
 		}
 
 	default:
@@ -380,54 +450,71 @@ func (t *Translator) TranslateAndApply(clientName string, clientConf config.Clie
 				serverMap["autoApprove"] = []string{}
 			}
 
+			copyExtras(serverMap, serverConf)
+
 			// Add the server to the map
 			mcpServers[serverID] = serverMap
 			genericConfig["mcpServers"] = mcpServers
 
 			outputData, err = json.MarshalIndent(genericConfig, "", "  ")
 			if err != nil {
-				return fmt.Errorf("failed to marshal generic JSON config: %w", err)
+				return nil, fmt.Errorf("failed to marshal generic JSON config: %w", err)
 			}
 
 		case ".yaml", ".yml":
-			// Create a map for the server with its ID as key
+			// Add the server to the existing servers, keyed by its ID
 			serverMap := make(map[string]config.MCPServer)
+			_ = yaml.Unmarshal(existingFile, &serverMap) // Invalid content is overwritten
 			serverMap[serverID] = serverConf
 
 			outputData, err = yaml.Marshal(serverMap)
 			if err != nil {
-				return fmt.Errorf("failed to marshal config to YAML for %s: %w", clientName, err)
+				return nil, fmt.Errorf("failed to marshal config to YAML for %s: %w", clientName, err)
 			}
 
 		case ".toml":
-			// Create a map for the server with its ID as key
+			// TOML output is encoded from the typed struct, so passthrough fields can't be carried over
+			if len(serverConf.Extras) > 0 {
+				fmt.Printf("  Warning: dropping unsupported fields %v of server '%s' for %s (TOML)\n", extraKeys(serverConf), serverID, clientName)
+			}
+
+			// Add the server to the existing servers, keyed by its ID
 			serverMap := make(map[string]config.MCPServer)
+			_, _ = toml.Decode(string(existingFile), &serverMap) // Invalid content is overwritten
 			serverMap[serverID] = serverConf
 
 			buf := new(bytes.Buffer)
 			if err := toml.NewEncoder(buf).Encode(serverMap); err != nil {
-				return fmt.Errorf("failed to marshal config to TOML for %s: %w", clientName, err)
+				return nil, fmt.Errorf("failed to marshal config to TOML for %s: %w", clientName, err)
 			}
 			outputData = buf.Bytes()
 
 		default:
-			return fmt.Errorf("unsupported config format '%s' for client %s", format, clientName)
+			return nil, fmt.Errorf("unsupported config format '%s' for client %s", format, clientName)
 		}
 	}
 
-	// Ensure the target directory exists
-	clientConfigDir := filepath.Dir(clientConfigPath)
-	if err := os.MkdirAll(clientConfigDir, 0750); err != nil {
-		return fmt.Errorf("failed to create directory '%s' for client %s: %w", clientConfigDir, clientName, err)
-	}
+	return outputData, nil
+}
 
-	// Write the translated config file
-	if err := os.WriteFile(clientConfigPath, outputData, 0644); err != nil { // Use 0644 for client configs generally
-		return fmt.Errorf("failed to write config file '%s' for client %s: %w", clientConfigPath, clientName, err)
+// copyExtras adds the server's passthrough fields to a client entry
+// without overriding any key the translator has already set.
+func copyExtras(entry map[string]interface{}, serverConf config.MCPServer) {
+	for key, value := range serverConf.Extras {
+		if _, exists := entry[key]; !exists {
+			entry[key] = value
+		}
 	}
+}
 
-	fmt.Printf("  Successfully wrote config for %s to '%s'\n", clientName, clientConfigPath)
-	return nil
+// extraKeys returns the sorted names of the server's passthrough fields.
+func extraKeys(serverConf config.MCPServer) []string {
+	keys := make([]string, 0, len(serverConf.Extras))
+	for key := range serverConf.Extras {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // RemoveClientServers removes servers from client configurations that no longer exist in the main MCP configuration
@@ -437,24 +524,47 @@ func (t *Translator) RemoveClientServers(clientName string, clientConf config.Cl
 		return fmt.Errorf("failed to expand client config path '%s' for %s: %w", clientConf.ConfigPath, clientName, err)
 	}
 
-	// Check if client config file exists
-	_, err = os.Stat(clientConfigPath)
-	if os.IsNotExist(err) {
-		// File doesn't exist, nothing to remove
-		return nil
-	} else if err != nil {
-		return fmt.Errorf("failed to stat client config file '%s': %w", clientConfigPath, err)
-	}
+	for attempt := 0; ; attempt++ {
+		// Read the client config file
+		clientConfigData, exists, err := readClientFile(clientConfigPath)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			// File doesn't exist, nothing to remove
+			return nil
+		}
 
-	// Read the client config file
-	clientConfigData, err := os.ReadFile(clientConfigPath)
-	if err != nil {
-		return fmt.Errorf("failed to read client config file '%s': %w", clientConfigPath, err)
+		outputData, removed, err := t.removeServers(clientName, clientConfigPath, clientConfigData)
+		if err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			return nil
+		}
+
+		// Merge again if the client rewrote its config since it was read
+		if err := checkUnchanged(clientConfigPath, contentHash(clientConfigData, exists)); err != nil {
+			if errors.Is(err, ErrConflict) && attempt < MaxConflictRetries {
+				continue
+			}
+			return err
+		}
+
+		for _, serverID := range removed {
+			fmt.Printf("  Removed obsolete server '%s' from client configuration\n", serverID)
+		}
+		return os.WriteFile(clientConfigPath, outputData, clientFileMode)
 	}
+}
 
+// removeServers drops the servers that no longer exist in the main MCP configuration
+// from the content of a client config file. It returns the new content and the IDs
+// of the removed servers; the content is returned unchanged if nothing was removed.
+func (t *Translator) removeServers(clientName, clientConfigPath string, clientConfigData []byte) ([]byte, []string, error) {
 	// If file is empty, nothing to do
 	if len(clientConfigData) == 0 {
-		return nil
+		return clientConfigData, nil, nil
 	}
 
 	format := strings.ToLower(filepath.Ext(clientConfigPath))
@@ -464,124 +574,124 @@ func (t *Translator) RemoveClientServers(clientName string, clientConf config.Cl
 	case ".json":
 		var clientConfig map[string]interface{}
 		if err := json.Unmarshal(clientConfigData, &clientConfig); err != nil {
-			return fmt.Errorf("failed to parse client JSON config file '%s': %w", clientConfigPath, err)
+			return nil, nil, fmt.Errorf("failed to parse client JSON config file '%s': %w", clientConfigPath, err)
 		}
 
-		// Handle different client formats
+		// Handle different client formats, mirroring the layout written by translateServer
+		var removed []string
 		switch {
-		case strings.Contains(clientName, "claude-desktop"):
-			mcpServers, ok := clientConfig["mcpServers"].(map[string]interface{})
-			if !ok {
-				// No mcpServers section, nothing to do
-				return nil
-			}
-
-			// Remove servers that don't exist in the main MCP configuration
-			changed := t.removeObsoleteServers(mcpServers)
-
-			if changed {
-				clientConfig["mcpServers"] = mcpServers
-				outputData, err := json.MarshalIndent(clientConfig, "", "  ")
-				if err != nil {
-					return fmt.Errorf("failed to marshal updated Claude Desktop config: %w", err)
-				}
-				return os.WriteFile(clientConfigPath, outputData, 0644)
-			}
-
-		case strings.Contains(clientName, "windsurf"):
-			mcpServers, ok := clientConfig["mcpServers"].(map[string]interface{})
-			if !ok {
-				// No mcpServers section, nothing to do
-				return nil
-			}
-
-			// Remove servers that don't exist in the main MCP configuration
-			changed := t.removeObsoleteServers(mcpServers)
-
-			if changed {
-				clientConfig["mcpServers"] = mcpServers
-				outputData, err := json.MarshalIndent(clientConfig, "", "  ")
-				if err != nil {
-					return fmt.Errorf("failed to marshal updated Windsurf config: %w", err)
-				}
-				return os.WriteFile(clientConfigPath, outputData, 0644)
-			}
-
-		case strings.Contains(clientName, "vscode") || strings.Contains(clientName, "cursor"):
+		case strings.Contains(clientName, "vscode"):
 			mcpObj, ok := clientConfig["mcp"].(map[string]interface{})
 			if !ok {
 				// No mcp section, nothing to do
-				return nil
+				return clientConfigData, nil, nil
 			}
 
 			servers, ok := mcpObj["servers"].(map[string]interface{})
 			if !ok {
 				// No servers section, nothing to do
-				return nil
+				return clientConfigData, nil, nil
 			}
 
 			// Remove servers that don't exist in the main MCP configuration
-			changed := t.removeObsoleteServers(servers)
-
-			if changed {
-				mcpObj["servers"] = servers
-				clientConfig["mcp"] = mcpObj
-				outputData, err := json.MarshalIndent(clientConfig, "", "  ")
-				if err != nil {
-					return fmt.Errorf("failed to marshal updated VS Code/Cursor config: %w", err)
-				}
-				return os.WriteFile(clientConfigPath, outputData, 0644)
-			}
+			removed = t.removeObsoleteServers(servers)
+			mcpObj["servers"] = servers
+			clientConfig["mcp"] = mcpObj
 
 		default:
-			// For unknown clients with JSON format
+			// Claude Desktop, Windsurf, Cursor and unknown JSON clients use mcpServers at the top level
 			mcpServers, ok := clientConfig["mcpServers"].(map[string]interface{})
 			if !ok {
 				// No mcpServers section, nothing to do
-				return nil
+				return clientConfigData, nil, nil
 			}
 
 			// Remove servers that don't exist in the main MCP configuration
-			changed := t.removeObsoleteServers(mcpServers)
-
-			if changed {
-				clientConfig["mcpServers"] = mcpServers
-				outputData, err := json.MarshalIndent(clientConfig, "", "  ")
-				if err != nil {
-					return fmt.Errorf("failed to marshal updated generic JSON config: %w", err)
-				}
-				return os.WriteFile(clientConfigPath, outputData, 0644)
-			}
+			removed = t.removeObsoleteServers(mcpServers)
+			clientConfig["mcpServers"] = mcpServers
 		}
 
-	// For YAML and TOML formats, we would handle similarly but with their respective formats
-	// For now, only JSON format is fully implemented
-	case ".yaml", ".yml", ".toml":
-		fmt.Printf("  Warning: Removing servers from %s format not fully implemented for %s\n", format, clientName)
+		if len(removed) == 0 {
+			return clientConfigData, nil, nil
+		}
+		outputData, err := json.MarshalIndent(clientConfig, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal updated config for %s: %w", clientName, err)
+		}
+		return outputData, removed, nil
+
+	case ".yaml", ".yml":
+		serverMap := make(map[string]config.MCPServer)
+		if err := yaml.Unmarshal(clientConfigData, &serverMap); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse client YAML config file '%s': %w", clientConfigPath, err)
+		}
+
+		removed := t.removeObsoleteServerIDs(serverMap)
+		if len(removed) == 0 {
+			return clientConfigData, nil, nil
+		}
+		outputData, err := yaml.Marshal(serverMap)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal config to YAML for %s: %w", clientName, err)
+		}
+		return outputData, removed, nil
+
+	case ".toml":
+		serverMap := make(map[string]config.MCPServer)
+		if _, err := toml.Decode(string(clientConfigData), &serverMap); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse client TOML config file '%s': %w", clientConfigPath, err)
+		}
+
+		removed := t.removeObsoleteServerIDs(serverMap)
+		if len(removed) == 0 {
+			return clientConfigData, nil, nil
+		}
+		buf := new(bytes.Buffer)
+		if err := toml.NewEncoder(buf).Encode(serverMap); err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal config to TOML for %s: %w", clientName, err)
+		}
+		return buf.Bytes(), removed, nil
 
 	default:
-		return fmt.Errorf("unsupported config format '%s' for client %s", format, clientName)
+		return nil, nil, fmt.Errorf("unsupported config format '%s' for client %s", format, clientName)
 	}
+}
 
-	return nil
+// obsolete reports whether a client's server entry should be removed: it is neither in the
+// main MCP configuration nor excluded on this machine.
+func (t *Translator) obsolete(serverID string) bool {
+	if _, exists := t.MCPConfig.MCPServers[serverID]; exists {
+		return false
+	}
+	_, excluded := t.Excluded[serverID]
+	return !excluded
 }
 
 // removeObsoleteServers removes server entries from a client config map that don't exist in the MCPConfig
-// and returns whether any changes were made
-func (t *Translator) removeObsoleteServers(servers map[string]interface{}) bool {
-	if len(servers) == 0 {
-		return false
+// and returns the sorted IDs of the removed servers
+func (t *Translator) removeObsoleteServers(servers map[string]interface{}) []string {
+	var removed []string
+	for serverID := range servers {
+		if t.obsolete(serverID) {
+			delete(servers, serverID)
+			removed = append(removed, serverID)
+		}
 	}
 
-	changed := false
+	sort.Strings(removed)
+	return removed
+}
+
+// removeObsoleteServerIDs is the typed counterpart of removeObsoleteServers for YAML and TOML clients.
+func (t *Translator) removeObsoleteServerIDs(servers map[string]config.MCPServer) []string {
+	var removed []string
 	for serverID := range servers {
-		// Check if this server exists in the main MCP configuration
-		if _, exists := t.MCPConfig.MCPServers[serverID]; !exists {
+		if t.obsolete(serverID) {
 			delete(servers, serverID)
-			fmt.Printf("  Removed obsolete server '%s' from client configuration\n", serverID)
-			changed = true
+			removed = append(removed, serverID)
 		}
 	}
 
-	return changed
+	sort.Strings(removed)
+	return removed
 }
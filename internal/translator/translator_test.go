@@ -0,0 +1,95 @@
+package translator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tuannvm/mcpenetes/internal/config"
+)
+
+func TestTranslateAndApplyKeepsServers(t *testing.T) {
+	mcpCfg := &config.MCPConfig{MCPServers: map[string]config.MCPServer{
+		"fetch": {Command: "uvx", Args: []string{"mcp-server-fetch"}},
+		"time":  {Command: "uvx", Args: []string{"mcp-server-time"}},
+	}}
+	trans := NewTranslator(config.GetDefaultConfig(), mcpCfg)
+
+	// YAML and TOML clients get every server, not only the last one applied
+	for _, fileName := range []string{"servers.yaml", "servers.toml"} {
+		clientConf := config.Client{ConfigPath: filepath.Join(t.TempDir(), fileName)}
+		for _, serverID := range []string{"fetch", "time"} {
+			if err := trans.TranslateAndApply("custom", clientConf, mcpCfg.MCPServers[serverID]); err != nil {
+				t.Fatalf("TranslateAndApply(%s) failed for %s: %v", serverID, fileName, err)
+			}
+		}
+		data, err := os.ReadFile(clientConf.ConfigPath)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", fileName, err)
+		}
+		if !strings.Contains(string(data), "mcp-server-fetch") || !strings.Contains(string(data), "mcp-server-time") {
+			t.Errorf("Expected both servers in %s, got:\n%s", fileName, data)
+		}
+	}
+}
+
+func TestRemoveClientServers(t *testing.T) {
+	mcpCfg := &config.MCPConfig{MCPServers: map[string]config.MCPServer{
+		"fetch": {Command: "uvx", Args: []string{"mcp-server-fetch"}},
+	}}
+	trans := NewTranslator(config.GetDefaultConfig(), mcpCfg)
+
+	testCases := []struct {
+		name       string
+		clientName string
+		fileName   string
+		content    string
+	}{
+		{
+			// Cursor keeps its servers under mcpServers, the layout TranslateAndApply writes
+			name:       "Cursor mcpServers",
+			clientName: "cursor",
+			fileName:   "mcp.json",
+			content:    `{"mcpServers": {"fetch": {"command": "uvx"}, "legacy": {"command": "legacy-server"}}, "theme": "dark"}`,
+		},
+		{
+			name:       "VS Code mcp.servers",
+			clientName: "vscode",
+			fileName:   "settings.json",
+			content:    `{"mcp": {"servers": {"fetch": {"command": "uvx"}, "legacy": {"command": "legacy-server"}}}, "theme": "dark"}`,
+		},
+		{
+			name:       "YAML",
+			clientName: "custom",
+			fileName:   "servers.yaml",
+			content:    "fetch:\n  command: uvx\nlegacy:\n  command: legacy-server\n",
+		},
+		{
+			name:       "TOML",
+			clientName: "custom",
+			fileName:   "servers.toml",
+			content:    "[fetch]\ncommand = \"uvx\"\n\n[legacy]\ncommand = \"legacy-server\"\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientPath := filepath.Join(t.TempDir(), tc.fileName)
+			if err := os.WriteFile(clientPath, []byte(tc.content), 0600); err != nil {
+				t.Fatalf("Failed to write client config: %v", err)
+			}
+
+			if err := trans.RemoveClientServers(tc.clientName, config.Client{ConfigPath: clientPath}); err != nil {
+				t.Fatalf("RemoveClientServers failed: %v", err)
+			}
+			data, err := os.ReadFile(clientPath)
+			if err != nil {
+				t.Fatalf("Failed to read client config: %v", err)
+			}
+			if strings.Contains(string(data), "legacy") || !strings.Contains(string(data), "uvx") {
+				t.Errorf("Expected only legacy to be removed, got:\n%s", data)
+			}
+		})
+	}
+}
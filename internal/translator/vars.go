@@ -0,0 +1,39 @@
+package translator
+
+import (
+	"strings"
+
+	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/vars"
+)
+
+// clientVarSyntax returns the variables a client expands itself in its MCP config.
+// Clients without variables (e.g. Claude Desktop, Windsurf) get literal values.
+func clientVarSyntax(clientName string) vars.Syntax {
+	switch {
+	case strings.Contains(clientName, "vscode"), strings.Contains(clientName, "cursor"):
+		return vars.Syntax{Home: "${userHome}", Workspace: "${workspaceFolder}", Env: "${env:%s}"}
+	}
+	return vars.Syntax{}
+}
+
+// renderServerTemplates returns a copy of serverConf with its {{...}} template variables
+// rendered for the given client.
+func (t *Translator) renderServerTemplates(clientName string, serverConf config.MCPServer) (config.MCPServer, error) {
+	syntax := clientVarSyntax(clientName)
+	ctx := t.VarContext
+	return mapServerStrings(serverConf, func(value string) (string, error) {
+		return vars.Render(value, syntax, ctx)
+	})
+}
+
+// prepareServer resolves a server's secret references, then renders its templates for a client.
+// Its when condition is for mcpenetes only and is dropped.
+func (t *Translator) prepareServer(clientName, serverID string, serverConf config.MCPServer) (config.MCPServer, error) {
+	serverConf.When = nil
+	resolved, err := t.resolveServer(serverID, serverConf)
+	if err != nil {
+		return config.MCPServer{}, err
+	}
+	return t.renderServerTemplates(clientName, resolved)
+}
@@ -6,6 +6,7 @@ import (
 	"runtime"
 
 	"github.com/tuannvm/mcpenetes/internal/config"
+	"github.com/tuannvm/mcpenetes/internal/paths"
 )
 
 // DetectedClient represents a client detected on the user's system
@@ -18,7 +19,7 @@ type DetectedClient struct {
 // and their configuration paths on the user's system
 func DetectMCPClients() (map[string]config.Client, error) {
 	clients := make(map[string]config.Client)
-	homeDir, err := os.UserHomeDir()
+	homeDir, err := paths.HomeDir()
 	if err != nil {
 		return nil, err
 	}
@@ -106,8 +107,14 @@ func DetectMCPClients() (map[string]config.Client, error) {
 			},
 		}
 	case "windows":
-		appData := os.Getenv("APPDATA")
-		userProfile := os.Getenv("USERPROFILE")
+		appData, err := paths.Rebase(os.Getenv("APPDATA"))
+		if err != nil {
+			return nil, err
+		}
+		userProfile, err := paths.Rebase(os.Getenv("USERPROFILE"))
+		if err != nil {
+			return nil, err
+		}
 		clientPaths = []struct {
 			Name       string
 			ConfigDir  string
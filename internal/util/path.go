@@ -1,21 +1,23 @@
 package util
 
 import (
-	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/tuannvm/mcpenetes/internal/paths"
 )
 
 // ExpandPath expands tilde (~) in a path to the user's home directory.
+// With --root or --home, the path is moved into that tree; expanding an already
+// expanded path returns it unchanged.
 func ExpandPath(path string) (string, error) {
 	if !strings.HasPrefix(path, "~") {
-		return path, nil // No tilde prefix, return as is
+		return paths.Rebase(path)
 	}
 
-	homeDir, err := os.UserHomeDir()
+	homeDir, err := paths.HomeDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get user home directory: %w", err)
+		return "", err
 	}
 
 	return filepath.Join(homeDir, path[1:]), nil
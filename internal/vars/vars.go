@@ -0,0 +1,128 @@
+package vars
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/tuannvm/mcpenetes/internal/paths"
+)
+
+// templatePattern matches {{name}} and {{env.NAME}}, with optional spaces inside the braces.
+var templatePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.]*)\s*\}\}`)
+
+// Syntax describes the variables a client expands itself. A variable with an empty
+// spelling is rendered as a literal value instead.
+type Syntax struct {
+	Home      string // e.g. ${userHome}
+	Workspace string // e.g. ${workspaceFolder}
+	Env       string // format string taking the variable name, e.g. ${env:%s}
+}
+
+// Context holds the literal values of the variables.
+type Context struct {
+	Home      string
+	Workspace string // Empty when there is no workspace to resolve {{workspace}} to
+	OS        string
+	LookupEnv func(name string) (string, bool)
+}
+
+// DefaultContext returns the context of the current user and system; Home is the home
+// directory selected with --root and --home, if any.
+func DefaultContext() Context {
+	home, _ := paths.HomeDir()
+	return Context{Home: home, OS: runtime.GOOS, LookupEnv: os.LookupEnv}
+}
+
+// HasTemplate reports whether value contains a template variable.
+func HasTemplate(value string) bool {
+	return templatePattern.MatchString(value)
+}
+
+// Render replaces the template variables in value:
+//
+//	{{home}}       the user's home directory
+//	{{workspace}}  the workspace folder the client has open
+//	{{os}}         the operating system (linux, darwin, windows, ...)
+//	{{env.NAME}}   the environment variable NAME
+//
+// Variables the client expands itself (see Syntax) are rendered in its syntax,
+// the others as literal values from ctx.
+func Render(value string, syntax Syntax, ctx Context) (string, error) {
+	var firstErr error
+	rendered := templatePattern.ReplaceAllStringFunc(value, func(match string) string {
+		if firstErr != nil {
+			return match
+		}
+		name := templatePattern.FindStringSubmatch(match)[1]
+		result, err := renderVariable(name, syntax, ctx)
+		if err != nil {
+			firstErr = fmt.Errorf("%s: %w", match, err)
+			return match
+		}
+		return result
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return rendered, nil
+}
+
+// renderVariable renders a single template variable.
+func renderVariable(name string, syntax Syntax, ctx Context) (string, error) {
+	switch {
+	case name == "home":
+		if syntax.Home != "" {
+			return syntax.Home, nil
+		}
+		if ctx.Home == "" {
+			return "", fmt.Errorf("the home directory is unknown")
+		}
+		return ctx.Home, nil
+	case name == "workspace":
+		if syntax.Workspace != "" {
+			return syntax.Workspace, nil
+		}
+		if ctx.Workspace == "" {
+			return "", fmt.Errorf("this client has no workspace folder variable")
+		}
+		return ctx.Workspace, nil
+	case name == "os":
+		return ctx.OS, nil
+	case strings.HasPrefix(name, "env."):
+		envName := strings.TrimPrefix(name, "env.")
+		if envName == "" {
+			return "", fmt.Errorf("missing environment variable name")
+		}
+		if syntax.Env != "" {
+			return fmt.Sprintf(syntax.Env, envName), nil
+		}
+		lookupEnv := ctx.LookupEnv
+		if lookupEnv == nil {
+			lookupEnv = os.LookupEnv
+		}
+		value, ok := lookupEnv(envName)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", envName)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("unknown template variable (expected home, workspace, os or env.NAME)")
+}
+
+// Validate checks that every template variable in value is known, without resolving any.
+func Validate(value string) error {
+	for _, match := range templatePattern.FindAllStringSubmatch(value, -1) {
+		name := match[1]
+		if name == "home" || name == "workspace" || name == "os" || (strings.HasPrefix(name, "env.") && name != "env.") {
+			continue
+		}
+		if name == "env." {
+			return fmt.Errorf("%s: missing environment variable name", match[0])
+		}
+		return fmt.Errorf("%s: unknown template variable (expected home, workspace, os or env.NAME)", match[0])
+	}
+	return nil
+}
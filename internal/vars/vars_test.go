@@ -0,0 +1,63 @@
+package vars
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	ctx := Context{
+		Home: "/home/me",
+		OS:   "linux",
+		LookupEnv: func(name string) (string, bool) {
+			if name == "PROJECTS" {
+				return "/src", true
+			}
+			return "", false
+		},
+	}
+	vscode := Syntax{Home: "${userHome}", Workspace: "${workspaceFolder}", Env: "${env:%s}"}
+
+	tests := []struct {
+		value    string
+		syntax   Syntax
+		expected string
+	}{
+		{"{{home}}/data", Syntax{}, "/home/me/data"},
+		{"{{ home }}/data", vscode, "${userHome}/data"},
+		{"{{workspace}}/src", vscode, "${workspaceFolder}/src"},
+		{"bin/{{os}}/server", vscode, "bin/linux/server"},
+		{"{{env.PROJECTS}}/x", Syntax{}, "/src/x"},
+		{"{{env.PROJECTS}}/x", vscode, "${env:PROJECTS}/x"},
+		{"no templates", Syntax{}, "no templates"},
+	}
+	for _, tt := range tests {
+		got, err := Render(tt.value, tt.syntax, ctx)
+		if err != nil {
+			t.Errorf("Render(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("Render(%q) = %q, expected %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	ctx := Context{Home: "/home/me", OS: "linux", LookupEnv: func(string) (string, bool) { return "", false }}
+	tests := []struct {
+		value   string
+		message string
+	}{
+		{"{{workspace}}", "no workspace folder variable"},
+		{"{{env.MISSING}}", "MISSING is not set"},
+		{"{{homedir}}", "unknown template variable"},
+		{"{{env.}}", "missing environment variable name"},
+	}
+	for _, tt := range tests {
+		_, err := Render(tt.value, Syntax{}, ctx)
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("Render(%q) error = %v, expected it to mention %q", tt.value, err, tt.message)
+		}
+	}
+}